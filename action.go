@@ -3,7 +3,10 @@ package zrule
 import (
 	"context"
 	"fmt"
+	"net/mail"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +19,25 @@ type ActionRepository interface {
 	CreateAction(ctx context.Context, action *Action) (*Action, error)
 	UpdateAction(ctx context.Context, id primitive.ObjectID, action *Action) (*Action, error)
 	DeleteAction(ctx context.Context, id primitive.ObjectID) error
+
+	// AddInfraction appends an infraction to the action, keeping only the most recent keep
+	// infractions, and returns the action as it is after the infraction was added
+	AddInfraction(ctx context.Context, id primitive.ObjectID, infraction *Infraction, keep int) (*Action, error)
+	ClearInfractions(ctx context.Context, id primitive.ObjectID) error
+	DisableAction(ctx context.Context, id primitive.ObjectID, reason string) error
+
+	// SetActionSecret sets the secret of an action that does not have one. False is
+	// returned when the action already had a secret, which is then left as it is
+	SetActionSecret(ctx context.Context, id primitive.ObjectID, secret string) (bool, error)
+
+	// SetEmailConfirmations sets the confirmations of an email action that does not have any and disables it
+	// until they are confirmed. False is returned when the action already had confirmations
+	SetEmailConfirmations(ctx context.Context, id primitive.ObjectID, confirmations []*EmailConfirmation) (bool, error)
+	// ConfirmEmail confirms the address of an email action that token belongs to, and returns the action
+	// as it is afterwards. mongo.ErrNoDocuments is returned when the token does not belong to the action
+	ConfirmEmail(ctx context.Context, id primitive.ObjectID, token string) (*Action, error)
+	// EnableAction enables an action, but only while it is disabled for reason
+	EnableAction(ctx context.Context, id primitive.ObjectID, reason string) error
 }
 
 type Action struct {
@@ -24,15 +46,95 @@ type Action struct {
 	Label          string             `bson:"label" json:"label"`
 	Platform       Platform           `bson:"platform" json:"platform"`
 	Endpoint       string             `bson:"endpoint" json:"endpoint"`
+	Layout         Layout             `bson:"layout,omitempty" json:"layout,omitempty"`
 	Tested         bool               `bson:"tested" json:"tested"`
 	IsDisabled     bool               `bson:"is_disabled" json:"is_disabled"`
 	DisabledReason *string            `bson:"disabled_reason" json:"disabled_reason"`
 	Infractions    []*Infraction      `bson:"infractions" json:"infractions"`
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// Token and Recipient are used by platforms that are not addressed by a webhook alone,
+	// ie. the bot token and chat id of a Telegram or Slack bot action, the access token and room id of a Matrix action,
+	// or the application token and user key of a Pushover action
+	Token     string `bson:"token,omitempty" json:"token,omitempty"`
+	Recipient string `bson:"recipient,omitempty" json:"recipient,omitempty"`
+
+	// Template is an optional text/template that replaces the default message sent to the action
+	Template string `bson:"template" json:"template,omitempty"`
+
+	// Secret is used to sign the requests that are made to REST actions. One is generated when a REST action is created without one
+	Secret string `bson:"secret,omitempty" json:"secret,omitempty"`
+
+	// Fallbacks are the actions, in order, that a delivery is handed to when this action fails or is disabled
+	Fallbacks []primitive.ObjectID `bson:"fallbacks" json:"fallbacks,omitempty"`
+
+	// Confirmations are the opt ins of the addresses of an email action, which is disabled until every address has confirmed
+	Confirmations []*EmailConfirmation `bson:"confirmations,omitempty" json:"confirmations,omitempty"`
+}
+
+// MaxFallbacks is the number of fallbacks that an action can have
+const MaxFallbacks = 3
+
+// Infraction is a failed delivery to an action. A successful delivery clears the infractions
+// of an action, so the infractions of an action are the deliveries that failed in a row
+type Infraction struct {
+	ID         primitive.ObjectID `bson:"_id" json:"_id"`
+	StatusCode int                `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Message    string             `bson:"message" json:"message"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ServerErrors returns the number of the most recent infractions in a row that were server errors
+func (a *Action) ServerErrors() int {
+
+	var count int
+	for i := len(a.Infractions) - 1; i >= 0; i-- {
+		if a.Infractions[i].StatusCode < 500 || a.Infractions[i].StatusCode > 599 {
+			break
+		}
+		count++
+	}
+
+	return count
+
 }
 
 func (a *Action) IsValid() error {
+	if a.Layout != "" && !a.Layout.IsValid() {
+		return fmt.Errorf("invalid layout %s, expected one of %v", a.Layout, AllLayouts)
+	}
+
+	// Platforms that are not addressed by a webhook alone are validated as the platform that was specified,
+	// which allows them to point at a self hosted server, or a stand in server when testing
+	switch a.Platform {
+	case PlatformSlack:
+		// Slack actions with a bot token post through the Web API, which lets battle reports be edited.
+		// Those without one are incoming webhooks, which are detected from their endpoint
+		if a.Token != "" {
+			return a.validateSlackBot()
+		}
+	case PlatformTelegram:
+		return a.validateTelegram()
+	case PlatformMatrix:
+		return a.validateMatrix()
+	case PlatformEmail:
+		return a.validateEmail()
+	case PlatformGotify:
+		return a.validateGotify()
+	case PlatformPushover:
+		return a.validatePushover()
+	case PlatformEVEMail:
+		return a.validateEVEMail()
+	case PlatformWebPush:
+		// Web push actions notify every browser that the owner has subscribed, so there is nothing to validate
+		return nil
+	}
+
+	if strings.HasPrefix(strings.ToLower(a.Endpoint), "mailto:") {
+		return a.validateEmail()
+	}
+
 	uri, err := url.Parse(a.Endpoint)
 	if err != nil {
 		return fmt.Errorf("failed to validate structure of endpoint. ")
@@ -42,11 +144,26 @@ func (a *Action) IsValid() error {
 		return fmt.Errorf("invalid url scheme detected. Please use http or https")
 	}
 
-	switch uri.Host {
-	case HostSlack.String():
+	// Mattermost is self hosted, so its webhooks can only be told apart by their path, and Teams can also
+	// be reached through a Power Automate workflow. Either can be chosen explicitly when detection falls short
+	explicit := a.Platform
+
+	switch {
+	case uri.Host == HostTelegram.String():
+		a.Platform = PlatformTelegram
+		return a.validateTelegram()
+	case uri.Host == HostPushover.String():
+		return a.validatePushover()
+	case explicit == PlatformNtfy, uri.Host == HostNtfy.String():
+		a.Platform = PlatformNtfy
+	case uri.Host == HostSlack.String():
 		a.Platform = PlatformSlack
-	case HostDiscord.String(), HostDiscordApp.String():
+	case uri.Host == HostDiscord.String(), uri.Host == HostDiscordApp.String():
 		a.Platform = PlatformDiscord
+	case explicit == PlatformTeams, strings.HasSuffix(uri.Host, "."+HostTeams.String()), uri.Host == HostTeamsLegacy.String():
+		a.Platform = PlatformTeams
+	case explicit == PlatformMattermost, mattermostWebhookPath.MatchString(uri.Path):
+		a.Platform = PlatformMattermost
 	default:
 		a.Platform = PlatformRest
 	}
@@ -55,16 +172,328 @@ func (a *Action) IsValid() error {
 		return fmt.Errorf("malformed discord webhook")
 	} else if a.Platform == PlatformSlack && !strings.HasPrefix(uri.Path, "/services") {
 		return fmt.Errorf("malformed slack webhook")
+	} else if a.Platform == PlatformTeams && explicit != PlatformTeams && !strings.Contains(uri.Path, "/webhook") {
+		return fmt.Errorf("malformed teams webhook")
+	} else if a.Platform == PlatformMattermost && !strings.Contains(uri.Path, "/hooks/") {
+		return fmt.Errorf("malformed mattermost webhook")
+	} else if a.Platform == PlatformNtfy && !ntfyTopicPath.MatchString(uri.Path) {
+		return fmt.Errorf("malformed ntfy topic, expected a url such as https://ntfy.sh/mytopic")
+	}
+
+	return nil
+}
+
+// validateTelegram validates a Telegram action. The endpoint is the base URL of the Bot API and defaults
+// to the public Bot API. A Bot API URL copied from the Telegram docs, ie. https://api.telegram.org/bot<token>/sendMessage?chat_id=<chat>,
+// is also accepted, with the token and chat id being read from it when they are not provided
+func (a *Action) validateTelegram() error {
+
+	a.Platform = PlatformTelegram
+
+	if a.Endpoint == "" {
+		a.Endpoint = fmt.Sprintf("https://%s", HostTelegram)
+	}
+
+	uri, err := url.Parse(a.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to validate structure of endpoint. ")
+	}
+
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return fmt.Errorf("invalid url scheme detected. Please use http or https")
+	}
+
+	parts := strings.Split(strings.Trim(uri.Path, "/"), "/")
+	if a.Token == "" && strings.HasPrefix(parts[0], "bot") {
+		a.Token = strings.TrimPrefix(parts[0], "bot")
+	}
+	if a.Recipient == "" {
+		a.Recipient = uri.Query().Get("chat_id")
+	}
+
+	if a.Token == "" {
+		return fmt.Errorf("a bot token is required for telegram actions")
+	}
+	if a.Recipient == "" {
+		return fmt.Errorf("a chat id is required for telegram actions")
+	}
+
+	// Only the base URL of the Bot API is kept, the token is stored on its own
+	a.Endpoint = fmt.Sprintf("%s://%s", uri.Scheme, uri.Host)
+
+	return nil
+
+}
+
+// validateSlackBot validates a Slack action that posts as a bot. The endpoint is the base URL of the Web API and
+// defaults to the public Web API, the token is the bot token of the Slack app, and the recipient is the id of the channel
+func (a *Action) validateSlackBot() error {
+
+	if a.Endpoint == "" {
+		a.Endpoint = fmt.Sprintf("https://%s/api", HostSlackAPI)
+	}
+
+	uri, err := url.Parse(a.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to validate structure of endpoint. ")
+	}
+
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return fmt.Errorf("invalid url scheme detected. Please use http or https")
+	}
+
+	if !strings.HasPrefix(a.Token, "xoxb-") {
+		return fmt.Errorf("slack bot actions require a bot token, which starts with xoxb-")
+	}
+	if a.Recipient == "" {
+		return fmt.Errorf("a channel id is required for slack bot actions")
+	}
+
+	a.Endpoint = strings.TrimSuffix(a.Endpoint, "/")
+
+	return nil
+
+}
+
+// validateMatrix validates a Matrix action. The endpoint is the base URL of the homeserver, the token is
+// the access token of the account that posts the messages, and the recipient is the id of the room, ie. !abc123:example.com
+func (a *Action) validateMatrix() error {
+
+	uri, err := url.Parse(a.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to validate structure of endpoint. ")
+	}
+
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return fmt.Errorf("invalid url scheme detected. Please use http or https")
+	}
+
+	if a.Token == "" {
+		return fmt.Errorf("an access token is required for matrix actions")
+	}
+
+	if !strings.HasPrefix(a.Recipient, "!") || !strings.Contains(a.Recipient, ":") {
+		return fmt.Errorf("a room id, ie. !abc123:example.com, is required for matrix actions")
+	}
+
+	// The client-server API is appended to the homeserver URL when sending, so it is trimmed off if it was included
+	if idx := strings.Index(uri.Path, "/_matrix"); idx >= 0 {
+		uri.Path = uri.Path[:idx]
+	}
+
+	a.Endpoint = fmt.Sprintf("%s://%s%s", uri.Scheme, uri.Host, strings.TrimSuffix(uri.Path, "/"))
+
+	return nil
+
+}
+
+// validateGotify validates a Gotify action. The endpoint is the base URL of the Gotify server and the token is
+// the token of the application that messages are posted as. A URL such as https://gotify.example.com/message?token=<token>
+// is also accepted, with the token being read from it when it is not provided
+func (a *Action) validateGotify() error {
+
+	a.Platform = PlatformGotify
+
+	uri, err := url.Parse(a.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to validate structure of endpoint. ")
+	}
+
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return fmt.Errorf("invalid url scheme detected. Please use http or https")
+	}
+
+	if a.Token == "" {
+		a.Token = uri.Query().Get("token")
+	}
+	if a.Token == "" {
+		return fmt.Errorf("an application token is required for gotify actions")
+	}
+
+	// Only the base URL of the server is kept, the token is stored on its own
+	a.Endpoint = fmt.Sprintf("%s://%s%s", uri.Scheme, uri.Host, strings.TrimSuffix(strings.TrimSuffix(uri.Path, "/"), "/message"))
+
+	return nil
+
+}
+
+// pushoverKey matches the application tokens and user and group keys that Pushover issues
+var pushoverKey = regexp.MustCompile(`^[A-Za-z0-9]{30}$`)
+
+// validatePushover validates a Pushover action. The token is the API token of the application that
+// messages are sent as and the recipient is the user or group key that receives them
+func (a *Action) validatePushover() error {
+
+	a.Platform = PlatformPushover
+
+	if a.Endpoint == "" {
+		a.Endpoint = fmt.Sprintf("https://%s", HostPushover)
+	}
+
+	uri, err := url.Parse(a.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to validate structure of endpoint. ")
+	}
+
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return fmt.Errorf("invalid url scheme detected. Please use http or https")
+	}
+
+	if !pushoverKey.MatchString(a.Token) {
+		return fmt.Errorf("a 30 character application token is required for pushover actions")
+	}
+	if !pushoverKey.MatchString(a.Recipient) {
+		return fmt.Errorf("a 30 character user or group key is required for pushover actions")
+	}
+
+	a.Endpoint = fmt.Sprintf("%s://%s", uri.Scheme, uri.Host)
+
+	return nil
+
+}
+
+// EVEMailRecipientTypes are the kinds of recipient that an EVE mail can be sent to
+var EVEMailRecipientTypes = []string{"character", "corporation", "alliance", "mailing_list"}
+
+// validateEVEMail validates an EVE mail action. Mail is sent through ESI as the character that owns the action,
+// so the only thing to provide is the recipient, as its type and id, ie. corporation:98000001. The endpoint
+// identifies the owner that the mail is sent as and is set when the action is created
+func (a *Action) validateEVEMail() error {
+
+	a.Platform = PlatformEVEMail
+
+	_, _, err := a.EVEMailRecipient()
+
+	return err
+
+}
+
+// EVEMailRecipient returns the type and id of the recipient of an EVE mail action
+func (a *Action) EVEMailRecipient() (string, uint64, error) {
+
+	parts := strings.SplitN(a.Recipient, ":", 2)
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("a recipient such as corporation:98000001 is required for eve mail actions")
+	}
+
+	valid := false
+	for _, t := range EVEMailRecipientTypes {
+		if parts[0] == t {
+			valid = true
+		}
+	}
+	if !valid {
+		return "", 0, fmt.Errorf("invalid recipient type %s, expected one of %v", parts[0], EVEMailRecipientTypes)
+	}
+
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || id == 0 {
+		return "", 0, fmt.Errorf("invalid recipient id %s", parts[1])
 	}
 
+	return parts[0], id, nil
+
+}
+
+// maxEmailRecipients bounds the number of addresses that a single email action sends to
+const maxEmailRecipients = 10
+
+// validateEmail validates an email action. The endpoint is a mailto URL listing the addresses
+// that receive the email, ie. mailto:fc@example.com,ceo@example.com
+func (a *Action) validateEmail() error {
+
+	a.Platform = PlatformEmail
+
+	if !strings.HasPrefix(strings.ToLower(a.Endpoint), "mailto:") {
+		return fmt.Errorf("email actions require a mailto endpoint, ie. mailto:fc@example.com")
+	}
+
+	list, err := url.PathUnescape(a.Endpoint[len("mailto:"):])
+	if err != nil {
+		return fmt.Errorf("failed to validate structure of endpoint. ")
+	}
+
+	// Headers such as ?subject= are not supported, the subject is generated from the killmail
+	if idx := strings.Index(list, "?"); idx >= 0 {
+		list = list[:idx]
+	}
+
+	addresses, err := mail.ParseAddressList(list)
+	if err != nil {
+		return fmt.Errorf("invalid email address in endpoint: %w", err)
+	}
+
+	if len(addresses) == 0 {
+		return fmt.Errorf("at least one email address is required for email actions")
+	}
+	if len(addresses) > maxEmailRecipients {
+		return fmt.Errorf("email actions can send to at most %d addresses", maxEmailRecipients)
+	}
+
+	recipients := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		recipients = append(recipients, address.Address)
+	}
+
+	a.Endpoint = fmt.Sprintf("mailto:%s", strings.Join(recipients, ","))
+
 	return nil
+
+}
+
+// EmailRecipients returns the addresses in the mailto endpoint of an email action
+func (a *Action) EmailRecipients() []string {
+	if !strings.HasPrefix(strings.ToLower(a.Endpoint), "mailto:") {
+		return nil
+	}
+	return strings.Split(a.Endpoint[len("mailto:"):], ",")
+}
+
+// Layout controls how much of a killmail is included in the messages that are sent to an action
+type Layout string
+
+const (
+	// LayoutFull includes the victim, final blow, location, value, and attacker count of the killmail
+	LayoutFull Layout = "full"
+	// LayoutCompact summarizes the killmail in a single line
+	LayoutCompact Layout = "compact"
+)
+
+var AllLayouts = []Layout{LayoutFull, LayoutCompact}
+
+func (l Layout) IsValid() bool {
+	for _, v := range AllLayouts {
+		if v == l {
+			return true
+		}
+	}
+
+	return false
 }
 
+func (l Layout) String() string { return string(l) }
+
 type Host string
 
 const HostSlack Host = "hooks.slack.com"
+const HostSlackAPI Host = "slack.com"
 const HostDiscordApp Host = "discordapp.com"
 const HostDiscord Host = "discord.com"
+const HostTelegram Host = "api.telegram.org"
+
+// HostTeams is the domain that Teams incoming webhooks are served from, under a subdomain per tenant,
+// ie. contoso.webhook.office.com. HostTeamsLegacy served webhooks that were created before it
+const HostTeams Host = "webhook.office.com"
+const HostTeamsLegacy Host = "outlook.office.com"
+
+const HostNtfy Host = "ntfy.sh"
+const HostPushover Host = "api.pushover.net"
+
+// ntfyTopicPath matches the path of an ntfy topic
+var ntfyTopicPath = regexp.MustCompile(`^/[-_A-Za-z0-9]{1,64}$`)
+
+// mattermostWebhookPath matches the path of a Mattermost incoming webhook, which ends in a 26 character id
+var mattermostWebhookPath = regexp.MustCompile(`/hooks/[a-z0-9]{26}$`)
 
 func (h Host) String() string {
 	return string(h)
@@ -73,12 +502,26 @@ func (h Host) String() string {
 type Platform string
 
 const (
-	PlatformSlack   Platform = "slack"
-	PlatformDiscord Platform = "discord"
-	PlatformRest    Platform = "rest"
+	PlatformSlack      Platform = "slack"
+	PlatformDiscord    Platform = "discord"
+	PlatformRest       Platform = "rest"
+	PlatformTelegram   Platform = "telegram"
+	PlatformMatrix     Platform = "matrix"
+	PlatformEmail      Platform = "email"
+	PlatformTeams      Platform = "teams"
+	PlatformMattermost Platform = "mattermost"
+	PlatformNtfy       Platform = "ntfy"
+	PlatformGotify     Platform = "gotify"
+	PlatformPushover   Platform = "pushover"
+	PlatformEVEMail    Platform = "evemail"
+	PlatformWebPush    Platform = "webpush"
 )
 
-var AllPlatforms = []Platform{PlatformSlack, PlatformDiscord, PlatformRest}
+var AllPlatforms = []Platform{
+	PlatformSlack, PlatformDiscord, PlatformRest, PlatformTelegram, PlatformMatrix,
+	PlatformEmail, PlatformTeams, PlatformMattermost, PlatformNtfy, PlatformGotify, PlatformPushover, PlatformEVEMail,
+	PlatformWebPush,
+}
 
 func (p Platform) IsValid() bool {
 	for _, v := range AllPlatforms {
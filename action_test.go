@@ -0,0 +1,62 @@
+package zrule_test
+
+import (
+	"testing"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestActionIsValidDetectsPlatform(t *testing.T) {
+
+	tests := []struct {
+		platform zrule.Platform
+		endpoint string
+		expected zrule.Platform
+	}{
+		{"", "https://discord.com/api/webhooks/1/abc", zrule.PlatformDiscord},
+		{"", "https://hooks.slack.com/services/T0/B0/abc", zrule.PlatformSlack},
+		{"", "https://contoso.webhook.office.com/webhookb2/abc/IncomingWebhook/def/ghi", zrule.PlatformTeams},
+		{"", "https://outlook.office.com/webhook/abc/IncomingWebhook/def/ghi", zrule.PlatformTeams},
+		{zrule.PlatformTeams, "https://prod-00.westus.logic.azure.com/workflows/abc/triggers/manual/paths/invoke", zrule.PlatformTeams},
+		{"", "https://chat.example.com/hooks/xi8oqf5ajjr6iefzgtfykbs7wa", zrule.PlatformMattermost},
+		{zrule.PlatformMattermost, "https://chat.example.com/mattermost/hooks/abc", zrule.PlatformMattermost},
+		{"", "https://example.com/hooks/zrule", zrule.PlatformRest},
+		{"", "mailto:fc@example.com", zrule.PlatformEmail},
+		{"", "https://ntfy.sh/zrule-alerts", zrule.PlatformNtfy},
+		{zrule.PlatformGotify, "https://gotify.example.com/message?token=AbCdEf", zrule.PlatformGotify},
+		{"", "https://example.com/hooks/message?token=AbCdEf", zrule.PlatformRest},
+	}
+
+	for _, test := range tests {
+		action := &zrule.Action{Platform: test.platform, Endpoint: test.endpoint}
+		if err := action.IsValid(); err != nil {
+			t.Errorf("unexpected error validating %s: %s", test.endpoint, err)
+			continue
+		}
+
+		if action.Platform != test.expected {
+			t.Errorf("expected %s to be detected as %s, got %s", test.endpoint, test.expected, action.Platform)
+		}
+	}
+
+}
+
+func TestActionServerErrorsCountsMostRecentInRow(t *testing.T) {
+
+	action := &zrule.Action{Infractions: []*zrule.Infraction{
+		{StatusCode: 502},
+		{StatusCode: 400},
+		{StatusCode: 500},
+		{StatusCode: 503},
+	}}
+
+	if errors := action.ServerErrors(); errors != 2 {
+		t.Errorf("expected 2 server errors in a row, got %d", errors)
+	}
+
+	action.Infractions = append(action.Infractions, &zrule.Infraction{Message: "connection refused"})
+	if errors := action.ServerErrors(); errors != 0 {
+		t.Errorf("expected a failure without a response to end the run, got %d", errors)
+	}
+
+}
@@ -0,0 +1,38 @@
+package zrule
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BacktestSource string
+
+const (
+	BacktestSourceArchive BacktestSource = "archive"
+	BacktestSourceFile    BacktestSource = "file"
+)
+
+// BacktestParams bounds a backtest. Killmails that occurred at or after Start and before End
+// are evaluated. A zero Start or End leaves that side of the range open
+type BacktestParams struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	SampleSize int       `json:"sample_size"`
+}
+
+type BacktestResult struct {
+	PolicyID      primitive.ObjectID `json:"policy_id"`
+	Source        BacktestSource     `json:"source"`
+	Start         time.Time          `json:"start"`
+	End           time.Time          `json:"end"`
+	Evaluated     uint               `json:"evaluated"`
+	Matches       uint               `json:"matches"`
+	MatchesPerDay []*BacktestDay     `json:"matches_per_day"`
+	Sample        []*Killmail        `json:"sample"`
+}
+
+type BacktestDay struct {
+	Date    string `json:"date"`
+	Matches uint   `json:"matches"`
+}
@@ -0,0 +1,211 @@
+package zrule
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BattleRepository interface {
+	// Battle returns the most recent battle of a policy and action in a solar system that was still being
+	// fought within window of t, or mongo.ErrNoDocuments when there is not one
+	Battle(ctx context.Context, policyID, actionID primitive.ObjectID, solarSystemID uint, t time.Time, window time.Duration) (*Battle, error)
+	SaveBattle(ctx context.Context, battle *Battle) (*Battle, error)
+}
+
+// PolicyMode controls how the matches of a policy are sent to its actions
+type PolicyMode string
+
+const (
+	// PolicyModeKillmail sends a message for every match. It is the mode of a policy that does not set one
+	PolicyModeKillmail PolicyMode = ""
+	// PolicyModeBattleReport groups the matches in a solar system into a single message per battle,
+	// which is edited as kills come in on platforms that can edit a message after sending it
+	PolicyModeBattleReport PolicyMode = "battle_report"
+)
+
+var AllPolicyModes = []PolicyMode{PolicyModeKillmail, PolicyModeBattleReport}
+
+func (m PolicyMode) IsValid() bool {
+	for _, v := range AllPolicyModes {
+		if v == m {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m PolicyMode) String() string { return string(m) }
+
+// DefaultBattleWindow is how long a battle goes without a kill before it is over, when the policy does not set one
+const DefaultBattleWindow = time.Minute * 15
+
+// MaxBattleWindow is the longest battle window, in minutes, that a policy can set
+const MaxBattleWindow = 180
+
+// BattleWindowDuration returns how long a battle of the policy goes without a kill before it is over
+func (p *Policy) BattleWindowDuration() time.Duration {
+	if p.BattleWindow == 0 {
+		return DefaultBattleWindow
+	}
+	return time.Duration(p.BattleWindow) * time.Minute
+}
+
+// Battle is the kills that a battle report policy matched in a solar system within the battle window of each other,
+// along with the message that reports them. MessageID is whatever the platform needs to edit that message.
+// Pending is set while the message is sent or edited, so that a kill whose report failed is reported again
+type Battle struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	PolicyID        primitive.ObjectID `bson:"policy_id" json:"policy_id"`
+	ActionID        primitive.ObjectID `bson:"action_id" json:"action_id"`
+	SolarSystemID   uint               `bson:"solar_system_id" json:"solar_system_id"`
+	SolarSystemName string             `bson:"solar_system_name" json:"solar_system_name"`
+	MessageID       string             `bson:"message_id" json:"message_id"`
+	Kills           []*BattleKill      `bson:"kills" json:"kills"`
+	Pending         bool               `bson:"pending" json:"pending"`
+	StartedAt       time.Time          `bson:"started_at" json:"started_at"`
+	EndedAt         time.Time          `bson:"ended_at" json:"ended_at"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// BattleKill is a kill of a battle. Side is the alliance of the victim, or its corporation when it is not in an alliance
+type BattleKill struct {
+	KillmailID   uint      `bson:"killmail_id" json:"killmail_id"`
+	KillmailTime time.Time `bson:"killmail_time" json:"killmail_time"`
+	ShipName     string    `bson:"ship_name" json:"ship_name"`
+	Side         string    `bson:"side" json:"side"`
+	Value        float64   `bson:"value" json:"value"`
+}
+
+// BattleSide is the losses of one side of a battle
+type BattleSide struct {
+	Name   string  `json:"name"`
+	Losses int     `json:"losses"`
+	Value  float64 `json:"value"`
+}
+
+// NewBattle starts the battle of a policy and action in the solar system of the notification
+func NewBattle(notification *Notification, actionID primitive.ObjectID) *Battle {
+
+	battle := &Battle{
+		PolicyID:      notification.Policy.ID,
+		ActionID:      actionID,
+		SolarSystemID: notification.Killmail.SolarSystemID,
+		Kills:         make([]*BattleKill, 0),
+	}
+
+	if notification.Details != nil && notification.Details.SolarSystem != nil {
+		battle.SolarSystemName = notification.Details.SolarSystem.Name
+	}
+
+	return battle
+
+}
+
+// NewBattleKill returns the kill that a notification adds to a battle
+func NewBattleKill(notification *Notification) *BattleKill {
+
+	kill := &BattleKill{
+		KillmailID:   notification.Killmail.ID,
+		KillmailTime: notification.Killmail.KillmailTime,
+		ShipName:     "Unknown",
+		Side:         "Unknown",
+		Value:        notification.Value(),
+	}
+
+	if notification.Details == nil || notification.Details.Victim == nil {
+		return kill
+	}
+
+	victim := notification.Details.Victim
+	kill.ShipName = victim.ShipName()
+
+	switch {
+	case victim.Alliance != nil:
+		kill.Side = victim.Alliance.Name
+	case victim.Corporation != nil:
+		kill.Side = victim.Corporation.Name
+	case victim.Faction != nil:
+		kill.Side = victim.Faction.Name
+	}
+
+	return kill
+
+}
+
+// AddKill adds a kill to the battle and extends the battle to cover it. False is returned
+// when the kill is already part of the battle, such as when its delivery is retried
+func (b *Battle) AddKill(kill *BattleKill) bool {
+
+	for _, k := range b.Kills {
+		if k.KillmailID == kill.KillmailID {
+			return false
+		}
+	}
+
+	b.Kills = append(b.Kills, kill)
+
+	if b.StartedAt.IsZero() || kill.KillmailTime.Before(b.StartedAt) {
+		b.StartedAt = kill.KillmailTime
+	}
+	if kill.KillmailTime.After(b.EndedAt) {
+		b.EndedAt = kill.KillmailTime
+	}
+
+	return true
+
+}
+
+// Sides returns the losses of each side of the battle, the side that lost the most ISK first
+func (b *Battle) Sides() []*BattleSide {
+
+	sides := make([]*BattleSide, 0)
+	index := make(map[string]*BattleSide)
+	for _, kill := range b.Kills {
+		side, ok := index[kill.Side]
+		if !ok {
+			side = &BattleSide{Name: kill.Side}
+			index[kill.Side] = side
+			sides = append(sides, side)
+		}
+		side.Losses++
+		side.Value += kill.Value
+	}
+
+	sort.SliceStable(sides, func(i, j int) bool {
+		return sides[i].Value > sides[j].Value
+	})
+
+	return sides
+
+}
+
+// TotalValue returns the ISK destroyed over the whole battle
+func (b *Battle) TotalValue() float64 {
+
+	var total float64
+	for _, kill := range b.Kills {
+		total += kill.Value
+	}
+
+	return total
+
+}
+
+// Title is a one line description of the battle, ie. Battle in Jita
+func (b *Battle) Title() string {
+	if b.SolarSystemName == "" {
+		return fmt.Sprintf("Battle in solar system %d", b.SolarSystemID)
+	}
+	return fmt.Sprintf("Battle in %s", b.SolarSystemName)
+}
+
+// ZKillboardURL returns the related kills page of zkillboard for the solar system and hour that the battle started in
+func (b *Battle) ZKillboardURL() string {
+	return fmt.Sprintf("https://zkillboard.com/related/%d/%s/", b.SolarSystemID, b.StartedAt.UTC().Format("200601021500"))
+}
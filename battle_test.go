@@ -0,0 +1,52 @@
+package zrule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestBattleAddKillTotalsSides(t *testing.T) {
+
+	start := time.Date(2020, time.November, 3, 18, 42, 0, 0, time.UTC)
+	battle := &zrule.Battle{SolarSystemID: 30000142}
+
+	kills := []*zrule.BattleKill{
+		{KillmailID: 1, KillmailTime: start.Add(time.Minute * 5), Side: "Goonswarm Federation", Value: 100e6},
+		{KillmailID: 2, KillmailTime: start, Side: "Pandemic Horde", Value: 250e6},
+		{KillmailID: 3, KillmailTime: start.Add(time.Minute * 9), Side: "Goonswarm Federation", Value: 200e6},
+	}
+
+	for _, kill := range kills {
+		if !battle.AddKill(kill) {
+			t.Fatalf("expected kill %d to be added", kill.KillmailID)
+		}
+	}
+
+	if battle.AddKill(&zrule.BattleKill{KillmailID: 2, KillmailTime: start, Side: "Pandemic Horde", Value: 250e6}) {
+		t.Error("expected a kill that is already part of the battle to be ignored")
+	}
+
+	if !battle.StartedAt.Equal(start) || !battle.EndedAt.Equal(start.Add(time.Minute*9)) {
+		t.Errorf("expected battle to span the kills, got %s to %s", battle.StartedAt, battle.EndedAt)
+	}
+
+	if total := battle.TotalValue(); total != 550e6 {
+		t.Errorf("expected total value of 550m, got %f", total)
+	}
+
+	sides := battle.Sides()
+	if len(sides) != 2 {
+		t.Fatalf("expected 2 sides, got %d", len(sides))
+	}
+
+	if sides[0].Name != "Goonswarm Federation" || sides[0].Losses != 2 || sides[0].Value != 300e6 {
+		t.Errorf("expected the side that lost the most isk first, got %+v", sides[0])
+	}
+
+	if url := battle.ZKillboardURL(); url != "https://zkillboard.com/related/30000142/202011031800/" {
+		t.Errorf("unexpected related kills url %s", url)
+	}
+
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/eveisesi/zrule/internal/backtest"
+	"github.com/eveisesi/zrule/internal/killmail"
+	"github.com/eveisesi/zrule/internal/mdb"
+	"github.com/eveisesi/zrule/internal/policy"
+	"github.com/urfave/cli"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var backtestFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "policy",
+		Usage: "ID of the policy to backtest",
+	},
+	cli.StringFlag{
+		Name:  "from",
+		Usage: "RFC3339 timestamp to start the backtest at. Defaults to 24 hours before --to",
+	},
+	cli.StringFlag{
+		Name:  "to",
+		Usage: "RFC3339 timestamp to end the backtest at. Defaults to now",
+	},
+	cli.StringFlag{
+		Name:  "file",
+		Usage: "Path to a file of newline delimited killmails to backtest against instead of the killmail archive",
+	},
+	cli.IntFlag{
+		Name:  "sample",
+		Usage: "Number of matched killmails to include in the result",
+	},
+	cli.StringFlag{
+		Name:  "out",
+		Usage: "Path to write the result to. Defaults to stdout",
+	},
+}
+
+func backtestCommand(c *cli.Context) {
+
+	basics := basics("backtest")
+
+	ctx := context.Background()
+
+	policyID, err := primitive.ObjectIDFromHex(c.String("policy"))
+	if err != nil {
+		basics.logger.WithError(err).Fatal("a valid policy id is required")
+	}
+
+	var params = zrule.BacktestParams{
+		SampleSize: c.Int("sample"),
+	}
+
+	params.End = time.Now()
+	if c.String("to") != "" {
+		params.End, err = time.Parse(time.RFC3339, c.String("to"))
+		if err != nil {
+			basics.logger.WithError(err).Fatal("failed to parse --to")
+		}
+	}
+
+	params.Start = params.End.Add(-time.Hour * 24)
+	if c.String("from") != "" {
+		params.Start, err = time.Parse(time.RFC3339, c.String("from"))
+		if err != nil {
+			basics.logger.WithError(err).Fatal("failed to parse --from")
+		}
+	}
+
+	policyRepo, err := mdb.NewPolicyRepository(basics.db)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize policyRepo")
+	}
+
+	killmailRepo, err := mdb.NewKillmailRepository(basics.db, basics.cfg.Retention.Killmails)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize killmailRepo")
+	}
+
+	repos := initializeRepositories(basics)
+	universeServ := newUniverseService(basics, repos)
+
+	policy, err := policy.NewService(universeServ, policyRepo).Policy(ctx, policyID)
+	if err != nil {
+		basics.logger.WithError(err).WithField("policyID", policyID.Hex()).Fatal("failed to look up policy")
+	}
+
+	backtestServ := backtest.NewService(basics.logger, killmail.NewService(basics.logger, universeServ, killmailRepo))
+
+	var result *zrule.BacktestResult
+	if c.String("file") != "" {
+		file, err := os.Open(c.String("file"))
+		if err != nil {
+			basics.logger.WithError(err).Fatal("failed to open killmail file")
+		}
+		defer file.Close()
+
+		result, err = backtestServ.BacktestReader(ctx, policy, file, params)
+		if err != nil {
+			basics.logger.WithError(err).Fatal("failed to backtest policy against file")
+		}
+	} else {
+		result, err = backtestServ.Backtest(ctx, policy, params)
+		if err != nil {
+			basics.logger.WithError(err).Fatal("failed to backtest policy against archive")
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if c.String("out") != "" {
+		file, err := os.Create(c.String("out"))
+		if err != nil {
+			basics.logger.WithError(err).Fatal("failed to create output file")
+		}
+		defer file.Close()
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "    ")
+	err = encoder.Encode(result)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to write backtest result")
+	}
+
+	basics.logger.WithField("evaluated", result.Evaluated).WithField("matches", result.Matches).Info("backtest complete")
+
+}
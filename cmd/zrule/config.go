@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -27,6 +28,79 @@ type config struct {
 		Sleep    int    `default:"5"`
 	}
 
+	// Retention controls how long archived documents are kept before
+	// Mongo removes them via their TTL indexes
+	Retention struct {
+		Killmails  time.Duration `default:"720h"`
+		Matches    time.Duration `default:"2160h"`
+		Deliveries time.Duration `default:"336h"`
+		Battles    time.Duration `default:"168h"`
+	}
+
+	// Dispatcher controls how failed deliveries are retried before
+	// they are moved to the dead letter queue
+	Dispatcher struct {
+		MaxAttempts uint          `default:"5"`
+		BackoffBase time.Duration `default:"30s"`
+		BackoffMax  time.Duration `default:"1h"`
+
+		// RateLimit and RateLimitBurst configure the token bucket that is kept per
+		// endpoint in Redis. Limits reported by the endpoint itself are honored on top of these
+		RateLimit      float64 `default:"1"`
+		RateLimitBurst int     `default:"5"`
+
+		// DisableStatusCodes disable an action as soon as its endpoint responds with one of them.
+		// DisableAfter is the number of server errors in a row that disable an action
+		DisableStatusCodes []int `default:"401,404"`
+		DisableAfter       uint  `default:"5"`
+
+		// CoalesceWindow is how long matches of the same killmail are held so that they are sent to an action together.
+		// Every match is delayed by the window, so it is off unless configured
+		CoalesceWindow time.Duration `default:"0s"`
+
+		Workers    int `default:"10"`
+		MaxPerHost int `default:"4"`
+
+		RestTimeout time.Duration `default:"10s"`
+
+		// AllowInternalEndpoints lets actions point at loopback, private, and link local addresses. It should
+		// only be set when pointing actions at local stand-ins, since users can read what those addresses respond with
+		AllowInternalEndpoints bool `default:"false"`
+
+		// AppURL is the base URL of the frontend that notifications link to
+		AppURL string
+
+		// ESIURL is where EVE mail is sent, which can be pointed at a local ESI stand-in. EVEMailInterval
+		// and EVEMailBurst limit how much mail a single character sends
+		ESIURL          string        `default:"https://esi.evetech.net"`
+		EVEMailInterval time.Duration `default:"15s"`
+		EVEMailBurst    int           `default:"4"`
+	}
+
+	// SMTP is the server that email actions are relayed through. StartTLS
+	// should only be disabled when pointing at a local SMTP stand-in
+	SMTP struct {
+		Host     string
+		Port     int `default:"587"`
+		Username string
+		Password string
+		From     string
+		StartTLS bool          `default:"true"`
+		Timeout  time.Duration `default:"30s"`
+	}
+
+	// WebPush configures the VAPID keys that web push notifications are sent with. Generate
+	// a private key with `zrule vapid`. Subject is a mailto: or https: contact for push services
+	WebPush struct {
+		Subject    string
+		PrivateKey string
+	}
+
+	// Admin lists the characters that are allowed to use the admin endpoints
+	Admin struct {
+		CharacterIDs []uint64
+	}
+
 	Redis struct {
 		Host                    string
 		Port                    uint
@@ -50,6 +124,8 @@ type config struct {
 
 	Server struct {
 		Port uint
+		// URL is the public base URL of the API, which the links that confirm the addresses of email actions point at
+		URL string
 	}
 
 	Auth struct {
@@ -74,6 +150,29 @@ func (c config) validateEnvironment() bool {
 	return false
 }
 
+// validateDispatcher rejects dispatcher settings that the rate limiters cannot work with
+func (c config) validateDispatcher() error {
+
+	if c.Dispatcher.RateLimit <= 0 {
+		return fmt.Errorf("invalid DISPATCHER_RATELIMIT %v declared, must be greater than zero", c.Dispatcher.RateLimit)
+	}
+
+	if c.Dispatcher.RateLimitBurst < 1 {
+		return fmt.Errorf("invalid DISPATCHER_RATELIMITBURST %d declared, must be at least one", c.Dispatcher.RateLimitBurst)
+	}
+
+	if c.Dispatcher.EVEMailInterval <= 0 {
+		return fmt.Errorf("invalid DISPATCHER_EVEMAILINTERVAL %s declared, must be greater than zero", c.Dispatcher.EVEMailInterval)
+	}
+
+	if c.Dispatcher.EVEMailBurst < 1 {
+		return fmt.Errorf("invalid DISPATCHER_EVEMAILBURST %d declared, must be at least one", c.Dispatcher.EVEMailBurst)
+	}
+
+	return nil
+
+}
+
 func loadConfig() (cfg config, err error) {
 	_ = godotenv.Load(".env")
 
@@ -86,6 +185,11 @@ func loadConfig() (cfg config, err error) {
 		return config{}, fmt.Errorf("invalid env %s declared", cfg.Env)
 	}
 
+	err = cfg.validateDispatcher()
+	if err != nil {
+		return config{}, err
+	}
+
 	return
 
 }
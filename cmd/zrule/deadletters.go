@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/urfave/cli"
+)
+
+func deadLettersListCommand(c *cli.Context) {
+
+	basics := basics("deadletters")
+
+	letters, err := newDispatcherService(basics, newActionService(basics)).DeadLetters(context.Background())
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to list dead letters")
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(letters)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to write dead letters")
+	}
+
+}
+
+func deadLettersReplayCommand(c *cli.Context) {
+
+	basics := basics("deadletters")
+
+	if !c.Args().Present() {
+		basics.logger.Fatal("at least one dead letter id is required")
+	}
+
+	dispatcher := newDispatcherService(basics, newActionService(basics))
+
+	for _, id := range c.Args() {
+		err := dispatcher.ReplayDeadLetter(context.Background(), id)
+		if err != nil {
+			basics.logger.WithError(err).WithField("id", id).Error("failed to replay dead letter")
+			continue
+		}
+
+		basics.logger.WithField("id", id).Info("dead letter replayed")
+	}
+
+}
+
+func deadLettersPurgeCommand(c *cli.Context) {
+
+	basics := basics("deadletters")
+
+	count, err := newDispatcherService(basics, newActionService(basics)).PurgeDeadLetters(context.Background(), c.Args()...)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to purge dead letters")
+	}
+
+	basics.logger.WithField("count", count).Info("dead letters purged")
+
+}
@@ -1,40 +1,231 @@
 package main
 
 import (
+	"context"
+	nethttp "net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/eveisesi/zrule/internal/action"
+	"github.com/eveisesi/zrule/internal/battle"
+	"github.com/eveisesi/zrule/internal/delivery"
 	"github.com/eveisesi/zrule/internal/dispatcher"
+	"github.com/eveisesi/zrule/internal/email"
+	"github.com/eveisesi/zrule/internal/killmail"
+	"github.com/eveisesi/zrule/internal/match"
 	"github.com/eveisesi/zrule/internal/mdb"
+	"github.com/eveisesi/zrule/internal/netguard"
 	"github.com/eveisesi/zrule/internal/policy"
+	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/urfave/cli"
 )
 
 func dispatcherCommand(c *cli.Context) {
 	basics := basics("dispatcher")
 
-	// Initialize Repositories
-	actionRepo, err := mdb.NewActionRepository(basics.db)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	osSignals := make(chan os.Signal, 1)
+	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-osSignals
+		basics.logger.WithField("sig", sig).Info("interrupt signal received, starting dispatcher shutdown")
+		cancel()
+	}()
+
+	// Only the long running processes prepare existing actions, so that the dead letter commands do not touch them
+	actionServ := newActionService(basics)
+	backfillSecrets(basics, actionServ)
+	requireEmailConfirmations(basics, actionServ, newConfirmer(basics))
+
+	err := newDispatcherService(basics, actionServ).Run(ctx)
 	if err != nil {
-		basics.logger.WithError(err).Fatal("failed to initialize actionRepo")
+		basics.logger.WithError(err).Fatal("failed to initialize and run dispatcher service")
 	}
 
-	basics.logger.Info("actionRepo initialized")
+	basics.logger.Info("dispatcher gracefully shutdown successfully")
+}
 
+func newDispatcherService(basics *app, actionServ action.Service) dispatcher.Service {
+
+	// Initialize Repositories
 	policyRepo, err := mdb.NewPolicyRepository(basics.db)
 	if err != nil {
 		basics.logger.WithError(err).Fatal("failed to initialize policyRepo")
 	}
 
 	basics.logger.Info("policyRepo initialized")
+
+	matchRepo, err := mdb.NewMatchRepository(basics.db, basics.cfg.Retention.Matches)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize matchRepo")
+	}
+
+	basics.logger.Info("matchRepo initialized")
+
+	deliveryRepo, err := mdb.NewDeliveryRepository(basics.db, basics.cfg.Retention.Deliveries)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize deliveryRepo")
+	}
+
+	basics.logger.Info("deliveryRepo initialized")
+
+	killmailRepo, err := mdb.NewKillmailRepository(basics.db, basics.cfg.Retention.Killmails)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize killmailRepo")
+	}
+
+	basics.logger.Info("killmailRepo initialized")
+
 	repos := initializeRepositories(basics)
-	err = dispatcher.NewService(
+	universeServ := newUniverseService(basics, repos)
+	return dispatcher.NewService(
 		basics.redis,
 		basics.logger,
 		basics.newrelic,
-		basics.client,
-		policy.NewService(newUniverseService(basics, repos), policyRepo),
-		action.NewService(actionRepo),
-	).Run()
+		deliveryClient(basics),
+		dispatcherConfig(basics),
+		policy.NewService(universeServ, policyRepo),
+		actionServ,
+		killmail.NewService(basics.logger, universeServ, killmailRepo),
+		match.NewService(matchRepo),
+		delivery.NewService(deliveryRepo),
+		newUserService(basics, newTokenService(basics), universeServ),
+		newBattleService(basics),
+		newSubscriptionService(basics),
+	)
+
+}
+
+func newActionService(basics *app) action.Service {
+
+	actionRepo, err := mdb.NewActionRepository(basics.db)
 	if err != nil {
-		basics.logger.WithError(err).Fatal("failed to initialize and run dispatcher service")
+		basics.logger.WithError(err).Fatal("failed to initialize actionRepo")
+	}
+
+	basics.logger.Info("actionRepo initialized")
+
+	return action.NewService(actionRepo)
+
+}
+
+// backfillSecrets generates secrets for REST actions that were created before they had one, which
+// are refused by the dispatcher until they do. It is run by the dispatcher and the server when they start up
+func backfillSecrets(basics *app, actionServ action.Service) {
+
+	count, err := actionServ.BackfillSecrets(context.Background())
+	if err != nil {
+		basics.logger.WithError(err).Error("failed to backfill secrets of rest actions")
+		return
+	}
+
+	if count > 0 {
+		basics.logger.WithField("count", count).Info("generated secrets for rest actions")
 	}
+
+}
+
+// deliveryClient returns the client that deliveries are made with, which refuses to connect to internal addresses
+// unless they are allowed, so that an action cannot be pointed at the network that zrule runs in
+func deliveryClient(basics *app) *nethttp.Client {
+
+	if basics.cfg.Dispatcher.AllowInternalEndpoints {
+		return basics.client
+	}
+
+	return &nethttp.Client{
+		Timeout:   basics.client.Timeout,
+		Transport: newrelic.NewRoundTripper(netguard.Transport()),
+	}
+
+}
+
+func newBattleService(basics *app) battle.Service {
+
+	battleRepo, err := mdb.NewBattleRepository(basics.db, basics.cfg.Retention.Battles)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize battleRepo")
+	}
+
+	basics.logger.Info("battleRepo initialized")
+
+	return battle.NewService(battleRepo)
+
+}
+
+func dispatcherConfig(basics *app) dispatcher.Config {
+	cfg := basics.cfg
+	return dispatcher.Config{
+		MaxAttempts: cfg.Dispatcher.MaxAttempts,
+		BackoffBase: cfg.Dispatcher.BackoffBase,
+		BackoffMax:  cfg.Dispatcher.BackoffMax,
+
+		RateLimit:      cfg.Dispatcher.RateLimit,
+		RateLimitBurst: cfg.Dispatcher.RateLimitBurst,
+
+		DisableStatusCodes: cfg.Dispatcher.DisableStatusCodes,
+		DisableAfter:       cfg.Dispatcher.DisableAfter,
+
+		CoalesceWindow: cfg.Dispatcher.CoalesceWindow,
+
+		Workers:    cfg.Dispatcher.Workers,
+		MaxPerHost: cfg.Dispatcher.MaxPerHost,
+
+		RestTimeout: cfg.Dispatcher.RestTimeout,
+		AppURL:      cfg.Dispatcher.AppURL,
+
+		ESIURL:          cfg.Dispatcher.ESIURL,
+		UserAgent:       userAgent,
+		EVEMailInterval: cfg.Dispatcher.EVEMailInterval,
+		EVEMailBurst:    cfg.Dispatcher.EVEMailBurst,
+
+		VAPID: newVAPID(basics),
+
+		Email: emailConfig(basics),
+	}
+}
+
+func emailConfig(basics *app) email.Config {
+	cfg := basics.cfg
+	return email.Config{
+		Host:     cfg.SMTP.Host,
+		Port:     cfg.SMTP.Port,
+		Username: cfg.SMTP.Username,
+		Password: cfg.SMTP.Password,
+		From:     cfg.SMTP.From,
+		StartTLS: cfg.SMTP.StartTLS,
+		Timeout:  cfg.SMTP.Timeout,
+	}
+}
+
+func newConfirmer(basics *app) *email.Confirmer {
+	return email.NewConfirmer(emailConfig(basics), basics.cfg.Server.URL)
+}
+
+// requireEmailConfirmations disables the email actions that were created before their addresses had to opt in,
+// and mails those addresses their confirmations
+func requireEmailConfirmations(basics *app, actionServ action.Service, confirmer *email.Confirmer) {
+
+	ctx := context.Background()
+
+	actions, err := actionServ.RequireEmailConfirmations(ctx)
+	if err != nil {
+		basics.logger.WithError(err).Error("failed to require confirmations of email actions")
+	}
+
+	for _, action := range actions {
+		err = confirmer.SendConfirmations(ctx, action)
+		if err != nil {
+			basics.logger.WithError(err).WithField("actionID", action.ID.Hex()).Error("failed to send confirmations of email action")
+		}
+	}
+
+	if len(actions) > 0 {
+		basics.logger.WithField("count", len(actions)).Info("disabled email actions until their addresses confirm")
+	}
+
 }
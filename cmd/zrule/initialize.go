@@ -14,7 +14,7 @@ func initializeCommand(c *cli.Context) {
 
 	ctx := context.Background()
 
-	esiServ := esi.NewService(basics.redis, "zrule v0.1.0")
+	esiServ := esi.NewService(basics.redis, userAgent)
 
 	status, m := esiServ.GetStatus(ctx)
 	if m.IsErr() {
@@ -104,6 +104,40 @@ func main() {
 			Aliases: []string{"d"},
 			Action:  dispatcherCommand,
 		},
+		cli.Command{
+			Name:   "backtest",
+			Usage:  "Evaluates a policy against the killmail archive, or a file of killmails, and reports how often it would have matched",
+			Flags:  backtestFlags,
+			Action: backtestCommand,
+		},
+		cli.Command{
+			Name:  "deadletters",
+			Usage: "Inspect, replay, and purge deliveries that exhausted their attempts",
+			Subcommands: []cli.Command{
+				cli.Command{
+					Name:   "list",
+					Usage:  "Lists every dead letter in the queue",
+					Action: deadLettersListCommand,
+				},
+				cli.Command{
+					Name:      "replay",
+					Usage:     "Pushes dead letters back onto the matched queue",
+					ArgsUsage: "<id> [<id>...]",
+					Action:    deadLettersReplayCommand,
+				},
+				cli.Command{
+					Name:      "purge",
+					Usage:     "Deletes dead letters. Deletes every dead letter when no ids are provided",
+					ArgsUsage: "[<id>...]",
+					Action:    deadLettersPurgeCommand,
+				},
+			},
+		},
+		cli.Command{
+			Name:   "vapid",
+			Usage:  "Generates the VAPID keys that web push notifications are sent with",
+			Action: vapidCommand,
+		},
 		cli.Command{
 			Name:    "initialize",
 			Aliases: []string{"i"},
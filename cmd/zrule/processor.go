@@ -1,6 +1,8 @@
 package main
 
 import (
+	"github.com/eveisesi/zrule/internal/killmail"
+	"github.com/eveisesi/zrule/internal/match"
 	"github.com/eveisesi/zrule/internal/mdb"
 	"github.com/eveisesi/zrule/internal/policy"
 	"github.com/eveisesi/zrule/internal/processor"
@@ -17,6 +19,20 @@ func processorCommand(c *cli.Context) {
 	}
 
 	basics.logger.Info("policyRepo initialized")
+
+	killmailRepo, err := mdb.NewKillmailRepository(basics.db, basics.cfg.Retention.Killmails)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize killmailRepository")
+	}
+
+	basics.logger.Info("killmailRepo initialized")
+
+	matchRepo, err := mdb.NewMatchRepository(basics.db, basics.cfg.Retention.Matches)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize matchRepository")
+	}
+
+	basics.logger.Info("matchRepo initialized")
 	repos := initializeRepositories(basics)
 
 	universeServ := newUniverseService(basics, repos)
@@ -25,8 +41,9 @@ func processorCommand(c *cli.Context) {
 		basics.redis,
 		basics.logger,
 		basics.newrelic,
+		killmail.NewService(basics.logger, universeServ, killmailRepo),
+		match.NewService(matchRepo),
 		policy.NewService(universeServ, policyRepo),
-		universeServ,
 	).Run(5)
 	if err != nil {
 		basics.logger.WithError(err).Fatal("failed to start processor service")
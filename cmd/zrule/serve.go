@@ -10,15 +10,15 @@ import (
 
 	"github.com/eveisesi/zrule/internal/search"
 
-	"golang.org/x/oauth2"
-
 	"github.com/eveisesi/zrule/internal/action"
+	"github.com/eveisesi/zrule/internal/backtest"
+	"github.com/eveisesi/zrule/internal/delivery"
 	"github.com/eveisesi/zrule/internal/dispatcher"
 	"github.com/eveisesi/zrule/internal/http"
+	"github.com/eveisesi/zrule/internal/killmail"
+	"github.com/eveisesi/zrule/internal/match"
 	"github.com/eveisesi/zrule/internal/mdb"
 	"github.com/eveisesi/zrule/internal/policy"
-	"github.com/eveisesi/zrule/internal/token"
-	"github.com/eveisesi/zrule/internal/user"
 
 	"github.com/urfave/cli"
 )
@@ -42,30 +42,28 @@ func serveCommand(c *cli.Context) {
 
 	basics.logger.Info("policyRepo initialized")
 
-	basics.logger.Info("solarSystemRepo initialized")
+	matchRepo, err := mdb.NewMatchRepository(basics.db, basics.cfg.Retention.Matches)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize matchRepo")
+	}
+
+	basics.logger.Info("matchRepo initialized")
 
-	userRepo, err := mdb.NewUserRepository(basics.db)
+	deliveryRepo, err := mdb.NewDeliveryRepository(basics.db, basics.cfg.Retention.Deliveries)
 	if err != nil {
-		basics.logger.WithError(err).Fatal("failed to initialize userRepo")
+		basics.logger.WithError(err).Fatal("failed to initialize deliveryRepo")
 	}
 
-	basics.logger.Info("userRepo initialized")
-
-	tokenServ := token.NewService(
-		basics.client,
-		&oauth2.Config{
-			ClientID:     basics.cfg.Auth.ClientID,
-			ClientSecret: basics.cfg.Auth.ClientSecret,
-			Endpoint: oauth2.Endpoint{
-				AuthURL:   basics.cfg.Auth.AuthorizationURL,
-				TokenURL:  basics.cfg.Auth.TokenURL,
-				AuthStyle: oauth2.AuthStyleInHeader,
-			},
-		},
-		basics.logger,
-		basics.redis,
-		basics.cfg.Auth.JWKSURL,
-	)
+	basics.logger.Info("deliveryRepo initialized")
+
+	killmailRepo, err := mdb.NewKillmailRepository(basics.db, basics.cfg.Retention.Killmails)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize killmailRepo")
+	}
+
+	basics.logger.Info("killmailRepo initialized")
+
+	tokenServ := newTokenService(basics)
 
 	basics.logger.Info("tokenServ service initialized")
 
@@ -83,16 +81,31 @@ func serveCommand(c *cli.Context) {
 	universeServ := newUniverseService(basics, repos)
 
 	actionServ := action.NewService(actionRepo)
-	userServ := user.NewService(basics.logger, basics.redis, tokenServ, universeServ, userRepo)
+	backfillSecrets(basics, actionServ)
+	confirmer := newConfirmer(basics)
+	requireEmailConfirmations(basics, actionServ, confirmer)
+	userServ := newUserService(basics, tokenServ, universeServ)
 	policyServ := policy.NewService(universeServ, policyRepo)
+	killmailServ := killmail.NewService(basics.logger, universeServ, killmailRepo)
+	matchServ := match.NewService(matchRepo)
+	deliveryServ := delivery.NewService(deliveryRepo)
+	subscriptionServ := newSubscriptionService(basics)
+	vapid := newVAPID(basics)
 
 	dispacther := dispatcher.NewService(
 		basics.redis,
 		basics.logger,
 		basics.newrelic,
-		basics.client,
+		deliveryClient(basics),
+		dispatcherConfig(basics),
 		policyServ,
 		actionServ,
+		killmailServ,
+		matchServ,
+		deliveryServ,
+		userServ,
+		newBattleService(basics),
+		subscriptionServ,
 	)
 	if err != nil {
 		basics.logger.WithError(err).Fatal("failed to initialize and run dispatcher service")
@@ -111,6 +124,15 @@ func serveCommand(c *cli.Context) {
 		universeServ,
 		dispacther,
 		searchServ,
+		backtest.NewService(basics.logger, killmailServ),
+		matchServ,
+		deliveryServ,
+		killmailServ,
+		subscriptionServ,
+		vapid,
+		confirmer,
+		basics.cfg.Dispatcher.AllowInternalEndpoints,
+		basics.cfg.Admin.CharacterIDs,
 	)
 
 	serverErrors := make(chan error, 1)
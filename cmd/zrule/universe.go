@@ -5,9 +5,11 @@ import (
 	"github.com/eveisesi/zrule/internal/universe"
 )
 
+const userAgent = "zrule v0.1.0"
+
 func newUniverseService(basics *app, repos repositories) universe.Service {
 
-	esiServ := esi.NewService(basics.redis, "zrule v0.1.0")
+	esiServ := esi.NewService(basics.redis, userAgent)
 	return universe.NewService(
 		basics.redis, basics.newrelic, esiServ,
 		repos.alliance, repos.corporation, repos.character,
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/eveisesi/zrule/internal/mdb"
+	"github.com/eveisesi/zrule/internal/token"
+	"github.com/eveisesi/zrule/internal/universe"
+	"github.com/eveisesi/zrule/internal/user"
+	"golang.org/x/oauth2"
+)
+
+func newTokenService(basics *app) token.Service {
+
+	return token.NewService(
+		basics.client,
+		&oauth2.Config{
+			ClientID:     basics.cfg.Auth.ClientID,
+			ClientSecret: basics.cfg.Auth.ClientSecret,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   basics.cfg.Auth.AuthorizationURL,
+				TokenURL:  basics.cfg.Auth.TokenURL,
+				AuthStyle: oauth2.AuthStyleInHeader,
+			},
+		},
+		basics.logger,
+		basics.redis,
+		basics.cfg.Auth.JWKSURL,
+	)
+
+}
+
+func newUserService(basics *app, tokenServ token.Service, universeServ universe.Service) user.Service {
+
+	userRepo, err := mdb.NewUserRepository(basics.db)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize userRepo")
+	}
+
+	basics.logger.Info("userRepo initialized")
+
+	return user.NewService(basics.logger, basics.redis, tokenServ, universeServ, userRepo)
+
+}
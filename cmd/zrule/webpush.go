@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/eveisesi/zrule/internal/mdb"
+	"github.com/eveisesi/zrule/internal/subscription"
+	"github.com/eveisesi/zrule/internal/webpush"
+	"github.com/urfave/cli"
+)
+
+// vapidCommand generates a VAPID key pair for the WEBPUSH_PRIVATEKEY setting. It does not need any of
+// the services that basics connects to, so that keys can be generated before anything else is set up
+func vapidCommand(c *cli.Context) {
+
+	public, private, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("WEBPUSH_PRIVATEKEY=%s\n", private)
+	fmt.Printf("# public key, derived from the private key: %s\n", public)
+
+}
+
+// newVAPID returns the VAPID keys that web push notifications are sent with, or nil when web push is not configured
+func newVAPID(basics *app) *webpush.VAPID {
+
+	if basics.cfg.WebPush.PrivateKey == "" {
+		return nil
+	}
+
+	vapid, err := webpush.NewVAPID(basics.cfg.WebPush.Subject, basics.cfg.WebPush.PrivateKey)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize vapid keys")
+	}
+
+	return vapid
+
+}
+
+func newSubscriptionService(basics *app) subscription.Service {
+
+	subscriptionRepo, err := mdb.NewPushSubscriptionRepository(basics.db)
+	if err != nil {
+		basics.logger.WithError(err).Fatal("failed to initialize subscriptionRepo")
+	}
+
+	basics.logger.Info("subscriptionRepo initialized")
+
+	return subscription.NewService(subscriptionRepo)
+
+}
@@ -0,0 +1,61 @@
+package zrule
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReasonAwaitingConfirmation is the disabled reason of an email action whose addresses have not all confirmed
+// that they want to receive its emails. Email actions are created disabled and enabled by the last confirmation
+const ReasonAwaitingConfirmation = "waiting for every address to confirm that it wants to receive these emails"
+
+// EmailConfirmation is the opt in of one of the addresses that an email action sends to. The address is mailed
+// a link with the token, which the API never returns, so only the owner of the address can confirm it
+type EmailConfirmation struct {
+	Address     string     `bson:"address" json:"address"`
+	Token       string     `bson:"token" json:"-"`
+	ConfirmedAt *time.Time `bson:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+}
+
+// URL returns the link that confirms the address, on the API at baseURL
+func (c *EmailConfirmation) URL(baseURL string, actionID primitive.ObjectID) string {
+	return fmt.Sprintf("%s/actions/%s/confirm/%s", strings.TrimSuffix(baseURL, "/"), actionID.Hex(), c.Token)
+}
+
+// Unconfirmed returns the addresses of an email action that have not confirmed that they want to receive its emails
+func (a *Action) Unconfirmed() []string {
+
+	confirmed := make(map[string]bool)
+	for _, confirmation := range a.Confirmations {
+		if confirmation.ConfirmedAt != nil {
+			confirmed[strings.ToLower(confirmation.Address)] = true
+		}
+	}
+
+	unconfirmed := make([]string, 0)
+	for _, address := range a.EmailRecipients() {
+		if !confirmed[strings.ToLower(address)] {
+			unconfirmed = append(unconfirmed, address)
+		}
+	}
+
+	return unconfirmed
+
+}
+
+// Confirmation returns the confirmation of the action that token belongs to, or nil when there is not one
+func (a *Action) Confirmation(token string) *EmailConfirmation {
+
+	for _, confirmation := range a.Confirmations {
+		if token != "" && subtle.ConstantTimeCompare([]byte(confirmation.Token), []byte(token)) == 1 {
+			return confirmation
+		}
+	}
+
+	return nil
+
+}
@@ -0,0 +1,34 @@
+package zrule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestActionUnconfirmed(t *testing.T) {
+
+	now := time.Now()
+	action := &zrule.Action{
+		Platform: zrule.PlatformEmail,
+		Endpoint: "mailto:fc@example.com,ceo@example.com",
+		Confirmations: []*zrule.EmailConfirmation{
+			{Address: "FC@example.com", Token: "a", ConfirmedAt: &now},
+			{Address: "ceo@example.com", Token: "b"},
+		},
+	}
+
+	unconfirmed := action.Unconfirmed()
+	if len(unconfirmed) != 1 || unconfirmed[0] != "ceo@example.com" {
+		t.Errorf("expected only ceo@example.com to be unconfirmed, got %v", unconfirmed)
+	}
+
+	if confirmation := action.Confirmation("b"); confirmation == nil || confirmation.Address != "ceo@example.com" {
+		t.Errorf("expected token b to belong to ceo@example.com, got %+v", confirmation)
+	}
+	if confirmation := action.Confirmation(""); confirmation != nil {
+		t.Errorf("expected an empty token not to confirm anything, got %+v", confirmation)
+	}
+
+}
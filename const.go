@@ -34,3 +34,9 @@ const QUEUES_KILLMAIL_PROCESSING = "zrule::killmail::processing"
 const QUEUE_STOP = "zrule::queue::stop"
 const QUEUE_RESTART_TRACKER = "zrule::tracker::restart"
 const QUEUES_KILLMAIL_MATCHED = "zrule::killmail::matched"
+const QUEUES_DISPATCH_RETRY = "zrule::dispatch::retry"
+const QUEUES_DISPATCH_DEADLETTER = "zrule::dispatch::deadletter"
+const CACHE_DISPATCH_COALESCE = "zrule::dispatch::coalesce::%d::%s"
+const CACHE_DISPATCH_COALESCE_LOCK = "zrule::dispatch::coalesce::%d::%s::lock"
+const CACHE_DISPATCH_BATTLE_LOCK = "zrule::dispatch::battle::%s::%s::%d::lock"
+const CACHE_RATELIMIT_BUCKET = "zrule::ratelimit::%s"
@@ -0,0 +1,39 @@
+package zrule
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeadLetter is a delivery to an action that exhausted all of its attempts.
+// Dead letters are kept until they are replayed or purged by an administrator
+type DeadLetter struct {
+	ID           string        `json:"id"`
+	Dispatchable *Dispatchable `json:"dispatchable"`
+	Error        string        `json:"error"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// UnmarshalJSON decodes a dead letter, reading the time that it was created at from
+// createdAt for the dead letters that were queued before the field was renamed
+func (l *DeadLetter) UnmarshalJSON(data []byte) error {
+
+	type deadLetter DeadLetter
+	var letter struct {
+		deadLetter
+		LegacyCreatedAt *time.Time `json:"createdAt"`
+	}
+
+	err := json.Unmarshal(data, &letter)
+	if err != nil {
+		return err
+	}
+
+	*l = DeadLetter(letter.deadLetter)
+	if l.CreatedAt.IsZero() && letter.LegacyCreatedAt != nil {
+		l.CreatedAt = *letter.LegacyCreatedAt
+	}
+
+	return nil
+
+}
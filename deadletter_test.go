@@ -0,0 +1,29 @@
+package zrule_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestDeadLetterReadsLegacyCreatedAt(t *testing.T) {
+
+	createdAt := time.Date(2020, 11, 9, 0, 40, 2, 0, time.UTC)
+
+	for _, data := range []string{
+		`{"id":"1","error":"failed","created_at":"2020-11-09T00:40:02Z"}`,
+		`{"id":"1","error":"failed","createdAt":"2020-11-09T00:40:02Z"}`,
+	} {
+		letter := new(zrule.DeadLetter)
+		err := json.Unmarshal([]byte(data), letter)
+		if err != nil {
+			t.Fatalf("failed to unmarshal %s: %s", data, err)
+		}
+		if letter.ID != "1" || letter.Error != "failed" || !letter.CreatedAt.Equal(createdAt) {
+			t.Errorf("expected dead letter 1 created at %s, got %+v from %s", createdAt, letter, data)
+		}
+	}
+
+}
@@ -0,0 +1,32 @@
+package zrule
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DeliveryRepository interface {
+	Deliveries(ctx context.Context, operators ...*Operator) ([]*Delivery, error)
+	CreateDelivery(ctx context.Context, delivery *Delivery) (*Delivery, error)
+}
+
+// Delivery is a record of a single attempt to deliver a message to an action. It captures
+// what the remote end responded with so that owners are able to debug their own integrations
+type Delivery struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"_id,omitempty"`
+	ActionID   primitive.ObjectID  `bson:"action_id" json:"action_id"`
+	OwnerID    primitive.ObjectID  `bson:"owner_id" json:"owner_id"`
+	PolicyID   *primitive.ObjectID `bson:"policy_id,omitempty" json:"policy_id,omitempty"`
+	MatchID    *primitive.ObjectID `bson:"match_id,omitempty" json:"match_id,omitempty"`
+	KillmailID uint                `bson:"killmail_id,omitempty" json:"killmail_id,omitempty"`
+	Platform   Platform            `bson:"platform" json:"platform"`
+	Test       bool                `bson:"test" json:"test"`
+	Status     DeliveryStatus      `bson:"status" json:"status"`
+	StatusCode int                 `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	LatencyMS  int64               `bson:"latency_ms" json:"latency_ms"`
+	Response   string              `bson:"response,omitempty" json:"response,omitempty"`
+	Error      *string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+}
@@ -2,9 +2,33 @@ package zrule
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 )
 
 type Dispatcher interface {
-	Send(ctx context.Context, policy *Policy, id uint, hash string) error
+	Send(ctx context.Context, notification *Notification) error
 	SendTest(ctx context.Context, message string) error
 }
+
+// RateLimitError is returned when a delivery cannot be made because the remote end is rate limiting us.
+// RetryAfter is how long to wait before delivering to the same endpoint again
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
+
+// BattleReporter is implemented by platforms that can edit a message after sending it, which lets the matches
+// of a battle report policy be kept in a single message per battle. SendBattleReport returns the id of the
+// message that it sent, which UpdateBattleReport is then given on the battle to edit the message in place
+type BattleReporter interface {
+	SendBattleReport(ctx context.Context, battle *Battle, notification *Notification) (string, error)
+	UpdateBattleReport(ctx context.Context, battle *Battle, notification *Notification) error
+}
+
+// ErrMessageNotFound is returned by UpdateBattleReport when the message of the battle was deleted
+var ErrMessageNotFound = errors.New("message not found")
@@ -0,0 +1,100 @@
+package zrule
+
+import (
+	"fmt"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxEscalations is the number of escalation tiers that a policy can have
+const MaxEscalations = 5
+
+// Escalation is a tier of a policy that notifies additional actions when a match is worth at least MinValue ISK,
+// ie. every match goes to #intel, and matches above 10b ISK also go to the leadership channel
+type Escalation struct {
+	MinValue float64              `bson:"min_value" json:"min_value"`
+	Actions  []primitive.ObjectID `bson:"actions" json:"actions"`
+}
+
+func (e *Escalation) IsValid() error {
+	if e.MinValue <= 0 {
+		return fmt.Errorf("escalation min_value must be greater than zero")
+	}
+	if len(e.Actions) == 0 {
+		return fmt.Errorf("escalation must have at least one action")
+	}
+	return nil
+}
+
+// EscalatedActions returns the actions of every escalation tier that a match worth value reaches,
+// leaving out the actions that the policy already notifies for every match
+func (p *Policy) EscalatedActions(value float64) []primitive.ObjectID {
+
+	seen := make(map[primitive.ObjectID]bool, len(p.Actions))
+	for _, actionID := range p.Actions {
+		seen[actionID] = true
+	}
+
+	actionIDs := make([]primitive.ObjectID, 0)
+	for _, escalation := range p.Escalations {
+		if value < escalation.MinValue {
+			continue
+		}
+		for _, actionID := range escalation.Actions {
+			if seen[actionID] {
+				continue
+			}
+			seen[actionID] = true
+			actionIDs = append(actionIDs, actionID)
+		}
+	}
+
+	return actionIDs
+
+}
+
+// AllActions returns every action that the policy can notify, for every match or through any of its escalation tiers, once each
+func (p *Policy) AllActions() []primitive.ObjectID {
+	return append(append([]primitive.ObjectID{}, p.Actions...), p.EscalatedActions(math.Inf(1))...)
+}
+
+// Reaches reports whether a match worth value is sent to an action, for every match or through an escalation tier that value reaches
+func (p *Policy) Reaches(id primitive.ObjectID, value float64) bool {
+
+	for _, actionID := range p.Actions {
+		if actionID == id {
+			return true
+		}
+	}
+
+	for _, actionID := range p.EscalatedActions(value) {
+		if actionID == id {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// HasAction reports whether the policy notifies an action, for every match or through an escalation tier
+func (p *Policy) HasAction(id primitive.ObjectID) bool {
+
+	for _, actionID := range p.Actions {
+		if actionID == id {
+			return true
+		}
+	}
+
+	for _, escalation := range p.Escalations {
+		for _, actionID := range escalation.Actions {
+			if actionID == id {
+				return true
+			}
+		}
+	}
+
+	return false
+
+}
@@ -0,0 +1,49 @@
+package zrule_test
+
+import (
+	"testing"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPolicyEscalatedActions(t *testing.T) {
+
+	intel, leadership, ceo := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	policy := &zrule.Policy{
+		Actions: []primitive.ObjectID{intel},
+		Escalations: []*zrule.Escalation{
+			{MinValue: 10_000_000_000, Actions: []primitive.ObjectID{leadership, intel}},
+			{MinValue: 50_000_000_000, Actions: []primitive.ObjectID{leadership, ceo}},
+		},
+	}
+
+	tests := []struct {
+		value    float64
+		expected []primitive.ObjectID
+	}{
+		{2_000_000_000, []primitive.ObjectID{}},
+		{12_000_000_000, []primitive.ObjectID{leadership}},
+		{80_000_000_000, []primitive.ObjectID{leadership, ceo}},
+	}
+
+	for _, test := range tests {
+		actions := policy.EscalatedActions(test.value)
+		if len(actions) != len(test.expected) {
+			t.Errorf("expected %d escalated actions for %.0f, got %v", len(test.expected), test.value, actions)
+			continue
+		}
+		for i := range actions {
+			if actions[i] != test.expected[i] {
+				t.Errorf("expected %v for %.0f, got %v", test.expected, test.value, actions)
+				break
+			}
+		}
+	}
+
+	if !policy.HasAction(ceo) || policy.HasAction(primitive.NewObjectID()) {
+		t.Errorf("expected HasAction to include escalated actions only")
+	}
+
+}
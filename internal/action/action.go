@@ -2,14 +2,20 @@ package action
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
 
 	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Service interface {
 	zrule.ActionRepository
+	BackfillSecrets(ctx context.Context) (int, error)
+	RequireEmailConfirmations(ctx context.Context) ([]*zrule.Action, error)
 }
 
 type service struct {
@@ -36,6 +42,165 @@ func (s *service) Actions(ctx context.Context, operators ...*zrule.Operator) ([]
 func (s *service) CreateAction(ctx context.Context, action *zrule.Action) (*zrule.Action, error) {
 
 	action.Infractions = make([]*zrule.Infraction, 0)
+
+	// REST actions sign every request that they receive, so they always need a secret
+	if action.Platform == zrule.PlatformRest && action.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, err
+		}
+		action.Secret = secret
+	}
+
+	// Email actions send to addresses that are not necessarily the owners, so every address has to opt in
+	// before anything is sent to it. The action stays disabled until then
+	action.Confirmations = nil
+	if action.Platform == zrule.PlatformEmail {
+		confirmations, err := newConfirmations(action)
+		if err != nil {
+			return nil, err
+		}
+		action.Confirmations = confirmations
+		action.IsDisabled = true
+		action.DisabledReason = newString(zrule.ReasonAwaitingConfirmation)
+	}
+
+	// EVE mail is sent as the owner of the action and web push notifies the browsers of the owner,
+	// so the owner is what identifies where they are sent
+	switch action.Platform {
+	case zrule.PlatformEVEMail:
+		action.Endpoint = fmt.Sprintf("evemail:%s", action.OwnerID.Hex())
+	case zrule.PlatformWebPush:
+		action.Endpoint = fmt.Sprintf("webpush:%s", action.OwnerID.Hex())
+	}
+
 	return s.ActionRepository.CreateAction(ctx, action)
 
 }
+
+// BackfillSecrets generates a secret for every REST action that was created before REST actions were signed.
+// Requests to a REST action without a secret would be signed with an empty key, which anyone can forge
+func (s *service) BackfillSecrets(ctx context.Context) (int, error) {
+
+	actions, err := s.Actions(ctx, zrule.NewEqualOperator("platform", zrule.PlatformRest))
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch rest actions: %w", err)
+	}
+
+	var count int
+	for _, action := range actions {
+		if action.Secret != "" {
+			continue
+		}
+
+		secret, err := newSecret()
+		if err != nil {
+			return count, err
+		}
+
+		set, err := s.SetActionSecret(ctx, action.ID, secret)
+		if err != nil {
+			return count, fmt.Errorf("failed to set secret of action %s: %w", action.ID.Hex(), err)
+		}
+		if set {
+			count++
+		}
+	}
+
+	return count, nil
+
+}
+
+// ConfirmEmail confirms an address of an email action, and enables the action once every address has confirmed
+func (s *service) ConfirmEmail(ctx context.Context, id primitive.ObjectID, token string) (*zrule.Action, error) {
+
+	action, err := s.ActionRepository.ConfirmEmail(ctx, id, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(action.Unconfirmed()) > 0 || !action.IsDisabled || action.DisabledReason == nil || *action.DisabledReason != zrule.ReasonAwaitingConfirmation {
+		return action, nil
+	}
+
+	err = s.EnableAction(ctx, id, zrule.ReasonAwaitingConfirmation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable confirmed action: %w", err)
+	}
+
+	action.IsDisabled = false
+	action.DisabledReason = nil
+
+	return action, nil
+
+}
+
+// RequireEmailConfirmations disables every email action that was created before their addresses had to opt in,
+// until those addresses confirm, and returns the actions so that the confirmations can be mailed out
+func (s *service) RequireEmailConfirmations(ctx context.Context) ([]*zrule.Action, error) {
+
+	actions, err := s.Actions(ctx, zrule.NewEqualOperator("platform", zrule.PlatformEmail))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch email actions: %w", err)
+	}
+
+	required := make([]*zrule.Action, 0)
+	for _, action := range actions {
+		if len(action.Confirmations) > 0 {
+			continue
+		}
+
+		confirmations, err := newConfirmations(action)
+		if err != nil {
+			return required, err
+		}
+
+		set, err := s.SetEmailConfirmations(ctx, action.ID, confirmations)
+		if err != nil {
+			return required, fmt.Errorf("failed to set confirmations of action %s: %w", action.ID.Hex(), err)
+		}
+		if set {
+			action.Confirmations = confirmations
+			action.IsDisabled = true
+			action.DisabledReason = newString(zrule.ReasonAwaitingConfirmation)
+			required = append(required, action)
+		}
+	}
+
+	return required, nil
+
+}
+
+// newConfirmations generates a confirmation for every address of an email action
+func newConfirmations(action *zrule.Action) ([]*zrule.EmailConfirmation, error) {
+
+	recipients := action.EmailRecipients()
+	confirmations := make([]*zrule.EmailConfirmation, 0, len(recipients))
+	for _, address := range recipients {
+		token, err := newSecret()
+		if err != nil {
+			return nil, err
+		}
+		confirmations = append(confirmations, &zrule.EmailConfirmation{Address: address, Token: token})
+	}
+
+	return confirmations, nil
+
+}
+
+func newString(s string) *string {
+	return &s
+}
+
+// newSecret generates a random secret, which signs the requests to REST actions and confirms the addresses of email actions
+func newSecret() (string, error) {
+
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+
+}
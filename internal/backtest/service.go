@@ -0,0 +1,256 @@
+package backtest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/eveisesi/zrule/internal/killmail"
+	"github.com/eveisesi/zrule/pkg/ruler"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/sirupsen/logrus"
+)
+
+type Service interface {
+	Backtest(ctx context.Context, policy *zrule.Policy, params zrule.BacktestParams) (*zrule.BacktestResult, error)
+	BacktestReader(ctx context.Context, policy *zrule.Policy, reader io.Reader, params zrule.BacktestParams) (*zrule.BacktestResult, error)
+}
+
+type service struct {
+	logger   *logrus.Logger
+	killmail killmail.Service
+}
+
+const (
+	defaultSampleSize = 10
+	maxSampleSize     = 100
+	archivePageSize   = 500
+	maxLineSize       = 4 * 1024 * 1024
+	dayFormat         = "2006-01-02"
+)
+
+func NewService(logger *logrus.Logger, killmail killmail.Service) Service {
+	return &service{
+		logger:   logger,
+		killmail: killmail,
+	}
+}
+
+// Backtest evaluates the policy against the killmail archive. Archived killmails have already been
+// hydrated by the processor before they were stored, so they are evaluated as is
+func (s *service) Backtest(ctx context.Context, policy *zrule.Policy, params zrule.BacktestParams) (*zrule.BacktestResult, error) {
+
+	seg := newrelic.FromContext(ctx).StartSegment("backtest policy against archive")
+	defer seg.End()
+
+	params, err := normalizeParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := newTally(policy, zrule.BacktestSourceArchive, params)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := params.Start, params.End
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	// Killmails are paged through by range on their time and id rather than skipped over, since many killmails
+	// share a time, and killmails that share one with the last killmail of a page would otherwise be
+	// repeated or left out by the next. The id of a killmail is unique, so it breaks the ties
+	var last *zrule.Killmail
+	for {
+		operators := []*zrule.Operator{
+			zrule.NewOrderOperator("killmail_time", zrule.SortAsc),
+			zrule.NewOrderOperator("killmail_id", zrule.SortAsc),
+			zrule.NewLimitOperator(archivePageSize),
+		}
+		if last != nil {
+			operators = append(operators, zrule.NewOrOperator(
+				zrule.NewGreaterThanOperator("killmail_time", last.KillmailTime),
+				zrule.NewAndOperator(
+					zrule.NewEqualOperator("killmail_time", last.KillmailTime),
+					zrule.NewGreaterThanOperator("killmail_id", last.ID),
+				),
+			))
+		}
+
+		killmails, err := s.killmail.KillmailsByTimeRange(ctx, start, end, operators...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch killmails from archive: %w", err)
+		}
+
+		for _, killmail := range killmails {
+			t.evaluate(killmail)
+		}
+
+		if len(killmails) < archivePageSize {
+			break
+		}
+
+		last = killmails[len(killmails)-1]
+	}
+
+	return t.result(), nil
+
+}
+
+// BacktestReader evaluates the policy against killmails read from reader. Each line is expected to
+// be a single killmail in the same format that is received from the zkillboard websocket.
+// These killmails are hydrated exactly as the processor would hydrate them before they are evaluated
+func (s *service) BacktestReader(ctx context.Context, policy *zrule.Policy, reader io.Reader, params zrule.BacktestParams) (*zrule.BacktestResult, error) {
+
+	seg := newrelic.FromContext(ctx).StartSegment("backtest policy against reader")
+	defer seg.End()
+
+	params, err := normalizeParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := newTally(policy, zrule.BacktestSourceFile, params)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var line uint
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+
+		var killmail = new(zrule.Killmail)
+		err := json.Unmarshal(data, killmail)
+		if err != nil {
+			s.logger.WithError(err).WithField("line", line).Error("failed to decode killmail, skipping")
+			continue
+		}
+
+		if !params.Start.IsZero() && killmail.KillmailTime.Before(params.Start) {
+			continue
+		}
+		if !params.End.IsZero() && !killmail.KillmailTime.Before(params.End) {
+			continue
+		}
+
+		s.killmail.Hydrate(ctx, killmail)
+
+		t.evaluate(killmail)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read killmails: %w", err)
+	}
+
+	return t.result(), nil
+
+}
+
+func normalizeParams(params zrule.BacktestParams) (zrule.BacktestParams, error) {
+
+	if !params.Start.IsZero() && !params.End.IsZero() && !params.Start.Before(params.End) {
+		return params, fmt.Errorf("backtest start must be before end")
+	}
+
+	if params.SampleSize <= 0 {
+		params.SampleSize = defaultSampleSize
+	}
+	if params.SampleSize > maxSampleSize {
+		params.SampleSize = maxSampleSize
+	}
+
+	return params, nil
+
+}
+
+// tally tracks the results of evaluating a stream of killmails against a policy.
+// The sample holds the most recently evaluated matches
+type tally struct {
+	ruler  *ruler.Ruler
+	params zrule.BacktestParams
+	res    *zrule.BacktestResult
+	days   map[string]uint
+}
+
+func newTally(policy *zrule.Policy, source zrule.BacktestSource, params zrule.BacktestParams) (*tally, error) {
+
+	if len(policy.Rules) == 0 {
+		return nil, fmt.Errorf("policy does not have any rules to backtest")
+	}
+
+	r, err := policy.Ruler()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tally{
+		ruler:  r,
+		params: params,
+		days:   make(map[string]uint),
+		res: &zrule.BacktestResult{
+			PolicyID: policy.ID,
+			Source:   source,
+			Start:    params.Start,
+			End:      params.End,
+			Sample:   make([]*zrule.Killmail, 0, params.SampleSize),
+		},
+	}, nil
+
+}
+
+func (t *tally) evaluate(killmail *zrule.Killmail) {
+
+	t.res.Evaluated++
+
+	if !t.ruler.Test(killmail) {
+		return
+	}
+
+	t.res.Matches++
+	t.days[killmail.KillmailTime.UTC().Format(dayFormat)]++
+
+	if len(t.res.Sample) == t.params.SampleSize {
+		t.res.Sample = t.res.Sample[1:]
+	}
+	t.res.Sample = append(t.res.Sample, killmail)
+
+}
+
+// result returns the backtest result. When the range is bounded on both sides, every day in the
+// range is included so that days without a match are reported with zero matches
+func (t *tally) result() *zrule.BacktestResult {
+
+	perDay := make([]*zrule.BacktestDay, 0, len(t.days))
+
+	if !t.params.Start.IsZero() && !t.params.End.IsZero() {
+		for day := t.params.Start.UTC().Truncate(time.Hour * 24); day.Before(t.params.End); day = day.Add(time.Hour * 24) {
+			date := day.Format(dayFormat)
+			perDay = append(perDay, &zrule.BacktestDay{Date: date, Matches: t.days[date]})
+		}
+	} else {
+		for date, matches := range t.days {
+			perDay = append(perDay, &zrule.BacktestDay{Date: date, Matches: matches})
+		}
+		sort.Slice(perDay, func(i, j int) bool {
+			return perDay[i].Date < perDay[j].Date
+		})
+	}
+
+	t.res.MatchesPerDay = perDay
+
+	return t.res
+
+}
@@ -0,0 +1,40 @@
+package battle
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type Service interface {
+	zrule.BattleRepository
+}
+
+type service struct {
+	zrule.BattleRepository
+}
+
+func NewService(battle zrule.BattleRepository) Service {
+	return &service{
+		BattleRepository: battle,
+	}
+}
+
+// Battle returns nil when there is no battle in the solar system within the window, so that the caller starts a new one
+func (s *service) Battle(ctx context.Context, policyID, actionID primitive.ObjectID, solarSystemID uint, t time.Time, window time.Duration) (*zrule.Battle, error) {
+
+	battle, err := s.BattleRepository.Battle(ctx, policyID, actionID, solarSystemID, t, window)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return battle, nil
+
+}
@@ -0,0 +1,34 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type Service interface {
+	zrule.DeliveryRepository
+}
+
+type service struct {
+	zrule.DeliveryRepository
+}
+
+func NewService(delivery zrule.DeliveryRepository) Service {
+	return &service{
+		DeliveryRepository: delivery,
+	}
+}
+
+func (s *service) Deliveries(ctx context.Context, operators ...*zrule.Operator) ([]*zrule.Delivery, error) {
+
+	deliveries, err := s.DeliveryRepository.Deliveries(ctx, operators...)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return deliveries, err
+	}
+
+	return deliveries, nil
+
+}
@@ -0,0 +1,111 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// maxBattleSides is the number of sides that are listed on a battle report, the rest are summed up on a single line
+const maxBattleSides = 10
+
+// SendBattleReport posts the report of a battle and returns the id of the message, which the webhook needs to edit it
+func (s *service) SendBattleReport(ctx context.Context, battle *zrule.Battle, notification *zrule.Notification) (string, error) {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send discord battle report")
+	defer seg.End()
+
+	// Only the kill that starts the battle mentions anyone, editing the message would not ping them again
+	text, allowed := mentions(notification)
+	message, err := s.dgo.WebhookExecute(s.id, s.token, true, &discordgo.WebhookParams{
+		Content:         text,
+		Embeds:          []*discordgo.MessageEmbed{battleEmbed(battle, notification)},
+		AllowedMentions: allowed,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return message.ID, nil
+
+}
+
+// UpdateBattleReport edits the message of a battle in place through the webhook that sent it
+func (s *service) UpdateBattleReport(ctx context.Context, battle *zrule.Battle, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("update discord battle report")
+	defer seg.End()
+
+	uri := fmt.Sprintf("%s/messages/%s", discordgo.EndpointWebhookToken(s.id, s.token), battle.MessageID)
+	_, err := s.dgo.RequestWithBucketID(http.MethodPatch, uri, map[string]interface{}{
+		"embeds": []*discordgo.MessageEmbed{battleEmbed(battle, notification)},
+	}, discordgo.EndpointWebhookToken("", "")+"/messages")
+
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound {
+		return zrule.ErrMessageNotFound
+	}
+
+	return err
+
+}
+
+// battleEmbed builds the embed of a battle report, with the ISK that each side has lost and the latest kill of the battle
+func battleEmbed(battle *zrule.Battle, notification *zrule.Notification) *discordgo.MessageEmbed {
+
+	title := battle.Title()
+	if notification.Details != nil && notification.Details.SolarSystem != nil {
+		title = fmt.Sprintf("%s (%s)", title, notification.Details.SecurityStatus())
+	}
+
+	e := &discordgo.MessageEmbed{
+		URL:         battle.ZKillboardURL(),
+		Title:       title,
+		Color:       embedColor,
+		Description: fmt.Sprintf("%d kills, %s destroyed", len(battle.Kills), zrule.FormatISK(battle.TotalValue())),
+		Timestamp:   battle.EndedAt.Format(time.RFC3339),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Matched %s %s, started %s EVE", notification.PolicyNoun(), notification.PolicyNames(), battle.StartedAt.UTC().Format("15:04")),
+		},
+	}
+
+	sides := battle.Sides()
+	for i, side := range sides {
+		if i == maxBattleSides {
+			var losses int
+			var value float64
+			for _, rest := range sides[i:] {
+				losses += rest.Losses
+				value += rest.Value
+			}
+			e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+				Name:   fmt.Sprintf("%d more", len(sides)-i),
+				Value:  fmt.Sprintf("%d losses, %s", losses, zrule.FormatISK(value)),
+				Inline: true,
+			})
+			break
+		}
+
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+			Name:   side.Name,
+			Value:  fmt.Sprintf("%d losses, %s", side.Losses, zrule.FormatISK(side.Value)),
+			Inline: true,
+		})
+	}
+
+	if details := notification.Details; details != nil && details.Victim != nil {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+			Name:  "Latest Kill",
+			Value: fmt.Sprintf("[%s lost a %s worth %s](%s)", details.Victim.Label(), details.Victim.ShipName(), zrule.FormatISK(details.TotalValue), notification.Killmail.ZKillboardURL()),
+		})
+	}
+
+	return e
+
+}
@@ -0,0 +1,129 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/eveisesi/zrule"
+)
+
+// embedColor is the color of the bar down the side of the embed
+const embedColor = 0xB22222
+
+// maxContentLength is the longest message that Discord accepts
+const maxContentLength = 2000
+
+// maxFieldLength is the longest value that Discord accepts for a field of an embed
+const maxFieldLength = 1024
+
+// maxAttackerLines is the number of attackers that are listed on an embed
+const maxAttackerLines = 20
+
+// embed builds the embed for a notification. Notifications without details, such as when the killmail
+// could not be found in the archive, fall back to the policy name and a link to zkillboard
+func embed(notification *zrule.Notification, layout zrule.Layout) *discordgo.MessageEmbed {
+
+	killmail, details, policy := notification.Killmail, notification.Details, notification.Policy
+
+	e := &discordgo.MessageEmbed{
+		URL:    killmail.ZKillboardURL(),
+		Title:  fmt.Sprintf("Killmail %d", killmail.ID),
+		Color:  embedColor,
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Matched %s %s", notification.PolicyNoun(), notification.PolicyNames())},
+	}
+
+	if !killmail.KillmailTime.IsZero() {
+		e.Timestamp = killmail.KillmailTime.Format(time.RFC3339)
+	}
+
+	if details == nil || details.Victim == nil {
+		e.Description = fmt.Sprintf("Match Found with Policy %s (%s)", policy.Name, policy.ID.Hex())
+		return e
+	}
+
+	victim := details.Victim
+	system := details.Location()
+
+	e.Title = fmt.Sprintf("%s destroyed in %s", victim.ShipName(), system)
+	if victim.Ship != nil {
+		e.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: zrule.ShipRenderURL(victim.Ship.ID, 128)}
+	}
+
+	if layout == zrule.LayoutCompact {
+		e.Description = fmt.Sprintf(
+			"%s lost a %s worth %s to %d attacker(s)",
+			victim.Label(), victim.ShipName(), zrule.FormatISK(details.TotalValue), details.AttackerCount,
+		)
+		return e
+	}
+
+	e.Author = &discordgo.MessageEmbedAuthor{Name: victim.Name()}
+	if victim.Character != nil {
+		e.Author.IconURL = zrule.CharacterPortraitURL(victim.Character.ID, 64)
+	}
+
+	location := system
+	if details.Region != nil {
+		location = fmt.Sprintf("%s / %s", system, details.Region.Name)
+	}
+
+	e.Fields = []*discordgo.MessageEmbedField{
+		{Name: "Victim", Value: victim.Label(), Inline: true},
+		{Name: "Ship", Value: victim.ShipName(), Inline: true},
+		{Name: "Value", Value: zrule.FormatISK(details.TotalValue), Inline: true},
+		{Name: "Location", Value: location, Inline: true},
+		{Name: "Attackers", Value: fmt.Sprintf("%d", details.AttackerCount), Inline: true},
+	}
+
+	if details.FinalBlow != nil {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+			Name:   "Final Blow",
+			Value:  fmt.Sprintf("%s flying a %s", details.FinalBlow.Label(), details.FinalBlow.ShipName()),
+			Inline: false,
+		})
+	}
+
+	if len(details.Attackers) > 0 {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+			Name:   "Top Attackers",
+			Value:  attackers(details),
+			Inline: false,
+		})
+	}
+
+	return e
+
+}
+
+// attackers lists the attackers of the killmail, one per line. The list is cut short once it reaches
+// maxAttackerLines or would exceed the length of a field, and the number of attackers left off is noted
+func attackers(details *zrule.KillmailDetails) string {
+
+	var b strings.Builder
+
+	listed := 0
+	for _, attacker := range details.Attackers {
+		if listed == maxAttackerLines {
+			break
+		}
+
+		line := fmt.Sprintf("• %s, %s (%d dmg)\n", attacker.Label(), attacker.ShipName(), attacker.Damage)
+
+		// Leave room for the line noting how many attackers were left off
+		if b.Len()+len(line) > maxFieldLength-64 {
+			break
+		}
+
+		b.WriteString(line)
+		listed++
+	}
+
+	if remaining := details.AttackerCount - listed; remaining > 0 {
+		fmt.Fprintf(&b, "_…and %d more_", remaining)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+
+}
@@ -0,0 +1,32 @@
+package discord
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestAttackersTruncation(t *testing.T) {
+
+	details := &zrule.KillmailDetails{AttackerCount: 300}
+	for i := 0; i < 25; i++ {
+		details.Attackers = append(details.Attackers, &zrule.KillmailParticipant{
+			Character:   &zrule.Character{Name: strings.Repeat("A", 200)},
+			Corporation: &zrule.Corporation{Ticker: "CORP"},
+			Ship:        &zrule.Item{Name: "Rifter"},
+		})
+	}
+
+	text := attackers(details)
+	if len(text) > maxFieldLength {
+		t.Errorf("expected attackers field to be at most %d characters, got %d", maxFieldLength, len(text))
+	}
+
+	listed := strings.Count(text, "•")
+	if listed == 0 || !strings.HasSuffix(text, "more_") || !strings.Contains(text, "and "+strconv.Itoa(300-listed)+" more") {
+		t.Errorf("expected attackers field to note the %d attackers that were left off, got %q", 300-listed, text)
+	}
+
+}
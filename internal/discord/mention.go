@@ -0,0 +1,59 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/eveisesi/zrule"
+)
+
+// mentions renders the mentions of a notification, along with the allowed mentions that let Discord ping exactly
+// them. Anything else in the content that looks like a mention, such as @everyone in a rendered template, is left inert
+func mentions(notification *zrule.Notification) (string, *discordgo.MessageAllowedMentions) {
+
+	allowed := &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}
+	text := make([]string, 0, len(notification.Mentions))
+
+	everyone := false
+	for _, mention := range notification.Mentions {
+		switch mention.Type {
+		case zrule.MentionEveryone:
+			text = append(text, "@everyone")
+			everyone = true
+		case zrule.MentionHere:
+			text = append(text, "@here")
+			everyone = true
+		case zrule.MentionRole:
+			text = append(text, fmt.Sprintf("<@&%s>", mention.ID))
+			allowed.Roles = append(allowed.Roles, mention.ID)
+		case zrule.MentionUser:
+			text = append(text, fmt.Sprintf("<@%s>", mention.ID))
+			allowed.Users = append(allowed.Users, mention.ID)
+		}
+	}
+
+	// Discord allows @everyone and @here together, there is no way to allow only one of them
+	if everyone {
+		allowed.Parse = append(allowed.Parse, discordgo.AllowedMentionTypeEveryone)
+	}
+
+	return strings.Join(text, " "), allowed
+
+}
+
+// inert breaks up @everyone and @here in a rendered template with a zero width space. Allowing the mentions
+// of a link to ping everyone allows it for the whole content, which must not extend to the template
+var inert = strings.NewReplacer("@everyone", "@\u200beveryone", "@here", "@\u200bhere")
+
+// content puts the mentions of a notification in front of its message
+func content(notification *zrule.Notification) (string, *discordgo.MessageAllowedMentions) {
+
+	text, allowed := mentions(notification)
+	if notification.Message != "" {
+		text = strings.TrimSpace(fmt.Sprintf("%s\n%s", text, inert.Replace(notification.Message)))
+	}
+
+	return zrule.Truncate(text, maxContentLength), allowed
+
+}
@@ -0,0 +1,41 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/eveisesi/zrule"
+)
+
+func TestContentAllowsOnlyConfiguredMentions(t *testing.T) {
+
+	notification := &zrule.Notification{
+		Message: "@everyone a titan died",
+		Mentions: []*zrule.Mention{
+			{Type: zrule.MentionRole, ID: "175928847299117063"},
+			{Type: zrule.MentionUser, ID: "80351110224678912"},
+		},
+	}
+
+	text, allowed := content(notification)
+	if text != "<@&175928847299117063> <@80351110224678912>\n@\u200beveryone a titan died" {
+		t.Errorf("unexpected content %q", text)
+	}
+
+	if len(allowed.Parse) != 0 {
+		t.Errorf("expected @everyone in the template to be left inert, got parse %v", allowed.Parse)
+	}
+	if len(allowed.Roles) != 1 || allowed.Roles[0] != "175928847299117063" || len(allowed.Users) != 1 {
+		t.Errorf("expected the role and user to be allowed, got %+v", allowed)
+	}
+
+	notification.Mentions = append(notification.Mentions, &zrule.Mention{Type: zrule.MentionHere})
+	text, allowed = content(notification)
+	if len(allowed.Parse) != 1 || allowed.Parse[0] != discordgo.AllowedMentionTypeEveryone {
+		t.Errorf("expected @here to allow everyone mentions, got parse %v", allowed.Parse)
+	}
+	if text != "<@&175928847299117063> <@80351110224678912> @here\n@\u200beveryone a titan died" {
+		t.Errorf("expected @everyone in the template to stay escaped, got %q", text)
+	}
+
+}
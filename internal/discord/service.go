@@ -15,6 +15,7 @@ import (
 type service struct {
 	dgo       *discordgo.Session
 	id, token string
+	layout    zrule.Layout
 }
 
 func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
@@ -47,27 +48,23 @@ func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, er
 	token = parts[4]
 
 	return &service{
-		dgo:   session,
-		id:    id,
-		token: token,
+		dgo:    session,
+		id:     id,
+		token:  token,
+		layout: action.Layout,
 	}, nil
 }
 
-func (s *service) Send(ctx context.Context, policy *zrule.Policy, id uint, hash string) error {
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
 
 	seg := newrelic.FromContext(ctx).StartSegment("send discord message")
 	defer seg.End()
 
-	uri := url.URL{
-		Scheme: "https",
-		Host:   "zkillboard.com",
-		Path:   fmt.Sprintf("/kill/%d", id),
-	}
-
-	content := fmt.Sprintf("Match Found with Policy %s (%s)\n%s", policy.Name, policy.ID.Hex(), uri.String())
-
+	text, allowed := content(notification)
 	_, err := s.dgo.WebhookExecute(s.id, s.token, true, &discordgo.WebhookParams{
-		Content: content,
+		Content:         text,
+		Embeds:          []*discordgo.MessageEmbed{embed(notification, s.layout)},
+		AllowedMentions: allowed,
 	})
 
 	return err
@@ -0,0 +1,173 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/go-redis/redis/v8"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// battleLockTTL bounds how long a battle stays locked when the dispatcher holding the lock goes away
+const battleLockTTL = time.Second * 30
+
+// battleLockPoll is how often a kill checks whether the battle that it belongs to is free while another kill is being added to it
+const battleLockPoll = time.Millisecond * 100
+
+// unlockScript releases the lock of a battle only while it is still held for the killmail that took it,
+// so that a lock that expired and was taken for another killmail is not released from under it
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+
+return 0
+`)
+
+// send sends a notification to the platform of an action. The matches of a battle report policy are
+// added to the report of the battle that they are part of instead, when the platform can edit messages.
+// Everywhere else, and for killmails without details, a message is sent for every match. A coalesced
+// notification reports the battle of each of its battle report policies, and sends a message for the rest
+func (s *service) send(ctx context.Context, platform zrule.Dispatcher, action *zrule.Action, notification *zrule.Notification) error {
+
+	reporter, ok := platform.(zrule.BattleReporter)
+	if !ok || notification.Details == nil {
+		return platform.Send(ctx, notification)
+	}
+
+	policies := notification.Policies
+	if len(policies) == 0 {
+		policies = []*zrule.Policy{notification.Policy}
+	}
+
+	// Battles are reported before the message of the other policies is sent, since a kill that is already part
+	// of a battle is not reported again, so a retry after a failure only sends what has not been sent yet
+	remaining := make([]*zrule.Policy, 0, len(policies))
+	for _, policy := range policies {
+		if policy.Mode != zrule.PolicyModeBattleReport {
+			remaining = append(remaining, policy)
+			continue
+		}
+
+		report := *notification
+		report.Policy, report.Policies = policy, nil
+		err := s.reportBattle(ctx, reporter, action, &report)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch len(remaining) {
+	case 0:
+		return nil
+	case len(policies):
+		return platform.Send(ctx, notification)
+	}
+
+	rest := *notification
+	rest.Policy, rest.Policies = remaining[0], nil
+	if len(remaining) > 1 {
+		rest.Policies = remaining
+	}
+
+	return platform.Send(ctx, &rest)
+
+}
+
+// reportBattle adds the killmail of the notification to the battle that the policy and action are reporting in
+// its solar system, starting a battle when there is not one within the battle window, and sends or edits the
+// message of the battle. Battles are locked while a kill is added, so that kills that arrive together are all counted,
+// and a kill waits for the lock in the worker so that it is not sent behind the later kills of the action.
+// The battle is saved as pending before its message is touched, so that a kill is never reported without being
+// part of the battle, and a kill whose report failed is reported again when its delivery is retried
+func (s *service) reportBattle(ctx context.Context, reporter zrule.BattleReporter, action *zrule.Action, notification *zrule.Notification) error {
+
+	policy, killmail := notification.Policy, notification.Killmail
+	entry := s.logger.WithField("policyID", policy.ID.Hex()).WithField("actionID", action.ID.Hex())
+
+	lock := fmt.Sprintf(zrule.CACHE_DISPATCH_BATTLE_LOCK, policy.ID.Hex(), action.ID.Hex(), killmail.SolarSystemID)
+	err := s.lockBattle(ctx, lock, killmail.ID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := unlockScript.Run(ctx, s.redis, []string{lock}, killmail.ID).Err()
+		if err != nil {
+			newrelic.FromContext(ctx).NoticeError(err)
+			entry.WithError(err).Error("failed to unlock battle")
+		}
+	}()
+
+	battle, err := s.battle.Battle(ctx, policy.ID, action.ID, killmail.SolarSystemID, killmail.KillmailTime, policy.BattleWindowDuration())
+	if err != nil {
+		return fmt.Errorf("failed to lookup battle: %w", err)
+	}
+	if battle == nil {
+		battle = zrule.NewBattle(notification, action.ID)
+	}
+
+	if !battle.AddKill(zrule.NewBattleKill(notification)) && !battle.Pending {
+		// The kill was reported by an earlier attempt of this delivery
+		return nil
+	}
+
+	battle.Pending = true
+	battle, err = s.battle.SaveBattle(ctx, battle)
+	if err != nil {
+		return fmt.Errorf("failed to save battle: %w", err)
+	}
+
+	if battle.MessageID != "" {
+		err = reporter.UpdateBattleReport(ctx, battle, notification)
+		if errors.Is(err, zrule.ErrMessageNotFound) {
+			// The message was deleted from the channel, so the battle is reported in a new one
+			battle.MessageID = ""
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if battle.MessageID == "" {
+		battle.MessageID, err = reporter.SendBattleReport(ctx, battle, notification)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The report has been sent, so failing to save the battle is not a failed delivery. The battle stays
+	// pending, and the next kill reports it in a new message if the id of this one was not saved
+	battle.Pending = false
+	_, err = s.battle.SaveBattle(ctx, battle)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		entry.WithError(err).Error("failed to save battle")
+	}
+
+	return nil
+
+}
+
+// lockBattle waits until the battle lock is taken for the killmail. The lock expires after battleLockTTL, so
+// a kill never waits for longer than that unless other kills keep taking the lock before it
+func (s *service) lockBattle(ctx context.Context, lock string, killmailID uint) error {
+
+	deadline := time.Now().Add(battleLockTTL)
+	for {
+		locked, err := s.redis.SetNX(ctx, lock, killmailID, battleLockTTL).Result()
+		if err != nil {
+			return fmt.Errorf("failed to lock battle: %w", err)
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for battle lock")
+		}
+
+		sleep(ctx, battleLockPoll)
+	}
+
+}
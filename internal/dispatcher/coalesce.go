@@ -0,0 +1,162 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// coalesceTTL is how many windows a buffer is kept for, so that a buffer whose flush was lost is eventually dropped
+const coalesceTTL = 10
+
+// buffer adds the match of a policy to the buffer of matches of the killmail for an action. The first match of a
+// window schedules the delivery that flushes the buffer once the window has passed, so that every policy that
+// matches the killmail within the window is sent to the action in a single message
+func (s *service) buffer(ctx context.Context, message *zrule.Dispatchable, actionID primitive.ObjectID) error {
+
+	key := fmt.Sprintf(zrule.CACHE_DISPATCH_COALESCE, message.ID, actionID.Hex())
+	lock := fmt.Sprintf(zrule.CACHE_DISPATCH_COALESCE_LOCK, message.ID, actionID.Hex())
+	ttl := s.config.CoalesceWindow * coalesceTTL
+
+	pipe := s.redis.TxPipeline()
+	pipe.SAdd(ctx, key, coalesceMember(message.PolicyID, message.MatchID))
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to buffer match: %w", err)
+	}
+
+	leader, err := s.redis.SetNX(ctx, lock, message.PolicyID.Hex(), ttl).Result()
+	if err != nil {
+		return fmt.Errorf("failed to claim buffer: %w", err)
+	}
+
+	if !leader {
+		// The match will be sent by the flush that the first match of the window scheduled
+		return nil
+	}
+
+	flush := *message
+	flush.ActionID = &actionID
+	flush.Coalesce = true
+
+	data, err := json.Marshal(flush)
+	if err != nil {
+		return fmt.Errorf("failed to marshal flush: %w", err)
+	}
+
+	next := time.Now().Add(s.config.CoalesceWindow)
+	_, err = s.redis.ZAdd(ctx, zrule.QUEUES_DISPATCH_RETRY, &redis.Z{Score: float64(next.UnixNano()), Member: string(data)}).Result()
+	if err != nil {
+		// Release the buffer so that the next match of the killmail schedules a flush in our place
+		_ = s.redis.Del(ctx, lock).Err()
+		return fmt.Errorf("failed to schedule flush: %w", err)
+	}
+
+	return nil
+
+}
+
+// flush empties the buffer that a flush delivery is for and returns the delivery as it should be sent, or nil when
+// the buffer was already emptied. When more than one policy matched, the delivery is sent for the policy that
+// scheduled the flush, or the first policy that matched when that match was flushed by an earlier delivery
+func (s *service) flush(ctx context.Context, message *zrule.Dispatchable) (*zrule.Dispatchable, error) {
+
+	key := fmt.Sprintf(zrule.CACHE_DISPATCH_COALESCE, message.ID, message.ActionID.Hex())
+	lock := fmt.Sprintf(zrule.CACHE_DISPATCH_COALESCE_LOCK, message.ID, message.ActionID.Hex())
+
+	pipe := s.redis.TxPipeline()
+	members := pipe.SMembers(ctx, key)
+	pipe.Del(ctx, key, lock)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flush buffer: %w", err)
+	}
+
+	matches := make([]*zrule.CoalescedMatch, 0, len(members.Val()))
+	for _, member := range members.Val() {
+		match, err := parseCoalesceMember(member)
+		if err != nil {
+			s.logger.WithError(err).WithField("member", member).Error("failed to parse buffered match")
+			continue
+		}
+		matches = append(matches, match)
+	}
+
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	flushed := *message
+	flushed.Coalesce = false
+
+	primary := matches[0]
+	for _, match := range matches {
+		if match.PolicyID == message.PolicyID {
+			primary = match
+		}
+	}
+	flushed.PolicyID, flushed.MatchID = primary.PolicyID, primary.MatchID
+
+	if len(matches) > 1 {
+		flushed.Coalesced = matches
+	}
+
+	return &flushed, nil
+
+}
+
+// coalesced looks up the policies of a delivery that was coalesced from several matches, with the policy of the delivery first
+func (s *service) coalesced(ctx context.Context, policy *zrule.Policy, message *zrule.Dispatchable) []*zrule.Policy {
+
+	if len(message.Coalesced) == 0 {
+		return nil
+	}
+
+	policies := []*zrule.Policy{policy}
+	for _, match := range message.Coalesced {
+		if match.PolicyID == policy.ID {
+			continue
+		}
+
+		coalesced, err := s.policy.Policy(ctx, match.PolicyID)
+		if err != nil {
+			s.logger.WithError(err).WithField("policyID", match.PolicyID.Hex()).Warn("failed to look up coalesced policy")
+			continue
+		}
+
+		policies = append(policies, coalesced)
+	}
+
+	return policies
+
+}
+
+func coalesceMember(policyID, matchID primitive.ObjectID) string {
+	return fmt.Sprintf("%s:%s", policyID.Hex(), matchID.Hex())
+}
+
+func parseCoalesceMember(member string) (*zrule.CoalescedMatch, error) {
+
+	parts := strings.SplitN(member, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("expected policy and match ids")
+	}
+
+	policyID, err := primitive.ObjectIDFromHex(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// Matches are recorded best effort, so a buffered match can be without an id
+	matchID, _ := primitive.ObjectIDFromHex(parts[1])
+
+	return &zrule.CoalescedMatch{PolicyID: policyID, MatchID: matchID}, nil
+
+}
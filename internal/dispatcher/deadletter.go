@@ -0,0 +1,135 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrDeadLetterNotFound is returned when a dead letter does not exist in the dead letter queue
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+func (s *service) deadLetter(ctx context.Context, message *zrule.Dispatchable) error {
+
+	letter := &zrule.DeadLetter{
+		ID:           primitive.NewObjectID().Hex(),
+		Dispatchable: message,
+		Error:        message.LastError,
+		CreatedAt:    time.Now(),
+	}
+
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	_, err = s.redis.HSet(ctx, zrule.QUEUES_DISPATCH_DEADLETTER, letter.ID, string(data)).Result()
+	return err
+
+}
+
+// DeadLetters returns every dead letter in the queue, oldest first
+func (s *service) DeadLetters(ctx context.Context) ([]*zrule.DeadLetter, error) {
+
+	results, err := s.redis.HGetAll(ctx, zrule.QUEUES_DISPATCH_DEADLETTER).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dead letters: %w", err)
+	}
+
+	letters := make([]*zrule.DeadLetter, 0, len(results))
+	for id, data := range results {
+		var letter = new(zrule.DeadLetter)
+		err = json.Unmarshal([]byte(data), letter)
+		if err != nil {
+			s.logger.WithError(err).WithField("id", id).Error("failed to decode dead letter, skipping")
+			continue
+		}
+		letters = append(letters, letter)
+	}
+
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].CreatedAt.Before(letters[j].CreatedAt)
+	})
+
+	return letters, nil
+
+}
+
+// ReplayDeadLetter removes the dead letter from the queue and pushes it back onto the matched queue
+// with its attempts reset, so that it is delivered to its action again
+func (s *service) ReplayDeadLetter(ctx context.Context, id string) error {
+
+	data, err := s.redis.HGet(ctx, zrule.QUEUES_DISPATCH_DEADLETTER, id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrDeadLetterNotFound
+		}
+		return fmt.Errorf("failed to fetch dead letter: %w", err)
+	}
+
+	var letter = new(zrule.DeadLetter)
+	err = json.Unmarshal([]byte(data), letter)
+	if err != nil {
+		return fmt.Errorf("failed to decode dead letter: %w", err)
+	}
+
+	message := letter.Dispatchable
+	message.Attempt = 0
+	message.LastError = ""
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dispatchable: %w", err)
+	}
+
+	removed, err := s.redis.HDel(ctx, zrule.QUEUES_DISPATCH_DEADLETTER, id).Result()
+	if err != nil {
+		return fmt.Errorf("failed to remove dead letter: %w", err)
+	}
+	if removed == 0 {
+		// Another caller replayed or purged this dead letter first
+		return ErrDeadLetterNotFound
+	}
+
+	_, err = s.redis.ZAdd(ctx, zrule.QUEUES_KILLMAIL_MATCHED, &redis.Z{Score: float64(time.Now().UnixNano()), Member: string(payload)}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to push dead letter to matched queue: %w", err)
+	}
+
+	return nil
+
+}
+
+// PurgeDeadLetters deletes the dead letters with the provided ids. When no ids are provided, every
+// dead letter is deleted. The number of dead letters that were deleted is returned
+func (s *service) PurgeDeadLetters(ctx context.Context, ids ...string) (int64, error) {
+
+	if len(ids) == 0 {
+		count, err := s.redis.HLen(ctx, zrule.QUEUES_DISPATCH_DEADLETTER).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count dead letters: %w", err)
+		}
+
+		_, err = s.redis.Del(ctx, zrule.QUEUES_DISPATCH_DEADLETTER).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge dead letters: %w", err)
+		}
+
+		return count, nil
+	}
+
+	count, err := s.redis.HDel(ctx, zrule.QUEUES_DISPATCH_DEADLETTER, ids...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead letters: %w", err)
+	}
+
+	return count, nil
+
+}
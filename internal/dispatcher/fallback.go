@@ -0,0 +1,92 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/go-redis/redis/v8"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fallback hands a delivery that failed, or that was for a disabled action, to the next fallback of the action
+// that the match was originally for. Fallbacks are only taken from that action, so a chain cannot loop through
+// the fallbacks of its fallbacks. It returns false when there is no fallback left, in which case the caller
+// carries on as it would without fallbacks
+func (s *service) fallback(ctx context.Context, message *zrule.Dispatchable, action *zrule.Action, reason string) bool {
+
+	primary, next := action, 0
+	if message.FallbackFor != nil {
+		var err error
+		primary, err = s.action.Action(ctx, *message.FallbackFor)
+		if err != nil {
+			newrelic.FromContext(ctx).NoticeError(err)
+			s.logger.WithError(err).WithField("actionID", message.FallbackFor.Hex()).Error("failed to lookup action to fall back from")
+			return false
+		}
+		next = message.Fallback + 1
+	}
+
+	if next >= len(primary.Fallbacks) {
+		return false
+	}
+
+	entry := s.logger.WithField("policyID", message.PolicyID.Hex()).WithField("actionID", action.ID.Hex()).WithField("fallbackID", primary.Fallbacks[next].Hex())
+
+	handoff := *message
+	handoff.ActionID = &primary.Fallbacks[next]
+	handoff.FallbackFor = &primary.ID
+	handoff.Fallback = next
+	handoff.Attempt = 0
+	handoff.LastError = reason
+
+	data, err := json.Marshal(handoff)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		entry.WithError(err).Error("failed to marshal fallback")
+		return false
+	}
+
+	_, err = s.redis.ZAdd(ctx, zrule.QUEUES_KILLMAIL_MATCHED, &redis.Z{Score: float64(time.Now().UnixNano()), Member: string(data)}).Result()
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		entry.WithError(err).Error("failed to queue fallback")
+		return false
+	}
+
+	entry.WithField("reason", reason).Info("delivery handed to fallback")
+
+	return true
+
+}
+
+// passOver hands a delivery for a fallback that has since been deleted to the next fallback of the chain,
+// or dead letters it when the deleted fallback was the last one
+func (s *service) passOver(ctx context.Context, message *zrule.Dispatchable, actionID primitive.ObjectID) {
+
+	reason := fmt.Sprintf("fallback %s was deleted", actionID.Hex())
+
+	// Fallbacks are taken from the action that the match was originally for, so the deleted action is only needed for its ID
+	if s.fallback(ctx, message, &zrule.Action{ID: actionID}, reason) {
+		return
+	}
+
+	letter := *message
+	letter.ActionID = &actionID
+	letter.LastError = reason
+
+	entry := s.logger.WithField("policyID", message.PolicyID.Hex()).WithField("actionID", actionID.Hex())
+
+	err := s.deadLetter(ctx, &letter)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		entry.WithError(err).Error("failed to move delivery to dead letter queue")
+		return
+	}
+
+	entry.Warn("fallback was deleted and there is no fallback after it, delivery has been dead lettered")
+
+}
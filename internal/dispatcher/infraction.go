@@ -0,0 +1,83 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxInfractions is the number of infractions that are kept on an action
+const maxInfractions = 20
+
+// recordInfraction records a failed delivery on the action and disables the action when the endpoint
+// has told us that it no longer exists, or when it has failed with too many server errors in a row.
+// It returns whether the action was disabled, in which case the delivery is not retried
+func (s *service) recordInfraction(ctx context.Context, action *zrule.Action, statusCode int, sendErr error) bool {
+
+	entry := s.logger.WithField("actionID", action.ID.Hex())
+
+	updated, err := s.action.AddInfraction(ctx, action.ID, &zrule.Infraction{
+		ID:         primitive.NewObjectID(),
+		StatusCode: statusCode,
+		Message:    sendErr.Error(),
+		CreatedAt:  time.Now(),
+	}, maxInfractions)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		entry.WithError(err).Error("failed to record infraction")
+		return false
+	}
+
+	reason := s.disableReason(updated, statusCode)
+	if reason == "" {
+		return false
+	}
+
+	err = s.action.DisableAction(ctx, action.ID, reason)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		entry.WithError(err).Error("failed to disable action")
+		return false
+	}
+
+	entry.WithField("reason", reason).Warn("action has been disabled")
+
+	return true
+
+}
+
+// disableReason returns why the action should be disabled after its latest infraction, or an empty string when it should not be
+func (s *service) disableReason(action *zrule.Action, statusCode int) string {
+
+	for _, code := range s.config.DisableStatusCodes {
+		if statusCode == code {
+			return fmt.Sprintf("endpoint responded with %d", statusCode)
+		}
+	}
+
+	if s.config.DisableAfter > 0 && action.ServerErrors() >= int(s.config.DisableAfter) {
+		return fmt.Sprintf("endpoint responded with a server error %d times in a row", action.ServerErrors())
+	}
+
+	return ""
+
+}
+
+// clearInfractions clears the infractions of an action after a successful delivery
+func (s *service) clearInfractions(ctx context.Context, action *zrule.Action) {
+
+	if len(action.Infractions) == 0 {
+		return
+	}
+
+	err := s.action.ClearInfractions(ctx, action.ID)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		s.logger.WithError(err).WithField("actionID", action.ID.Hex()).Error("failed to clear infractions")
+	}
+
+}
@@ -0,0 +1,165 @@
+package dispatcher
+
+import (
+	"context"
+	"hash/fnv"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// workerQueueSize is the number of jobs that can wait on a single worker before submitting to it blocks
+const workerQueueSize = 100
+
+// job is the delivery of a matched killmail to a single action. When route is set, the job is for an action
+// that the policy could notify, and the worker leaves it out unless the match is for the action. A flushed buffer
+// is routed for each of its policies, leaving out those whose match is not for the action
+type job struct {
+	match    *matched
+	actionID primitive.ObjectID
+	route    bool
+}
+
+// matched is a killmail that is being delivered to one or more actions. Its notification is built by the
+// first worker that needs it and shared with every other, so that the killmail is hydrated once per match and
+// a slow lookup holds up the deliveries of that match rather than the queue
+type matched struct {
+	message *zrule.Dispatchable
+	policy  *zrule.Policy
+
+	once         sync.Once
+	notification *zrule.Notification
+}
+
+// pool is a fixed set of workers that deliver jobs concurrently. Every job for an action is handled by the same
+// worker, so deliveries to an action happen in the order that they were submitted. The number of deliveries
+// in flight to any single host is bounded so that one busy host cannot occupy every worker. A worker waits out
+// a busy host or a rate limit in place rather than putting the job back, so that the later jobs of the action
+// are not sent ahead of it. Once the pool is draining, a job that would wait is put back instead, and so is
+// every later job of its action, so that they are still delivered in order
+type pool struct {
+	service *service
+	workers []chan *job
+	wg      sync.WaitGroup
+	quit    chan struct{}
+
+	maxPerHost int
+	mx         sync.Mutex
+	hosts      map[string]chan struct{}
+	parked     map[primitive.ObjectID]bool
+}
+
+func newPool(service *service, workers, maxPerHost int) *pool {
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	p := &pool{
+		service:    service,
+		workers:    make([]chan *job, workers),
+		quit:       make(chan struct{}),
+		maxPerHost: maxPerHost,
+		hosts:      make(map[string]chan struct{}),
+		parked:     make(map[primitive.ObjectID]bool),
+	}
+
+	for i := range p.workers {
+		p.workers[i] = make(chan *job, workerQueueSize)
+		p.wg.Add(1)
+		go p.work(p.workers[i])
+	}
+
+	return p
+
+}
+
+func (p *pool) work(jobs <-chan *job) {
+	defer p.wg.Done()
+
+	for job := range jobs {
+		p.service.deliver(context.Background(), p, job)
+	}
+}
+
+// submit hands the job to the worker that owns its action
+func (p *pool) submit(job *job) {
+
+	h := fnv.New32a()
+	_, _ = h.Write(job.actionID[:])
+
+	p.workers[h.Sum32()%uint32(len(p.workers))] <- job
+
+}
+
+// drain stops accepting jobs and waits for the workers to finish the jobs that they have already been handed
+func (p *pool) drain() {
+
+	close(p.quit)
+	for _, worker := range p.workers {
+		close(worker)
+	}
+
+	p.wg.Wait()
+
+}
+
+// acquireHost waits until a delivery to the host of endpoint is allowed to start. The returned func must be
+// called once the delivery has finished
+func (p *pool) acquireHost(endpoint string) func() {
+
+	if p.maxPerHost < 1 {
+		return func() {}
+	}
+
+	host := endpoint
+	if uri, err := url.Parse(endpoint); err == nil && uri.Host != "" {
+		host = uri.Host
+	}
+
+	p.mx.Lock()
+	sem, ok := p.hosts[host]
+	if !ok {
+		sem = make(chan struct{}, p.maxPerHost)
+		p.hosts[host] = sem
+	}
+	p.mx.Unlock()
+
+	sem <- struct{}{}
+
+	return func() {
+		<-sem
+	}
+
+}
+
+// wait pauses the worker for d. It returns false without waiting out d once the pool is draining
+func (p *pool) wait(d time.Duration) bool {
+
+	select {
+	case <-p.quit:
+		return false
+	case <-time.After(d):
+		return true
+	}
+
+}
+
+// park marks the action as having a job that was put back while the pool is draining
+func (p *pool) park(actionID primitive.ObjectID) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.parked[actionID] = true
+}
+
+// isParked reports whether an earlier job of the action was put back, in which case its later jobs must be as well
+func (p *pool) isParked(actionID primitive.ObjectID) bool {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return p.parked[actionID]
+}
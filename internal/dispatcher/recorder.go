@@ -0,0 +1,76 @@
+package dispatcher
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// maxRecordedResponse is the number of bytes of a response body that are kept on a delivery record
+const maxRecordedResponse = 1024
+
+// maxResponseSize is the number of bytes of a response body that are read at all. No platform responds
+// with anywhere near this much, so a larger body is cut off rather than held in memory in full
+const maxResponseSize = 1 << 20
+
+// recorder is an http.RoundTripper that captures the status, headers, latency, and body of the last
+// response that passed through it. The platform services own the requests they make, so a recorder is
+// wrapped around the shared client for each delivery to see what the remote end responded with.
+type recorder struct {
+	next http.RoundTripper
+
+	statusCode int
+	header     http.Header
+	body       []byte
+	latency    time.Duration
+}
+
+func newRecorder(next http.RoundTripper) *recorder {
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &recorder{next: next}
+
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	start := time.Now()
+	res, err := r.next.RoundTrip(req)
+	r.latency = time.Since(start)
+	if err != nil {
+		return res, err
+	}
+
+	r.statusCode = res.StatusCode
+	r.header = res.Header.Clone()
+
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Hand the caller a fresh reader over the body that we just consumed
+	res.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	if len(data) > maxRecordedResponse {
+		data = data[:maxRecordedResponse]
+	}
+	r.body = data
+
+	return res, nil
+
+}
+
+// reset forgets the last response, so that a request that is retried is not recorded with the response of the one before
+func (r *recorder) reset() {
+	r.statusCode = 0
+	r.header = nil
+	r.body = nil
+	r.latency = 0
+}
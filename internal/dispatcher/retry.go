@@ -0,0 +1,194 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/eveisesi/zrule/internal/email"
+	"github.com/eveisesi/zrule/internal/webpush"
+	"github.com/go-redis/redis/v8"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Config controls how the dispatcher retries failed deliveries
+type Config struct {
+	// MaxAttempts is the total number of times a delivery to an action is attempted
+	// before it is moved to the dead letter queue
+	MaxAttempts uint
+	// BackoffBase is the delay before the first retry. Each subsequent retry doubles the delay
+	BackoffBase time.Duration
+	// BackoffMax caps the delay between two attempts
+	BackoffMax time.Duration
+	// RateLimit is the number of requests per second that are made to a single endpoint,
+	// and RateLimitBurst is the number of requests that can be made at once
+	RateLimit      float64
+	RateLimitBurst int
+
+	// DisableStatusCodes are the response codes that disable an action immediately, because they mean that the
+	// endpoint no longer exists. DisableAfter is the number of server errors in a row that disable an action,
+	// zero never disables an action for server errors
+	DisableStatusCodes []int
+	DisableAfter       uint
+
+	// CoalesceWindow is how long the matches of a killmail are buffered for, so that an action that several
+	// policies matched the killmail for receives one message. Zero sends every match on its own
+	CoalesceWindow time.Duration
+
+	// Workers is the number of deliveries that are made concurrently
+	Workers int
+	// MaxPerHost bounds the number of deliveries in flight to a single host
+	MaxPerHost int
+
+	// RestTimeout bounds how long a REST action has to respond to a delivery
+	RestTimeout time.Duration
+
+	// AppURL is the base URL of the zrule frontend that notifications link back to
+	AppURL string
+
+	// Email is the SMTP server that email actions are delivered through
+	Email email.Config
+
+	// VAPID signs web push notifications. Web push actions fail when it is nil
+	VAPID *webpush.VAPID
+
+	// ESIURL is the base URL of ESI that EVE mail is sent through, which can point at a local stand-in
+	ESIURL    string
+	UserAgent string
+	// EVEMailInterval is how often a character can send EVE mail once EVEMailBurst mails have been sent
+	EVEMailInterval time.Duration
+	EVEMailBurst    int
+}
+
+// retryBatchSize is the maximum number of due retries that are moved back onto the matched queue per check
+const retryBatchSize = 100
+
+// backoff returns the delay before the given attempt. The delay grows exponentially from BackoffBase
+// and is capped at BackoffMax. Half of the delay is jittered so that deliveries that failed together
+// do not all retry at the same moment
+func (s *service) backoff(attempt uint) time.Duration {
+
+	delay := s.config.BackoffBase
+	for i := uint(1); i < attempt && delay < s.config.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > s.config.BackoffMax {
+		delay = s.config.BackoffMax
+	}
+
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+
+	return time.Duration(half + rand.Int63n(half))
+
+}
+
+// retry schedules another attempt at delivering the message to a single action. Once the
+// delivery has been attempted MaxAttempts times, it is moved to the dead letter queue instead
+func (s *service) retry(ctx context.Context, message *zrule.Dispatchable, actionID primitive.ObjectID, sendErr error) {
+
+	entry := s.logger.WithField("policyID", message.PolicyID.Hex()).WithField("actionID", actionID.Hex())
+
+	retry := *message
+	retry.ActionID = &actionID
+	retry.Attempt = message.Attempt + 1
+	retry.LastError = sendErr.Error()
+
+	if retry.Attempt >= s.config.MaxAttempts {
+		err := s.deadLetter(ctx, &retry)
+		if err != nil {
+			newrelic.FromContext(ctx).NoticeError(err)
+			entry.WithError(err).Error("failed to move delivery to dead letter queue")
+			return
+		}
+		entry.WithField("attempts", retry.Attempt).Warn("delivery exhausted its attempts and has been dead lettered")
+		return
+	}
+
+	data, err := json.Marshal(retry)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		entry.WithError(err).Error("failed to marshal retry")
+		return
+	}
+
+	next := time.Now().Add(s.backoff(retry.Attempt))
+	_, err = s.redis.ZAdd(ctx, zrule.QUEUES_DISPATCH_RETRY, &redis.Z{Score: float64(next.UnixNano()), Member: string(data)}).Result()
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		entry.WithError(err).Error("failed to schedule retry")
+		return
+	}
+
+	entry.WithField("attempt", retry.Attempt).WithField("next", next).Info("delivery failed, retry scheduled")
+
+}
+
+// delay puts the message back for a single action after the provided duration without counting
+// an attempt against it. It is used when the dispatcher shuts down while a delivery is waiting out a rate limit
+func (s *service) delay(ctx context.Context, message *zrule.Dispatchable, actionID primitive.ObjectID, after time.Duration) {
+
+	delayed := *message
+	delayed.ActionID = &actionID
+
+	data, err := json.Marshal(delayed)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		s.logger.WithError(err).Error("failed to marshal delayed delivery")
+		return
+	}
+
+	next := time.Now().Add(after)
+	_, err = s.redis.ZAdd(ctx, zrule.QUEUES_DISPATCH_RETRY, &redis.Z{Score: float64(next.UnixNano()), Member: string(data)}).Result()
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		s.logger.WithError(err).WithField("actionID", actionID.Hex()).Error("failed to delay delivery")
+	}
+
+}
+
+// promoteScript moves a retry from the retry set onto the matched queue in one step, so that a retry is never lost
+// between the two. It returns 0 when the retry was already promoted by another dispatcher
+var promoteScript = redis.NewScript(`
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+
+redis.call("ZADD", KEYS[2], ARGV[2], ARGV[1])
+
+return 1
+`)
+
+// promoteRetries moves retries whose next attempt is due onto the matched queue. A retry is only
+// promoted by the instance that successfully removes it from the retry set, so that multiple
+// dispatchers do not deliver the same retry twice
+func (s *service) promoteRetries(ctx context.Context) error {
+
+	now := time.Now()
+	members, err := s.redis.ZRangeByScore(ctx, zrule.QUEUES_DISPATCH_RETRY, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixNano(), 10),
+		Count: retryBatchSize,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to fetch due retries: %w", err)
+	}
+
+	keys := []string{zrule.QUEUES_DISPATCH_RETRY, zrule.QUEUES_KILLMAIL_MATCHED}
+	for _, member := range members {
+		err := promoteScript.Run(ctx, s.redis, keys, member, now.UnixNano()).Err()
+		if err != nil {
+			return fmt.Errorf("failed to move retry to matched queue: %w", err)
+		}
+	}
+
+	return nil
+
+}
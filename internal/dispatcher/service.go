@@ -3,24 +3,50 @@ package dispatcher
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/eveisesi/zrule"
 	"github.com/eveisesi/zrule/internal/action"
+	"github.com/eveisesi/zrule/internal/battle"
+	"github.com/eveisesi/zrule/internal/delivery"
 	"github.com/eveisesi/zrule/internal/discord"
+	"github.com/eveisesi/zrule/internal/email"
+	"github.com/eveisesi/zrule/internal/evemail"
+	"github.com/eveisesi/zrule/internal/gotify"
+	"github.com/eveisesi/zrule/internal/killmail"
+	"github.com/eveisesi/zrule/internal/match"
+	"github.com/eveisesi/zrule/internal/matrix"
+	"github.com/eveisesi/zrule/internal/mattermost"
+	"github.com/eveisesi/zrule/internal/ntfy"
 	"github.com/eveisesi/zrule/internal/policy"
+	"github.com/eveisesi/zrule/internal/pushover"
+	"github.com/eveisesi/zrule/internal/ratelimit"
+	"github.com/eveisesi/zrule/internal/render"
 	"github.com/eveisesi/zrule/internal/rest"
 	"github.com/eveisesi/zrule/internal/slack"
+	"github.com/eveisesi/zrule/internal/subscription"
+	"github.com/eveisesi/zrule/internal/teams"
+	"github.com/eveisesi/zrule/internal/telegram"
+	"github.com/eveisesi/zrule/internal/user"
+	"github.com/eveisesi/zrule/internal/webpush"
 	"github.com/go-redis/redis/v8"
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Service interface {
-	Run() error
+	Run(ctx context.Context) error
 	SendTestMessage(ctx context.Context, action *zrule.Action, message string) error
+	DeadLetters(ctx context.Context) ([]*zrule.DeadLetter, error)
+	ReplayDeadLetter(ctx context.Context, id string) error
+	PurgeDeadLetters(ctx context.Context, ids ...string) (int64, error)
 }
 
 type service struct {
@@ -28,76 +54,113 @@ type service struct {
 	logger   *logrus.Logger
 	newrelic *newrelic.Application
 	client   *http.Client
+	config   Config
+	limiter  *ratelimit.Limiter
 	policy   policy.Service
 	action   action.Service
+	killmail killmail.Service
+	match    match.Service
+	delivery delivery.Service
+	user     user.Service
+	battle   battle.Service
+
+	subscription subscription.Service
+
+	// mailLimiter bounds how often a single character sends EVE mail
+	mailLimiter *ratelimit.Limiter
 }
 
-func NewService(redis *redis.Client, logger *logrus.Logger, newrelic *newrelic.Application, client *http.Client, policy policy.Service, action action.Service) Service {
+func NewService(redis *redis.Client, logger *logrus.Logger, newrelic *newrelic.Application, client *http.Client, config Config, policy policy.Service, action action.Service, killmail killmail.Service, match match.Service, delivery delivery.Service, user user.Service, battle battle.Service, subscription subscription.Service) Service {
+
+	// Seed the jitter applied to retries so that separate dispatchers do not back off in lockstep
+	rand.Seed(time.Now().UnixNano())
 
 	return &service{
 		redis:    redis,
 		logger:   logger,
 		newrelic: newrelic,
 		client:   client,
+		config:   config,
+		limiter:  ratelimit.NewLimiter(redis, config.RateLimit, config.RateLimitBurst),
 		policy:   policy,
 		action:   action,
+		killmail: killmail,
+		match:    match,
+		delivery: delivery,
+		user:     user,
+		battle:   battle,
+
+		subscription: subscription,
+
+		mailLimiter: ratelimit.NewLimiter(redis, 1/config.EVEMailInterval.Seconds(), config.EVEMailBurst),
 	}
 
 }
 
-func (s *service) Run() error {
+// Run pops matched killmails off of the queue and hands every action that they need to be delivered to
+// off to the worker pool until ctx is cancelled. Once ctx is cancelled, no further killmails are popped
+// and Run returns after the deliveries that have already been handed to the workers have finished
+func (s *service) Run(ctx context.Context) error {
+
+	pool := newPool(s, s.config.Workers, s.config.MaxPerHost)
+	defer pool.drain()
 
 	for {
-		var ctx = context.Background()
+		select {
+		case <-ctx.Done():
+			s.logger.Info("dispatcher shutting down, draining in flight deliveries")
+			return nil
+		default:
+		}
+
+		// Deliveries run in their own context so that a shutdown lets in flight deliveries finish
+		var qctx = context.Background()
 		txn := s.newrelic.StartTransaction("dispatch queue check")
-		ctx = newrelic.NewContext(ctx, txn)
+		qctx = newrelic.NewContext(qctx, txn)
 
-		stop, err := s.redis.Get(ctx, zrule.QUEUE_STOP).Int64()
-		if err != nil {
-			s.logger.WithError(err).Error("stop flag is missing. attempting to create with default value of 0")
-			_, err := s.redis.Set(ctx, zrule.QUEUE_STOP, 0, 0).Result()
-			if err != nil {
-				txn.NoticeError(err)
-				s.logger.WithError(err).Fatal("error encountered attempting to create stop flag with default value")
-			}
+		stop, err := s.redis.Get(qctx, zrule.QUEUE_STOP).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			txn.NoticeError(err)
+			s.logger.WithError(err).Error("unable to determine value of stop flag")
 			txn.End()
+			sleep(ctx, time.Second*2)
 			continue
 		}
 
 		if stop == 1 {
 			s.logger.Info("stop signal set, sleeping for 5 seconds")
-			time.Sleep(time.Second * 5)
 			txn.End()
+			sleep(ctx, time.Second*5)
 			continue
 		}
 
-		count, err := s.redis.ZCount(ctx, zrule.QUEUES_KILLMAIL_MATCHED, "-inf", "+inf").Result()
+		err = s.promoteRetries(qctx)
 		if err != nil {
 			txn.NoticeError(err)
-			s.logger.WithError(err).Error("unable to determine count of message queue")
-			txn.End()
-			time.Sleep(time.Second * 2)
-			continue
+			s.logger.WithError(err).Error("failed to promote due retries")
 		}
 
-		if count == 0 {
+		// Pop the oldest matches first, so that killmails are delivered in the order that they matched
+		results, err := s.redis.ZPopMin(qctx, zrule.QUEUES_KILLMAIL_MATCHED, int64(s.config.Workers)).Result()
+		if err != nil {
+			txn.NoticeError(err)
+			s.logger.WithError(err).Error("unable to retrieve messages from queue")
 			txn.End()
-			time.Sleep(time.Second * 2)
+			sleep(ctx, time.Second*2)
 			continue
 		}
 
-		results, err := s.redis.ZPopMax(ctx, zrule.QUEUES_KILLMAIL_MATCHED, 1).Result()
-		if err != nil {
-			txn.NoticeError(err)
-			s.logger.WithError(err).Fatal("unable to retrieve hashes from queue")
+		if len(results) == 0 {
 			txn.End()
+			sleep(ctx, time.Second*2)
 			continue
 		}
 
 		for _, result := range results {
-			s.logger.Info("handling message")
 			data := result.Member.(string)
-			s.handleMessage(ctx, []byte(data), 0)
+			for _, job := range s.handleMessage(qctx, []byte(data)) {
+				pool.submit(job)
+			}
 		}
 
 		txn.End()
@@ -106,66 +169,493 @@ func (s *service) Run() error {
 
 }
 
-func (s *service) handleMessage(ctx context.Context, data []byte, sleep int) {
+// sleep pauses for d, returning early if ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
 
-	txn := s.newrelic.StartTransaction("handle message")
-	ctx = newrelic.NewContext(ctx, txn)
+// handleMessage decodes a matched killmail and returns a job for each action that it needs to be delivered to
+func (s *service) handleMessage(ctx context.Context, data []byte) []*job {
 
 	var message = new(zrule.Dispatchable)
 	err := json.Unmarshal(data, message)
 	if err != nil {
-		txn.NoticeError(err)
+		newrelic.FromContext(ctx).NoticeError(err)
 		s.logger.WithError(err).WithField("data", string(data)).Error("failed to unmarsahl data onto dispatchable struct")
-		return
+		return nil
+	}
+
+	// A flush is the first delivery of its matches to the action, which were buffered before they were routed
+	route := false
+	if message.Coalesce {
+		route = true
+		flushed, err := s.flush(ctx, message)
+		if err != nil {
+			newrelic.FromContext(ctx).NoticeError(err)
+			s.logger.WithError(err).WithField("killmailID", message.ID).Error("failed to flush coalesced matches")
+			return nil
+		}
+		if flushed == nil {
+			return nil
+		}
+		message = flushed
 	}
 
 	policy, err := s.policy.Policy(ctx, message.PolicyID)
 	if err != nil {
-		txn.NoticeError(err)
+		newrelic.FromContext(ctx).NoticeError(err)
 		s.logger.WithError(err).WithField("policyID", message.PolicyID).Error("failed to look up policy")
-		return
+		return nil
 	}
 
-	txn.AddAttribute("policyID", message.PolicyID.Hex())
+	if message.ActionID != nil {
+		// Retries and fallbacks target a single action. If the action they are for has since
+		// been removed from the policy, there is nothing left to deliver
+		target := *message.ActionID
+		if message.FallbackFor != nil {
+			target = *message.FallbackFor
+		}
+		if !policy.HasAction(target) {
+			return nil
+		}
+	}
 
-	for _, actionID := range policy.Actions {
-		dispatchTxn := s.newrelic.StartTransaction("dispatch action")
-		dispatchTxn.AddAttribute("policyID", message.PolicyID.Hex())
-		dispatchTxn.AddAttribute("actionID", actionID)
-		entry := s.logger.WithField("policyID", message.PolicyID.Hex()).WithField("actionID", actionID)
-		action, err := s.action.Action(ctx, actionID)
-		if err != nil {
-			dispatchTxn.NoticeError(err)
-			entry.WithError(err).Error("failed to lookup action")
-			continue
+	m := &matched{message: message, policy: policy}
+
+	if message.ActionID != nil {
+		return []*job{{match: m, actionID: *message.ActionID, route: route || message.Route}}
+	}
+
+	// Which actions a match is sent to depends on the value and contents of the killmail, which are only known
+	// once it has been hydrated. Every action that the policy could notify is handed to the workers, which
+	// hydrate the killmail and leave out the actions that the match is not for
+	actionIDs := policy.AllActions()
+	if len(actionIDs) == 0 {
+		return nil
+	}
+
+	jobs := make([]*job, 0, len(actionIDs))
+	for _, actionID := range actionIDs {
+		// New matches are buffered before the killmail is hydrated, so that the matches of every policy for the
+		// killmail are sent to the action together, and the killmail is only hydrated once the buffer is flushed
+		if s.config.CoalesceWindow > 0 {
+			err := s.buffer(ctx, message, actionID)
+			if err == nil {
+				continue
+			}
+			newrelic.FromContext(ctx).NoticeError(err)
+			s.logger.WithError(err).WithField("actionID", actionID.Hex()).Error("failed to buffer match, delivering it on its own")
 		}
-		dispatchTxn.AddAttribute("platform", action.Platform.String())
-		entry = entry.WithField("platform", action.Platform.String())
 
-		platform, err := s.serviceForPlatform(action)
-		if err != nil {
-			dispatchTxn.NoticeError(err)
-			entry.WithError(err).Error("unable to determine platform to use")
-			continue
+		jobs = append(jobs, &job{match: m, actionID: actionID, route: true})
+	}
+
+	return jobs
+
+}
+
+// hydrate returns the notification of a match, building it the first time that it is needed
+func (s *service) hydrate(ctx context.Context, m *matched) *zrule.Notification {
+
+	m.once.Do(func() {
+		m.notification = s.notification(ctx, m.policy, m.message)
+	})
+
+	return m.notification
+
+}
+
+// errNotArchived is returned when whether a match is sent to an action depends on a killmail that is not in the archive
+var errNotArchived = errors.New("killmail is not in the archive")
+
+// route narrows the match down to the policies whose match is sent to the action, returning the notification and
+// message to deliver, or nil when none of them is. Only a coalesced match can be for more than one policy.
+// errNotArchived is returned when any of the policies cannot be routed without the contents of the killmail
+func (s *service) route(ctx context.Context, notification *zrule.Notification, message *zrule.Dispatchable, actionID primitive.ObjectID) (*zrule.Notification, *zrule.Dispatchable, error) {
+
+	if len(notification.Policies) == 0 {
+		notifies, err := s.routes(ctx, notification, actionID)
+		if err != nil || !notifies {
+			return nil, nil, err
 		}
+		copied := *message
+		return notification, &copied, nil
+	}
 
-		err = platform.Send(newrelic.NewContext(ctx, dispatchTxn), policy, message.ID, message.Hash)
+	matches := make(map[primitive.ObjectID]*zrule.CoalescedMatch, len(message.Coalesced))
+	for _, match := range message.Coalesced {
+		matches[match.PolicyID] = match
+	}
+
+	policies := make([]*zrule.Policy, 0, len(notification.Policies))
+	coalesced := make([]*zrule.CoalescedMatch, 0, len(notification.Policies))
+	for _, policy := range notification.Policies {
+		candidate := *notification
+		candidate.Policy = policy
+		match, ok := matches[policy.ID]
+		if !ok {
+			continue
+		}
+		notifies, err := s.routes(ctx, &candidate, actionID)
 		if err != nil {
-			dispatchTxn.NoticeError(err)
-			entry.WithError(err).Error("failed to send message to platform")
+			return nil, nil, err
+		}
+		if !notifies {
 			continue
 		}
-		time.Sleep(time.Second)
-		dispatchTxn.End()
+		policies = append(policies, policy)
+		coalesced = append(coalesced, match)
+	}
+
+	if len(policies) == 0 {
+		return nil, nil, nil
+	}
+
+	routed, routedMessage := *notification, *message
+	routed.Policy, routed.Policies = policies[0], nil
+	routedMessage.PolicyID, routedMessage.MatchID, routedMessage.Coalesced = coalesced[0].PolicyID, coalesced[0].MatchID, nil
+	if len(policies) > 1 {
+		routed.Policies = policies
+		routedMessage.Coalesced = coalesced
+	}
+
+	return &routed, &routedMessage, nil
+
+}
+
+// routes reports whether a match is sent to an action. Escalation tiers are evaluated against the value of the
+// hydrated killmail, and an action that the policy has a link to must match the rules of the link as well.
+// Neither escalation tiers nor link rules can be evaluated against a killmail that is not in the archive,
+// whose value and contents are unknown, which returns errNotArchived
+func (s *service) routes(ctx context.Context, notification *zrule.Notification, actionID primitive.ObjectID) (bool, error) {
+
+	policy := notification.Policy
+	if !policy.Reaches(actionID, notification.Value()) {
+		// Only the actions of escalation tiers can fail to be reached, which an unknown value must not decide
+		if !notification.Archived() {
+			return false, errNotArchived
+		}
+		return false, nil
+	}
+
+	link := policy.Link(actionID)
+	if link == nil || len(link.Rules) == 0 {
+		return true, nil
+	}
+
+	if !notification.Archived() {
+		return false, errNotArchived
+	}
+
+	notifies, err := link.Notifies(notification.Killmail)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		s.logger.WithError(err).WithField("policyID", policy.ID.Hex()).WithField("actionID", actionID.Hex()).Error("failed to evaluate link rules, skipping action")
+		return false, nil
+	}
+
+	return notifies, nil
+
+}
+
+// notification builds the notification for a match from the killmail archive. When the killmail
+// is not in the archive, the notification carries only the ID and hash of the killmail
+func (s *service) notification(ctx context.Context, policy *zrule.Policy, message *zrule.Dispatchable) *zrule.Notification {
+
+	notification := &zrule.Notification{
+		Policy:   policy,
+		Policies: s.coalesced(ctx, policy, message),
+		Killmail: &zrule.Killmail{ID: message.ID, Hash: message.Hash},
+	}
+
+	if s.config.AppURL != "" {
+		notification.PolicyURL = fmt.Sprintf("%s/policies/%s", strings.TrimSuffix(s.config.AppURL, "/"), policy.ID.Hex())
+	}
+
+	killmail, err := s.killmail.Killmail(ctx, message.ID)
+	if err != nil {
+		s.logger.WithError(err).WithField("killmailID", message.ID).Warn("failed to fetch killmail from archive, sending notification without details")
+		return notification
+	}
+
+	notification.Killmail = killmail
+	notification.Details = s.killmail.Details(ctx, killmail)
+
+	return notification
+
+}
+
+// renderTemplate renders the template of the policy, or of the action when the policy does not override it,
+// onto a copy of the notification. The notification is shared by every action of the match, so it is never
+// modified. If the template fails to render, the platforms default message is sent instead
+func (s *service) renderTemplate(ctx context.Context, notification *zrule.Notification, action *zrule.Action) *zrule.Notification {
+
+	text := action.Template
+	if notification.Policy.Template != "" {
+		text = notification.Policy.Template
+	}
+
+	if text == "" {
+		return notification
+	}
+
+	message, err := render.Render(text, notification)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		s.logger.WithError(err).WithField("actionID", action.ID.Hex()).Error("failed to render template, sending default message")
+		return notification
+	}
+
+	rendered := *notification
+	rendered.Message = message
+
+	return &rendered
+
+}
+
+// mention returns a copy of the notification with the mentions that it carries to the action, or the
+// notification itself when it does not mention anyone. Like the template, mentions differ per action
+func mention(notification *zrule.Notification, action *zrule.Action) *zrule.Notification {
+
+	mentions := notification.MentionsFor(action.ID)
+	if len(mentions) == 0 {
+		return notification
+	}
+
+	mentioned := *notification
+	mentioned.Mentions = mentions
+
+	return &mentioned
+
+}
+
+// deliver delivers a killmail to a single action. It is called by the workers of the pool
+func (s *service) deliver(ctx context.Context, pool *pool, job *job) {
+
+	message, policy, actionID := job.match.message, job.match.policy, job.actionID
+
+	dispatchTxn := s.newrelic.StartTransaction("dispatch action")
+	defer dispatchTxn.End()
+	ctx = newrelic.NewContext(ctx, dispatchTxn)
+
+	if pool.isParked(actionID) {
+		// An earlier delivery to the action was put back while draining, which this one must not overtake
+		parked := *message
+		parked.Route = parked.Route || job.route
+		s.delay(ctx, &parked, actionID, 0)
+		return
+	}
+
+	// Retries and fallbacks were routed when the match was first delivered, so they are not routed again unless
+	// the killmail was not in the archive at the time
+	shared := s.hydrate(ctx, job.match)
+	if job.route {
+		routed, routedMessage, err := s.route(ctx, shared, message, actionID)
+		if errors.Is(err, errNotArchived) {
+			// The killmail may still be on its way into the archive, so the match is routed again once it is
+			unrouted := *message
+			unrouted.Route = true
+			s.logger.WithField("policyID", message.PolicyID.Hex()).WithField("actionID", actionID.Hex()).WithField("killmailID", message.ID).Warn("killmail is not in the archive, retrying the routing of the match")
+			s.retry(ctx, &unrouted, actionID, err)
+			return
+		}
+		if routed == nil {
+			return
+		}
+		shared, message, policy = routed, routedMessage, routed.Policy
+		message.Route = false
+	}
+
+	dispatchTxn.AddAttribute("policyID", message.PolicyID.Hex())
+	dispatchTxn.AddAttribute("actionID", actionID.Hex())
+	dispatchTxn.AddAttribute("attempt", message.Attempt)
+	entry := s.logger.WithField("policyID", message.PolicyID.Hex()).WithField("actionID", actionID.Hex())
+
+	action, err := s.action.Action(ctx, actionID)
+	if err != nil {
+		dispatchTxn.NoticeError(err)
+		entry.WithError(err).Error("failed to lookup action")
+		if message.FallbackFor != nil && errors.Is(err, mongo.ErrNoDocuments) {
+			s.passOver(ctx, message, actionID)
+		}
+		return
+	}
+	dispatchTxn.AddAttribute("platform", action.Platform.String())
+	entry = entry.WithField("platform", action.Platform.String())
+
+	if action.IsDisabled {
+		if !s.fallback(ctx, message, action, "action is disabled") {
+			entry.Info("action is disabled, skipping delivery")
+		}
+		return
+	}
+
+	rec, client := s.recordingClient()
+	platform, err := s.serviceForPlatform(ctx, action, client)
+	if err != nil {
+		// The action cannot be sent to as it stands, such as an email action on a server without SMTP,
+		// which is a failed delivery like any other rather than a match to drop
+		dispatchTxn.NoticeError(err)
+		entry.WithError(err).Error("unable to determine platform to use")
+		s.recordMatchDeliveries(ctx, message, action, err)
+		s.fail(ctx, message, action, 0, err)
+		return
+	}
+
+	notification := mention(s.renderTemplate(ctx, shared, action), action)
+
+	// Rate limited deliveries are waited out without counting against the attempts of the delivery,
+	// so that the later deliveries to the action are not sent ahead of this one
+	for {
+		release := pool.acquireHost(action.Endpoint)
+		err = s.send(ctx, platform, action, notification)
+		release()
+
+		var rateLimitErr *zrule.RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			break
+		}
+
+		if rec.statusCode != 0 {
+			s.recordDelivery(ctx, &zrule.Delivery{
+				ActionID:   action.ID,
+				OwnerID:    action.OwnerID,
+				PolicyID:   &policy.ID,
+				MatchID:    optionalObjectID(message.MatchID),
+				KillmailID: message.ID,
+			}, action, rec, err)
+			rec.reset()
+		}
+
+		entry.WithField("retryAfter", rateLimitErr.RetryAfter).Info("endpoint is rate limited, waiting to deliver")
+		if !pool.wait(rateLimitErr.RetryAfter) {
+			entry.Info("dispatcher is shutting down, delaying rate limited delivery")
+			pool.park(actionID)
+			s.delay(ctx, message, actionID, rateLimitErr.RetryAfter)
+			return
+		}
+	}
+
+	s.recordMatchDeliveries(ctx, message, action, err)
+	s.recordDelivery(ctx, &zrule.Delivery{
+		ActionID:   action.ID,
+		OwnerID:    action.OwnerID,
+		PolicyID:   &policy.ID,
+		MatchID:    optionalObjectID(message.MatchID),
+		KillmailID: message.ID,
+	}, action, rec, err)
+	if err != nil {
+		dispatchTxn.NoticeError(err)
+		entry.WithError(err).Error("failed to send message to platform")
+		statusCode := rec.statusCode
+		if action.Platform == zrule.PlatformWebPush && (statusCode == http.StatusNotFound || statusCode == http.StatusGone) {
+			// A push service that no longer knows a subscription speaks for a single browser, whose
+			// subscription the web push service has already removed, rather than for the whole action
+			statusCode = 0
+		}
+		s.fail(ctx, message, action, statusCode, err)
+		return
+	}
+
+	s.clearInfractions(ctx, action)
+
+}
+
+// fail records an infraction against an action that a delivery failed on, and then hands the delivery to a
+// fallback of the action or retries it. Actions with fallbacks hand a failed delivery over rather than retrying
+// it, so that the match is not held up while the action is down, nor delivered twice
+func (s *service) fail(ctx context.Context, message *zrule.Dispatchable, action *zrule.Action, statusCode int, sendErr error) {
+
+	disabled := s.recordInfraction(ctx, action, statusCode, sendErr)
+	if s.fallback(ctx, message, action, sendErr.Error()) || disabled {
+		return
+	}
+
+	s.retry(ctx, message, action.ID, sendErr)
+
+}
+
+// recordMatchDeliveries records the outcome of a delivery on the history of every match that it was coalesced from
+func (s *service) recordMatchDeliveries(ctx context.Context, message *zrule.Dispatchable, action *zrule.Action, sendErr error) {
+
+	if len(message.Coalesced) == 0 {
+		s.recordMatchDelivery(ctx, message.MatchID, action, sendErr)
+		return
 	}
 
-	txn.End()
+	for _, match := range message.Coalesced {
+		s.recordMatchDelivery(ctx, match.MatchID, action, sendErr)
+	}
 
 }
 
+// recordMatchDelivery appends the outcome of delivering a match to an action onto the match history.
+// Dispatchables queued before match history was recorded do not have a MatchID and are skipped
+func (s *service) recordMatchDelivery(ctx context.Context, matchID primitive.ObjectID, action *zrule.Action, sendErr error) {
+
+	if matchID.IsZero() {
+		return
+	}
+
+	delivery := &zrule.MatchDelivery{
+		ActionID:    action.ID,
+		Platform:    action.Platform,
+		Status:      zrule.DeliveryStatusSucceeded,
+		DeliveredAt: time.Now(),
+	}
+
+	if sendErr != nil {
+		msg := sendErr.Error()
+		delivery.Status = zrule.DeliveryStatusFailed
+		delivery.Error = &msg
+	}
+
+	err := s.match.CreateMatchDelivery(ctx, matchID, delivery)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		s.logger.WithError(err).WithField("matchID", matchID.Hex()).WithField("actionID", action.ID.Hex()).Error("failed to record match delivery")
+	}
+
+}
+
+// recordDelivery completes the delivery with the outcome of the send and the response captured by the recorder
+// and writes it to the delivery log. Failing to record a delivery does not fail the delivery itself
+func (s *service) recordDelivery(ctx context.Context, delivery *zrule.Delivery, action *zrule.Action, rec *recorder, sendErr error) {
+
+	delivery.Platform = action.Platform
+	delivery.Status = zrule.DeliveryStatusSucceeded
+	delivery.StatusCode = rec.statusCode
+	delivery.LatencyMS = rec.latency.Milliseconds()
+	delivery.Response = string(rec.body)
+
+	if sendErr != nil {
+		msg := sendErr.Error()
+		delivery.Status = zrule.DeliveryStatusFailed
+		delivery.Error = &msg
+	}
+
+	_, err := s.delivery.CreateDelivery(ctx, delivery)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		s.logger.WithError(err).WithField("actionID", action.ID.Hex()).Error("failed to record delivery")
+	}
+
+}
+
+func optionalObjectID(id primitive.ObjectID) *primitive.ObjectID {
+	if id.IsZero() {
+		return nil
+	}
+	return &id
+}
+
 func (s *service) SendTestMessage(ctx context.Context, action *zrule.Action, message string) error {
 
-	platform, err := s.serviceForPlatform(action)
+	rec, client := s.recordingClient()
+	platform, err := s.serviceForPlatform(ctx, action, client)
 	if err != nil {
 		newrelic.FromContext(ctx).NoticeError(err)
 		s.logger.WithError(err).Error("failed to lookup action")
@@ -173,6 +663,11 @@ func (s *service) SendTestMessage(ctx context.Context, action *zrule.Action, mes
 	}
 
 	err = platform.SendTest(ctx, message)
+	s.recordDelivery(ctx, &zrule.Delivery{
+		ActionID: action.ID,
+		OwnerID:  action.OwnerID,
+		Test:     true,
+	}, action, rec, err)
 	if err != nil {
 		newrelic.FromContext(ctx).NoticeError(err)
 		s.logger.WithError(err).Error("failed to send message")
@@ -183,14 +678,58 @@ func (s *service) SendTestMessage(ctx context.Context, action *zrule.Action, mes
 
 }
 
-func (s *service) serviceForPlatform(action *zrule.Action) (zrule.Dispatcher, error) {
+// recordingClient returns a copy of the shared client whose requests are rate limited per endpoint
+// and whose responses are captured by the returned recorder. The recorder sits beneath the rate limiter,
+// so a response that the limiter turns into an error is still captured
+func (s *service) recordingClient() (*recorder, *http.Client) {
+
+	rec := newRecorder(s.client.Transport)
+
+	return rec, &http.Client{
+		Transport:     ratelimit.NewTransport(s.limiter, rec),
+		CheckRedirect: s.client.CheckRedirect,
+		Jar:           s.client.Jar,
+		Timeout:       s.client.Timeout,
+	}
+
+}
+
+func (s *service) serviceForPlatform(ctx context.Context, action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
 	switch action.Platform {
 	case zrule.PlatformDiscord:
-		return discord.NewService(action, s.client)
+		return discord.NewService(action, client)
 	case zrule.PlatformSlack:
-		return slack.NewService(action, s.client)
+		return slack.NewService(action, client)
 	case zrule.PlatformRest:
-		return rest.NewService(action, s.client)
+		return rest.NewService(action, client, s.config.RestTimeout)
+	case zrule.PlatformTelegram:
+		return telegram.NewService(action, client)
+	case zrule.PlatformMatrix:
+		return matrix.NewService(action, client)
+	case zrule.PlatformEmail:
+		return email.NewService(action, s.config.Email)
+	case zrule.PlatformTeams:
+		return teams.NewService(action, client)
+	case zrule.PlatformMattermost:
+		return mattermost.NewService(action, client)
+	case zrule.PlatformNtfy:
+		return ntfy.NewService(action, client)
+	case zrule.PlatformGotify:
+		return gotify.NewService(action, client)
+	case zrule.PlatformPushover:
+		return pushover.NewService(action, client)
+	case zrule.PlatformEVEMail:
+		sender, err := s.user.UserByID(ctx, action.OwnerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup owner of eve mail action: %w", err)
+		}
+		return evemail.NewService(action, sender, s.user, s.mailLimiter, client, s.config.ESIURL, s.config.UserAgent)
+	case zrule.PlatformWebPush:
+		subscriptions, err := s.subscription.PushSubscriptions(ctx, zrule.NewEqualOperator("user_id", action.OwnerID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup push subscriptions of owner: %w", err)
+		}
+		return webpush.NewService(action, subscriptions, s.config.VAPID, s.subscription, client)
 	default:
 		return nil, fmt.Errorf("unknown platform specified")
 	}
@@ -0,0 +1,86 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// Confirmer mails the addresses of email actions the link that confirms that they want to receive its emails.
+// BaseURL is the public URL of the API, which the links point at
+type Confirmer struct {
+	config  Config
+	baseURL string
+}
+
+func NewConfirmer(config Config, baseURL string) *Confirmer {
+	return &Confirmer{
+		config:  config,
+		baseURL: baseURL,
+	}
+}
+
+// Configured reports why email actions cannot be confirmed on this server, or nil when they can
+func (c *Confirmer) Configured() error {
+
+	if c.config.Host == "" || c.config.From == "" {
+		return fmt.Errorf("email actions cannot be delivered, an smtp host and from address have not been configured")
+	}
+
+	if c.baseURL == "" {
+		return fmt.Errorf("email actions cannot be confirmed, the public url of the api has not been configured")
+	}
+
+	return nil
+
+}
+
+// SendConfirmations mails every address of the action that has not confirmed yet
+func (c *Confirmer) SendConfirmations(ctx context.Context, action *zrule.Action) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send email confirmations")
+	defer seg.End()
+
+	err := c.Configured()
+	if err != nil {
+		return err
+	}
+
+	for _, confirmation := range action.Confirmations {
+		if confirmation.ConfirmedAt != nil {
+			continue
+		}
+
+		s := &service{config: c.config, recipients: []string{confirmation.Address}}
+		msg, err := s.confirmationMessage(action, confirmation.URL(c.baseURL, action.ID))
+		if err != nil {
+			return err
+		}
+
+		err = s.send(ctx, msg)
+		if err != nil {
+			return fmt.Errorf("failed to send confirmation to %s: %w", confirmation.Address, err)
+		}
+	}
+
+	return nil
+
+}
+
+func (s *service) confirmationMessage(action *zrule.Action, link string) ([]byte, error) {
+
+	text := fmt.Sprintf("Someone asked zrule to email %s about killmails that match their policies, with the action %q. ", s.recipients[0], action.Label)
+	text += "Nothing is sent to this address until it is confirmed. If you were not expecting this, ignore this email."
+
+	return s.compose(
+		"Confirm that you want zrule notifications",
+		messageID(fmt.Sprintf("confirm:%s", action.ID.Hex()), uint(time.Now().UnixNano())),
+		fmt.Sprintf("%s\n\nConfirm: %s\n", text, link),
+		fmt.Sprintf("<!DOCTYPE html>\n<html>\n<body>\n<p>%s</p>\n<p><a href=\"%s\">Confirm</a></p>\n</body>\n</html>\n", html.EscapeString(text), html.EscapeString(link)),
+	)
+
+}
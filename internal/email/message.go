@@ -0,0 +1,203 @@
+package email
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+var htmlBody = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #222;">
+{{- if .Message }}
+<p>{{ .Message }}</p>
+{{- else if .Victim }}
+<table cellpadding="4">
+<tr>
+<td><img src="{{ .ShipImage }}" width="64" height="64" alt="{{ .Victim.ShipName }}"></td>
+<td><strong>{{ .Victim.ShipName }} destroyed in {{ .System }}</strong></td>
+</tr>
+{{- range .Lines }}
+<tr><td>{{ index . 0 }}</td><td>{{ index . 1 }}</td></tr>
+{{- end }}
+</table>
+{{- else }}
+<p>Match Found with {{ .PolicyNoun }} <strong>{{ .Policies }}</strong></p>
+{{- end }}
+<p><a href="{{ .ZKillboardURL }}">View on zKillboard</a>{{ if .PolicyURL }} | <a href="{{ .PolicyURL }}">{{ .Policy }}</a>{{ end }}</p>
+<p style="color: #888; font-size: 12px;">Matched {{ .PolicyNoun }} {{ .Policies }}</p>
+</body>
+</html>
+`))
+
+type htmlData struct {
+	Message       template.HTML
+	Victim        *zrule.KillmailParticipant
+	ShipImage     string
+	System        string
+	Lines         [][2]string
+	Policy        string
+	PolicyURL     string
+	ZKillboardURL string
+
+	// Policies and PolicyNoun name every policy that matched when several were coalesced into the email
+	Policies   string
+	PolicyNoun string
+}
+
+// message builds a multipart email with a plain text and an HTML body for a notification
+func (s *service) message(notification *zrule.Notification) ([]byte, error) {
+
+	killmail, details, policy := notification.Killmail, notification.Details, notification.Policy
+
+	data := htmlData{
+		Policy:        policy.Name,
+		PolicyURL:     notification.PolicyURL,
+		ZKillboardURL: killmail.ZKillboardURL(),
+		Policies:      notification.PolicyNames(),
+		PolicyNoun:    notification.PolicyNoun(),
+	}
+
+	subject := fmt.Sprintf("Match Found with %s %s", data.PolicyNoun, data.Policies)
+
+	var plain strings.Builder
+	switch {
+	case notification.Message != "":
+		// Templates are written as plain text, so the HTML body only preserves their line breaks
+		plain.WriteString(notification.Message)
+		data.Message = template.HTML(strings.Replace(html.EscapeString(notification.Message), "\n", "<br>\n", -1))
+	case details != nil && details.Victim != nil:
+		data.Victim = details.Victim
+		data.System = details.Location()
+		if details.Victim.Ship != nil {
+			data.ShipImage = zrule.ShipRenderURL(details.Victim.Ship.ID, 64)
+		}
+
+		subject = fmt.Sprintf("%s destroyed in %s worth %s", details.Victim.ShipName(), data.System, zrule.FormatISK(details.TotalValue))
+
+		data.Lines = lines(details, s.layout)
+		fmt.Fprintf(&plain, "%s destroyed in %s\n", details.Victim.ShipName(), data.System)
+		for _, line := range data.Lines {
+			fmt.Fprintf(&plain, "%s: %s\n", line[0], line[1])
+		}
+	default:
+		plain.WriteString(subject)
+	}
+
+	fmt.Fprintf(&plain, "\n\n%s\n", data.ZKillboardURL)
+	if data.PolicyURL != "" {
+		fmt.Fprintf(&plain, "%s\n", data.PolicyURL)
+	}
+	fmt.Fprintf(&plain, "\nMatched %s %s\n", data.PolicyNoun, data.Policies)
+
+	var body bytes.Buffer
+	err := htmlBody.Execute(&body, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render email body: %w", err)
+	}
+
+	return s.compose(subject, messageID(policy.ID.Hex(), killmail.ID), plain.String(), body.String())
+
+}
+
+func (s *service) testMessage(message string) ([]byte, error) {
+	return s.compose(
+		"zrule test notification",
+		messageID("test", uint(time.Now().UnixNano())),
+		message,
+		fmt.Sprintf("<!DOCTYPE html>\n<html>\n<body>\n<p>%s</p>\n</body>\n</html>\n", html.EscapeString(message)),
+	)
+}
+
+func lines(details *zrule.KillmailDetails, layout zrule.Layout) [][2]string {
+
+	lines := [][2]string{
+		{"Victim", details.Victim.Label()},
+		{"Value", zrule.FormatISK(details.TotalValue)},
+	}
+
+	if layout == zrule.LayoutCompact {
+		return lines
+	}
+
+	if details.Region != nil {
+		lines = append(lines, [2]string{"Region", details.Region.Name})
+	}
+	lines = append(lines, [2]string{"Attackers", fmt.Sprintf("%d", details.AttackerCount)})
+	if details.FinalBlow != nil {
+		lines = append(lines, [2]string{"Final Blow", fmt.Sprintf("%s (%s)", details.FinalBlow.Label(), details.FinalBlow.ShipName())})
+	}
+
+	return lines
+
+}
+
+// messageID derives the Message-ID of an email from what it is about, so that a retried delivery
+// can be recognized as a duplicate by the recipients mail server
+func messageID(scope string, id uint) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", scope, id)))
+	return fmt.Sprintf("<%s@zrule>", hex.EncodeToString(sum[:16]))
+}
+
+// compose writes the headers and a multipart/alternative body of an email
+func (s *service) compose(subject, id, plain, htmlBody string) ([]byte, error) {
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	headers := []string{
+		fmt.Sprintf("From: %s", s.config.From),
+		fmt.Sprintf("To: %s", strings.Join(s.recipients, ", ")),
+		fmt.Sprintf("Subject: %s", mime.QEncoding.Encode("utf-8", subject)),
+		fmt.Sprintf("Date: %s", time.Now().UTC().Format(time.RFC1123Z)),
+		fmt.Sprintf("Message-ID: %s", id),
+		"MIME-Version: 1.0",
+		fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q", w.Boundary()),
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", plain},
+		{"text/html; charset=utf-8", htmlBody},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email part: %w", err)
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		_, err = qw.Write([]byte(part.body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+		err = qw.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+	}
+
+	err := w.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to close email body: %w", err)
+	}
+
+	return buf.Bytes(), nil
+
+}
@@ -0,0 +1,173 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// Config is the SMTP server that emails are relayed through
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// From is the address that emails are sent from
+	From string
+	// StartTLS requires the connection to be upgraded with STARTTLS before authenticating. It should
+	// only be disabled for a local SMTP stand-in
+	StartTLS bool
+	// Timeout bounds how long the conversation with the SMTP server can take
+	Timeout time.Duration
+}
+
+type service struct {
+	config     Config
+	recipients []string
+	layout     zrule.Layout
+}
+
+func NewService(action *zrule.Action, config Config) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformEmail {
+		return nil, fmt.Errorf("invalid platform for email service constructor")
+	}
+
+	if config.Host == "" || config.From == "" {
+		return nil, fmt.Errorf("email actions cannot be delivered, an smtp host and from address have not been configured")
+	}
+
+	recipients := action.EmailRecipients()
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("email action does not have any recipients")
+	}
+
+	if unconfirmed := action.Unconfirmed(); len(unconfirmed) > 0 {
+		return nil, fmt.Errorf("email action is waiting for %s to confirm", strings.Join(unconfirmed, ", "))
+	}
+
+	return &service{
+		config:     config,
+		recipients: recipients,
+		layout:     action.Layout,
+	}, nil
+
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send email")
+	defer seg.End()
+
+	msg, err := s.message(notification)
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, msg)
+
+}
+
+func (s *service) SendTest(ctx context.Context, message string) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send test email")
+	defer seg.End()
+
+	msg, err := s.testMessage(message)
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, msg)
+
+}
+
+// send relays a message to the recipients of the action through the configured SMTP server
+func (s *service) send(ctx context.Context, msg []byte) error {
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+
+	var deadline time.Time
+	if s.config.Timeout > 0 {
+		deadline = time.Now().Add(s.config.Timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	if !deadline.IsZero() {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to start smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if s.config.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server does not support STARTTLS")
+		}
+
+		err = client.StartTLS(&tls.Config{ServerName: s.config.Host})
+		if err != nil {
+			return fmt.Errorf("failed to upgrade smtp connection: %w", err)
+		}
+	}
+
+	if s.config.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host))
+		if err != nil {
+			return fmt.Errorf("failed to authenticate with smtp server: %w", err)
+		}
+	}
+
+	err = client.Mail(s.config.From)
+	if err != nil {
+		return fmt.Errorf("smtp server rejected sender: %w", err)
+	}
+
+	for _, recipient := range s.recipients {
+		err = client.Rcpt(recipient)
+		if err != nil {
+			return fmt.Errorf("smtp server rejected recipient %s: %w", recipient, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp server rejected data: %w", err)
+	}
+
+	_, err = w.Write(msg)
+	if err != nil {
+		return fmt.Errorf("failed to write message to smtp server: %w", err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("smtp server rejected message: %w", err)
+	}
+
+	// The server has accepted the message once DATA is closed, so a failure to say goodbye is not a failed
+	// delivery. Returning it would have the delivery retried, and the recipients would receive the email twice
+	_ = client.Quit()
+
+	return nil
+
+}
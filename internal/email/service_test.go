@@ -0,0 +1,267 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// smtpStandIn is a minimal SMTP server that accepts a single message. When dropQuit is set,
+// it hangs up on QUIT without replying, as a server that goes away after accepting the message would
+type smtpStandIn struct {
+	listener   net.Listener
+	recipients []string
+	data       chan string
+	dropQuit   bool
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+
+	s := &smtpStandIn{listener: listener, data: make(chan string, 1)}
+	go s.serve()
+
+	return s
+
+}
+
+func (s *smtpStandIn) serve() {
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch command {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			reply("250 OK")
+		case "RCPT":
+			s.recipients = append(s.recipients, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.data <- data.String()
+			reply("250 OK")
+		case "QUIT":
+			if s.dropQuit {
+				return
+			}
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+
+}
+
+func (s *smtpStandIn) config() Config {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return Config{Host: "127.0.0.1", Port: addr.Port, From: "zrule@example.com", Timeout: time.Second * 5}
+}
+
+// confirm confirms every address of an email action
+func confirm(action *zrule.Action) {
+	now := time.Now()
+	for _, address := range action.EmailRecipients() {
+		action.Confirmations = append(action.Confirmations, &zrule.EmailConfirmation{Address: address, ConfirmedAt: &now})
+	}
+}
+
+func TestNewServiceRequiresConfirmedAddresses(t *testing.T) {
+
+	action := &zrule.Action{Endpoint: "mailto:fc@example.com,ceo@example.com"}
+	err := action.IsValid()
+	if err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+
+	now := time.Now()
+	action.Confirmations = []*zrule.EmailConfirmation{
+		{Address: "fc@example.com", Token: "a", ConfirmedAt: &now},
+		{Address: "ceo@example.com", Token: "b"},
+	}
+
+	_, err = NewService(action, Config{Host: "127.0.0.1", From: "zrule@example.com"})
+	if err == nil || !strings.Contains(err.Error(), "waiting for ceo@example.com to confirm") {
+		t.Fatalf("expected the unconfirmed address to be refused, got %v", err)
+	}
+
+}
+
+func TestSendConfirmationsMailsLink(t *testing.T) {
+
+	standIn := newSMTPStandIn(t)
+	defer standIn.listener.Close()
+
+	action := &zrule.Action{
+		ID:            primitive.NewObjectID(),
+		Label:         "Leadership",
+		Endpoint:      "mailto:fc@example.com",
+		Confirmations: []*zrule.EmailConfirmation{{Address: "fc@example.com", Token: "0123abcd"}},
+	}
+
+	err := NewConfirmer(standIn.config(), "https://api.zrule.example/").SendConfirmations(context.Background(), action)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if strings.Join(standIn.recipients, ",") != "fc@example.com" {
+		t.Errorf("unexpected recipients %v", standIn.recipients)
+	}
+
+	link := "https://api.zrule.example/actions/" + action.ID.Hex() + "/confirm/0123abcd"
+	data := <-standIn.data
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %s", err)
+	}
+	_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	part, err := multipart.NewReader(msg.Body, params["boundary"]).NextPart()
+	if err != nil {
+		t.Fatalf("failed to read plain body: %s", err)
+	}
+	body, _ := ioutil.ReadAll(part)
+	if !strings.Contains(string(body), link) {
+		t.Errorf("expected the confirmation link %s in the body, got %q", link, body)
+	}
+
+}
+
+func TestSendDeliversMultipartMessage(t *testing.T) {
+
+	standIn := newSMTPStandIn(t)
+	defer standIn.listener.Close()
+
+	action := &zrule.Action{Endpoint: "mailto:fc@example.com,ceo@example.com"}
+	err := action.IsValid()
+	if err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+	confirm(action)
+
+	dispatcher, err := NewService(action, standIn.config())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.Send(context.Background(), &zrule.Notification{
+		Policy:   &zrule.Policy{ID: primitive.NewObjectID(), Name: "Capital Losses"},
+		Killmail: &zrule.Killmail{ID: 88486806, Hash: "3c9ed419c00f123ff8d46475b05b70616d1381d8"},
+		Message:  "A <capital> died",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if strings.Join(standIn.recipients, ",") != "fc@example.com,ceo@example.com" {
+		t.Errorf("unexpected recipients %v", standIn.recipients)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(<-standIn.data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %s", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected a multipart/alternative message, got %s", msg.Header.Get("Content-Type"))
+	}
+
+	bodies := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		body, _ := ioutil.ReadAll(part)
+		bodies[strings.SplitN(part.Header.Get("Content-Type"), ";", 2)[0]] = string(body)
+	}
+
+	if !strings.Contains(bodies["text/plain"], "A <capital> died") {
+		t.Errorf("expected template in plain body, got %q", bodies["text/plain"])
+	}
+	if !strings.Contains(bodies["text/html"], "A &lt;capital&gt; died") {
+		t.Errorf("expected escaped template in html body, got %q", bodies["text/html"])
+	}
+
+}
+
+func TestSendSucceedsWhenQuitFailsAfterData(t *testing.T) {
+
+	standIn := newSMTPStandIn(t)
+	standIn.dropQuit = true
+	defer standIn.listener.Close()
+
+	action := &zrule.Action{Endpoint: "mailto:fc@example.com"}
+	err := action.IsValid()
+	if err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+	confirm(action)
+
+	dispatcher, err := NewService(action, standIn.config())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.SendTest(context.Background(), "Is this thing on?!?!")
+	if err != nil {
+		t.Fatalf("expected the accepted message to count as delivered, got %s", err)
+	}
+
+	<-standIn.data
+
+}
+
+func TestIsValidRejectsInvalidAddresses(t *testing.T) {
+
+	for _, endpoint := range []string{"mailto:", "mailto:not-an-address", "mailto:fc@"} {
+		action := &zrule.Action{Endpoint: endpoint}
+		if err := action.IsValid(); err == nil {
+			t.Errorf("expected %s to be rejected", endpoint)
+		}
+	}
+
+}
@@ -0,0 +1,48 @@
+package evemail
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/eveisesi/zrule"
+)
+
+// escape escapes text for the subset of HTML that the game renders in mail, keeping line breaks
+func escape(text string) string {
+	return strings.Replace(html.EscapeString(text), "\n", "<br>", -1)
+}
+
+// body formats a notification as the body of an EVE mail. The kill report link opens
+// the killmail in the client, the zkillboard link opens it in the browser
+func body(notification *zrule.Notification) string {
+
+	killmail, details := notification.Killmail, notification.Details
+
+	var b strings.Builder
+
+	if notification.Message != "" {
+		fmt.Fprintf(&b, "%s<br><br>", escape(notification.Message))
+	} else if details != nil && details.Victim != nil {
+		victim := details.Victim
+		fmt.Fprintf(&b, "<b>%s</b><br>", escape(notification.Title()))
+		fmt.Fprintf(&b, "Victim: %s<br>", escape(victim.Label()))
+		fmt.Fprintf(&b, "Value: %s<br>", zrule.FormatISK(details.TotalValue))
+		fmt.Fprintf(&b, "Attackers: %d<br>", details.AttackerCount)
+		if details.FinalBlow != nil {
+			fmt.Fprintf(&b, "Final Blow: %s (%s)<br>", escape(details.FinalBlow.Label()), escape(details.FinalBlow.ShipName()))
+		}
+		b.WriteString("<br>")
+	} else {
+		fmt.Fprintf(&b, "Killmail %d matched %s %s<br><br>", killmail.ID, notification.PolicyNoun(), escape(notification.PolicyNames()))
+	}
+
+	if killmail.Hash != "" {
+		fmt.Fprintf(&b, `<a href="killReport:%d:%s">Kill Report</a> | `, killmail.ID, killmail.Hash)
+	}
+	fmt.Fprintf(&b, `<a href="%s">zKillboard</a><br><br>`, killmail.ZKillboardURL())
+	fmt.Fprintf(&b, "Matched %s %s", notification.PolicyNoun(), escape(notification.PolicyNames()))
+
+	return b.String()
+
+}
@@ -0,0 +1,173 @@
+package evemail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// Limits imposed by ESI on mail
+// https://esi.evetech.net/ui/#/Mail/post_characters_character_id_mail
+const (
+	maxSubjectLength = 1000
+	maxBodyLength    = 10000
+)
+
+// statusMailError is the status that ESI responds with when the game server rejects a mail
+const statusMailError = 520
+
+// defaultMailRetryAfter is used when the game server asks us to stop spamming without saying for how long
+const defaultMailRetryAfter = time.Minute
+
+// TokenSource provides an access token for calling ESI on behalf of a user
+type TokenSource interface {
+	AccessToken(ctx context.Context, user *zrule.User, scope string) (string, error)
+}
+
+// Limiter bounds how often a character can send mail, on top of the limits that the game enforces
+type Limiter interface {
+	Take(ctx context.Context, key string) (time.Duration, error)
+}
+
+type service struct {
+	client        *http.Client
+	limiter       Limiter
+	tokens        TokenSource
+	esiURL        string
+	userAgent     string
+	sender        *zrule.User
+	recipientType string
+	recipientID   uint64
+}
+
+func NewService(action *zrule.Action, sender *zrule.User, tokens TokenSource, limiter Limiter, client *http.Client, esiURL, userAgent string) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformEVEMail {
+		return nil, fmt.Errorf("invalid platform for eve mail service constructor")
+	}
+
+	recipientType, recipientID, err := action.EVEMailRecipient()
+	if err != nil {
+		return nil, err
+	}
+
+	if !sender.HasScope(zrule.ScopeSendMail) {
+		return nil, fmt.Errorf("owner of the action has not granted the %s scope", zrule.ScopeSendMail)
+	}
+
+	return &service{
+		client:        client,
+		limiter:       limiter,
+		tokens:        tokens,
+		esiURL:        strings.TrimSuffix(esiURL, "/"),
+		userAgent:     userAgent,
+		sender:        sender,
+		recipientType: recipientType,
+		recipientID:   recipientID,
+	}, nil
+
+}
+
+type mailRecipient struct {
+	RecipientID   uint64 `json:"recipient_id"`
+	RecipientType string `json:"recipient_type"`
+}
+
+type mail struct {
+	ApprovedCost uint64           `json:"approved_cost"`
+	Body         string           `json:"body"`
+	Recipients   []*mailRecipient `json:"recipients"`
+	Subject      string           `json:"subject"`
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send eve mail")
+	defer seg.End()
+
+	return s.send(ctx, notification.Title(), body(notification))
+
+}
+
+func (s *service) SendTest(ctx context.Context, message string) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send eve mail test message")
+	defer seg.End()
+
+	return s.send(ctx, "zrule test notification", escape(message))
+
+}
+
+func (s *service) send(ctx context.Context, subject, body string) error {
+
+	// Characters that send too much mail are blocked by the game, so mail is held back
+	// per character before the game has to tell us to stop
+	wait, err := s.limiter.Take(ctx, fmt.Sprintf("evemail:%d", s.sender.CharacterID))
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &zrule.RateLimitError{RetryAfter: wait}
+	}
+
+	token, err := s.tokens.AccessToken(ctx, s.sender, zrule.ScopeSendMail)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&mail{
+		Body:       zrule.Truncate(body, maxBodyLength),
+		Recipients: []*mailRecipient{{RecipientID: s.recipientID, RecipientType: s.recipientType}},
+		Subject:    zrule.Truncate(subject, maxSubjectLength),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to prepare request body to send to esi: %w", err)
+	}
+
+	uri := fmt.Sprintf("%s/v1/characters/%d/mail/", s.esiURL, s.sender.CharacterID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to prepare request to esi: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("User-Agent", s.userAgent)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request to esi: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusCreated {
+		return nil
+	}
+
+	data, _ = ioutil.ReadAll(res.Body)
+
+	var result struct {
+		Error string `json:"error"`
+		// RemainingTime is how long the character is blocked from sending mail, in 100 nanosecond ticks
+		RemainingTime int64 `json:"remainingTime"`
+	}
+	_ = json.Unmarshal(data, &result)
+
+	if res.StatusCode == statusMailError && strings.Contains(result.Error, "MailStopSpamming") {
+		retryAfter := time.Duration(result.RemainingTime) * 100 * time.Nanosecond
+		if retryAfter <= 0 {
+			retryAfter = defaultMailRetryAfter
+		}
+		return &zrule.RateLimitError{RetryAfter: retryAfter}
+	}
+
+	return fmt.Errorf("invalid response code %d received from esi: %s", res.StatusCode, result.Error)
+
+}
@@ -0,0 +1,110 @@
+package evemail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+type staticTokens string
+
+func (t staticTokens) AccessToken(ctx context.Context, user *zrule.User, scope string) (string, error) {
+	return string(t), nil
+}
+
+type staticLimiter time.Duration
+
+func (l staticLimiter) Take(ctx context.Context, key string) (time.Duration, error) {
+	return time.Duration(l), nil
+}
+
+var sender = &zrule.User{CharacterID: 90000001, Scopes: []string{zrule.ScopeSendMail}}
+
+func TestSendPostsMailToESI(t *testing.T) {
+
+	var received *mail
+	esi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/characters/90000001/mail/" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			t.Errorf("unexpected authorization header %s", r.Header.Get("Authorization"))
+		}
+
+		received = new(mail)
+		_ = json.NewDecoder(r.Body).Decode(received)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("384827"))
+	}))
+	defer esi.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformEVEMail, Recipient: "corporation:98000001"}
+	if err := action.IsValid(); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+
+	dispatcher, err := NewService(action, sender, staticTokens("access-token"), staticLimiter(0), esi.Client(), esi.URL, "zrule test")
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.Send(context.Background(), &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "Structures"},
+		Killmail: &zrule.Killmail{ID: 88486806, Hash: "3c9ed419c00f123ff8d46475b05b70616d1381d8"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received == nil || len(received.Recipients) != 1 || received.Recipients[0].RecipientID != 98000001 || received.Recipients[0].RecipientType != "corporation" {
+		t.Fatalf("unexpected mail received: %+v", received)
+	}
+
+	if !strings.Contains(received.Body, `killReport:88486806:3c9ed419c00f123ff8d46475b05b70616d1381d8`) {
+		t.Errorf("expected a kill report link in the body, got %s", received.Body)
+	}
+
+}
+
+func TestSendHonorsMailLimits(t *testing.T) {
+
+	esi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusMailError)
+		_, _ = w.Write([]byte(`{"error":"MailStopSpamming","remainingTime":1200000000}`))
+	}))
+	defer esi.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformEVEMail, Recipient: "character:90000002"}
+
+	var rateLimited *zrule.RateLimitError
+
+	limited, _ := NewService(action, sender, staticTokens("access-token"), staticLimiter(time.Second*15), esi.Client(), esi.URL, "zrule test")
+	if err := limited.SendTest(context.Background(), "test"); !errors.As(err, &rateLimited) || rateLimited.RetryAfter != time.Second*15 {
+		t.Errorf("expected the character limit to delay the mail, got %v", err)
+	}
+
+	dispatcher, _ := NewService(action, sender, staticTokens("access-token"), staticLimiter(0), esi.Client(), esi.URL, "zrule test")
+	if err := dispatcher.SendTest(context.Background(), "test"); !errors.As(err, &rateLimited) || rateLimited.RetryAfter != time.Minute*2 {
+		t.Errorf("expected the game to delay the mail by 2m, got %v", err)
+	}
+
+}
+
+func TestIsValidRejectsInvalidRecipients(t *testing.T) {
+
+	for _, recipient := range []string{"", "98000001", "station:60003760", "corporation:abc"} {
+		action := &zrule.Action{Platform: zrule.PlatformEVEMail, Recipient: recipient}
+		if err := action.IsValid(); err == nil {
+			t.Errorf("expected %q to be rejected", recipient)
+		}
+	}
+
+}
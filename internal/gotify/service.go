@@ -0,0 +1,127 @@
+package gotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// priorities maps a priority onto the scale of Gotify, from 0 to 10. The Android
+// client stays silent below 4 and raises a heads up notification from 8
+var priorities = map[zrule.Priority]int{
+	zrule.PriorityMin:     1,
+	zrule.PriorityLow:     3,
+	zrule.PriorityDefault: 5,
+	zrule.PriorityHigh:    8,
+	zrule.PriorityUrgent:  10,
+}
+
+type service struct {
+	client   *http.Client
+	endpoint string
+	token    string
+}
+
+func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformGotify {
+		return nil, fmt.Errorf("invalid platform for gotify service constructor")
+	}
+
+	if action.Token == "" {
+		return nil, fmt.Errorf("gotify actions require an application token")
+	}
+
+	return &service{
+		client:   client,
+		endpoint: strings.TrimSuffix(action.Endpoint, "/"),
+		token:    action.Token,
+	}, nil
+
+}
+
+// message is the body of a message that is created on a Gotify server
+// https://gotify.net/api-docs#/message/createMessage
+type message struct {
+	Title    string                 `json:"title,omitempty"`
+	Message  string                 `json:"message"`
+	Priority int                    `json:"priority"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send gotify message")
+	defer seg.End()
+
+	return s.create(ctx, &message{
+		Title:    notification.Title(),
+		Message:  notification.Summary(),
+		Priority: priorities[notification.Priority()],
+		Extras: map[string]interface{}{
+			// https://gotify.net/docs/msgextras#clientnotification
+			"client::notification": map[string]interface{}{
+				"click": map[string]string{"url": notification.Killmail.ZKillboardURL()},
+			},
+		},
+	})
+
+}
+
+func (s *service) SendTest(ctx context.Context, msg string) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send gotify test message")
+	defer seg.End()
+
+	return s.create(ctx, &message{
+		Message:  msg,
+		Priority: priorities[zrule.PriorityDefault],
+	})
+
+}
+
+func (s *service) create(ctx context.Context, msg *message) error {
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request body to post to gotify: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/message", s.endpoint), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to prepare request to gotify: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", s.token)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request to gotify: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var result struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"errorDescription"`
+		}
+
+		data, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decode error response: %w", err)
+		}
+		_ = json.Unmarshal(data, &result)
+
+		return fmt.Errorf("invalid response code %d received from gotify: %s %s", res.StatusCode, result.Error, result.ErrorDescription)
+	}
+
+	return nil
+
+}
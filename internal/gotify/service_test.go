@@ -0,0 +1,54 @@
+package gotify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestSendCreatesMessage(t *testing.T) {
+
+	var received *message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/message" || r.Header.Get("X-Gotify-Key") != "AbCdEf" {
+			t.Errorf("unexpected request to %s with key %s", r.URL.Path, r.Header.Get("X-Gotify-Key"))
+		}
+
+		received = new(message)
+		_ = json.NewDecoder(r.Body).Decode(received)
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformGotify, Endpoint: server.URL + "/message?token=AbCdEf"}
+	if err := action.IsValid(); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+
+	dispatcher, err := NewService(action, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.Send(context.Background(), &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "Capitals"},
+		Killmail: &zrule.Killmail{ID: 88486806},
+		Details:  &zrule.KillmailDetails{Victim: &zrule.KillmailParticipant{}, TotalValue: 3_200_000_000},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received == nil || received.Priority != 8 {
+		t.Fatalf("expected a high priority message, got %+v", received)
+	}
+
+	click := received.Extras["client::notification"].(map[string]interface{})["click"].(map[string]interface{})
+	if click["url"] != "https://zkillboard.com/kill/88486806/" {
+		t.Errorf("unexpected click url %v", click["url"])
+	}
+
+}
@@ -1,13 +1,17 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/eveisesi/zrule"
+	"github.com/eveisesi/zrule/internal/netguard"
 	"github.com/go-chi/chi"
 )
 
@@ -101,6 +105,75 @@ func (s *server) handlePostActionTest(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// handlePostActionEnable re-enables an action that the dispatcher disabled. A test message is sent first,
+// and the action is only enabled once the test has been delivered
+func (s *server) handlePostActionEnable(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	actionID := chi.URLParam(r, "actionID")
+	if actionID == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("actionID is required to enable an action"))
+		return
+	}
+
+	entry := s.logger.WithField("actionID", actionID)
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		err := fmt.Errorf("ctx does not contain a user")
+		entry.WithError(err).Errorln()
+		s.writeError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(actionID)
+	if err != nil {
+		msg := "provided action id is invalid"
+		entry.WithError(err).Error(msg)
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf(msg))
+		return
+	}
+
+	entry = entry.WithField("ownerID", user.ID)
+
+	actions, err := s.action.Actions(ctx, zrule.NewEqualOperator("owner_id", user.ID), zrule.NewEqualOperator("_id", objectID))
+	if err != nil {
+		entry.WithError(err).Error("failed to find action for provided actionID")
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to find action for provided actionID"))
+		return
+	}
+
+	if len(actions) != 1 {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("failed to locate an action with ID of %s", actionID))
+		return
+	}
+
+	action := actions[0]
+
+	err = s.dispatcher.SendTestMessage(ctx, action, "Is this thing on?!?!")
+	if err != nil {
+		entry.WithError(err).Error("failed to send test message")
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("action was not enabled, the test message failed: %w", err))
+		return
+	}
+
+	action.Tested = true
+	action.IsDisabled = false
+	action.DisabledReason = nil
+	action.Infractions = make([]*zrule.Infraction, 0)
+
+	action, err = s.action.UpdateAction(ctx, action.ID, action)
+	if err != nil {
+		entry.WithError(err).Error("failed to update action")
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, action)
+
+}
+
 func (s *server) handleCreateAction(w http.ResponseWriter, r *http.Request) {
 
 	var ctx = r.Context()
@@ -125,6 +198,20 @@ func (s *server) handleCreateAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// What an endpoint responds with is recorded on the deliveries of the action, so an action that could
+	// point at the network that zrule runs in would let its owner read the services on it
+	if !s.allowInternalEndpoints {
+		if err := netguard.CheckEndpoint(ctx, action.Endpoint); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := s.validateTemplate(ctx, action.Template); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	user := UserFromContext(ctx)
 	if user == nil {
 		err = fmt.Errorf("ctx does not contain a user")
@@ -135,14 +222,258 @@ func (s *server) handleCreateAction(w http.ResponseWriter, r *http.Request) {
 
 	action.OwnerID = user.ID
 
-	_, err = s.action.CreateAction(ctx, action)
+	if err := s.validateFallbacks(ctx, user, action); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if action.Platform == zrule.PlatformEVEMail && !user.HasScope(zrule.ScopeSendMail) {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("eve mail actions require logging in again and granting the %s scope", zrule.ScopeSendMail))
+		return
+	}
+
+	if action.Platform == zrule.PlatformEmail {
+		if err := s.confirmer.Configured(); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	action, err = s.action.CreateAction(ctx, action)
 	if err != nil {
 		s.logger.WithError(err).Error("failed to save action")
 		s.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to save action"))
 		return
 	}
 
-	s.writeResponse(w, http.StatusCreated, nil)
+	// Email actions are created disabled, and are enabled once every address has confirmed from the link that
+	// it is mailed. A confirmation that fails to send can be sent again with POST /actions/{actionID}/confirmations
+	if action.Platform == zrule.PlatformEmail {
+		err = s.confirmer.SendConfirmations(ctx, action)
+		if err != nil {
+			s.logger.WithError(err).WithField("actionID", action.ID.Hex()).Error("failed to send confirmations of email action")
+		}
+	}
+
+	// The action is returned so that the secret of a REST action is available to sign requests with
+	s.writeResponse(w, http.StatusCreated, action)
+
+}
+
+// handlePostActionConfirmations mails the confirmation again to every address of an email action that has not confirmed yet
+func (s *server) handlePostActionConfirmations(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	actionID := chi.URLParam(r, "actionID")
+	if actionID == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("actionID is required to send confirmations"))
+		return
+	}
+
+	entry := s.logger.WithField("actionID", actionID)
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		err := fmt.Errorf("ctx does not contain a user")
+		entry.WithError(err).Errorln()
+		s.writeError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(actionID)
+	if err != nil {
+		msg := "provided action id is invalid"
+		entry.WithError(err).Error(msg)
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf(msg))
+		return
+	}
+
+	actions, err := s.action.Actions(ctx, zrule.NewEqualOperator("owner_id", user.ID), zrule.NewEqualOperator("_id", objectID))
+	if err != nil {
+		entry.WithError(err).Error("failed to find action for provided actionID")
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to find action for provided actionID"))
+		return
+	}
+
+	if len(actions) != 1 {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("failed to locate an action with ID of %s", actionID))
+		return
+	}
+
+	action := actions[0]
+	if action.Platform != zrule.PlatformEmail || len(action.Unconfirmed()) == 0 {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("action does not have any addresses waiting to confirm"))
+		return
+	}
+
+	err = s.confirmer.SendConfirmations(ctx, action)
+	if err != nil {
+		entry.WithError(err).Error("failed to send confirmations of email action")
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusAccepted, nil)
+
+}
+
+// handleGetActionConfirm confirms an address of an email action from the link that the address was mailed.
+// The action is enabled once every one of its addresses has confirmed
+func (s *server) handleGetActionConfirm(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "actionID"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("provided action id is invalid"))
+		return
+	}
+
+	action, err := s.action.ConfirmEmail(ctx, objectID, chi.URLParam(r, "token"))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("this confirmation link is not valid, or the action has been deleted"))
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).WithField("actionID", objectID.Hex()).Error("failed to confirm email action")
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to confirm address"))
+		return
+	}
+
+	confirmation := action.Confirmation(chi.URLParam(r, "token"))
+	if confirmation == nil {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("this confirmation link is not valid, or the action has been deleted"))
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, map[string]interface{}{
+		"message": fmt.Sprintf("%s will receive the emails of this action", confirmation.Address),
+	})
+
+}
+
+// handlePutActionFallbacks replaces the fallbacks of an action
+func (s *server) handlePutActionFallbacks(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	actionID := chi.URLParam(r, "actionID")
+	if actionID == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("actionID is required to update fallbacks"))
+		return
+	}
+
+	entry := s.logger.WithField("actionID", actionID)
+
+	var body struct {
+		Fallbacks []primitive.ObjectID `json:"fallbacks"`
+	}
+	defer r.Body.Close()
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read body: %w", err))
+		return
+	}
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		err := fmt.Errorf("ctx does not contain a user")
+		entry.WithError(err).Errorln()
+		s.writeError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(actionID)
+	if err != nil {
+		msg := "provided action id is invalid"
+		entry.WithError(err).Error(msg)
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf(msg))
+		return
+	}
+
+	actions, err := s.action.Actions(ctx, zrule.NewEqualOperator("owner_id", user.ID), zrule.NewEqualOperator("_id", objectID))
+	if err != nil {
+		entry.WithError(err).Error("failed to find action for provided actionID")
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to find action for provided actionID"))
+		return
+	}
+
+	if len(actions) != 1 {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("failed to locate an action with ID of %s", actionID))
+		return
+	}
+
+	action := actions[0]
+	action.Fallbacks = body.Fallbacks
+
+	err = s.validateFallbacks(ctx, user, action)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	action, err = s.action.UpdateAction(ctx, action.ID, action)
+	if err != nil {
+		entry.WithError(err).Error("failed to update action")
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, action)
+
+}
+
+// validateFallbacks verifies that the fallbacks of an action are other actions of the same owner, each listed once
+func (s *server) validateFallbacks(ctx context.Context, user *zrule.User, action *zrule.Action) error {
+
+	if len(action.Fallbacks) > zrule.MaxFallbacks {
+		return fmt.Errorf("an action can have at most %d fallbacks", zrule.MaxFallbacks)
+	}
+
+	seen := make(map[primitive.ObjectID]bool, len(action.Fallbacks))
+	for _, fallback := range action.Fallbacks {
+		if fallback == action.ID {
+			return fmt.Errorf("an action cannot be its own fallback")
+		}
+		if seen[fallback] {
+			return fmt.Errorf("fallback %s is listed more than once", fallback.Hex())
+		}
+		seen[fallback] = true
+	}
+
+	return s.validateOwnedActions(ctx, user, action.Fallbacks)
+
+}
+
+// validateOwnedActions verifies that every action id refers to an action of the user
+func (s *server) validateOwnedActions(ctx context.Context, user *zrule.User, actionIDs []primitive.ObjectID) error {
+
+	unique := make(map[primitive.ObjectID]bool, len(actionIDs))
+	values := make([]zrule.OpValue, 0, len(actionIDs))
+	for _, actionID := range actionIDs {
+		if unique[actionID] {
+			continue
+		}
+		unique[actionID] = true
+		values = append(values, actionID)
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	actions, err := s.action.Actions(ctx, zrule.NewEqualOperator("owner_id", user.ID), zrule.NewInOperator("_id", values))
+	if err != nil {
+		s.logger.WithError(err).Error("failed to fetch actions by ownerID and actionIDs")
+		return fmt.Errorf("failed to verify actions")
+	}
+
+	if len(actions) != len(values) {
+		return fmt.Errorf("every action must be an existing action of yours")
+	}
+
+	return nil
 
 }
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/eveisesi/zrule"
@@ -22,12 +23,37 @@ func (s *server) handleGetAuthURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	uri := fmt.Sprintf(loginURI, state)
+
+	// Scopes are only requested when a feature needs them, ie. ?scopes=esi-mail.send_mail.v1 before creating an EVE mail action
+	if requested := r.URL.Query().Get("scopes"); requested != "" {
+		scopes := strings.Split(requested, ",")
+		for _, scope := range scopes {
+			if !isKnownScope(scope) {
+				s.writeError(w, http.StatusBadRequest, fmt.Errorf("unknown scope %s, expected one of %v", scope, zrule.AllScopes))
+				return
+			}
+		}
+
+		uri = fmt.Sprintf("%s&scope=%s", uri, url.QueryEscape(strings.Join(scopes, " ")))
+	}
+
 	s.writeResponse(w, http.StatusOK, map[string]interface{}{
-		"url": fmt.Sprintf(loginURI, state),
+		"url": uri,
 	})
 
 }
 
+func isKnownScope(scope string) bool {
+	for _, v := range zrule.AllScopes {
+		if v == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s *server) handleGetAuthLogin(w http.ResponseWriter, r *http.Request) {
 	var ctx = r.Context()
 
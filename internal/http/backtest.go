@@ -0,0 +1,86 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxBacktestRange caps the range that can be backtested over http so that the request
+// completes inside of the servers write timeout. Larger ranges can be backtested with the cli
+const maxBacktestRange = time.Hour * 72
+
+func (s *server) handlePostPolicyBacktest(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	policyID := chi.URLParam(r, "policyID")
+	if policyID == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("policyID is required"))
+		return
+	}
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		err := fmt.Errorf("ctx does not contain a user")
+		s.logger.WithError(err).Errorln()
+		s.writeError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(policyID)
+	if err != nil {
+		msg := "provided policy id is invalid"
+		s.logger.WithError(err).Error(msg)
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf(msg))
+		return
+	}
+
+	var params zrule.BacktestParams
+	defer r.Body.Close()
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read body: %w", err))
+		return
+	}
+
+	if params.End.IsZero() {
+		params.End = time.Now()
+	}
+	if params.Start.IsZero() {
+		params.Start = params.End.Add(-time.Hour * 24)
+	}
+
+	if params.End.Sub(params.Start) > maxBacktestRange {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("backtest range cannot exceed %s", maxBacktestRange))
+		return
+	}
+
+	policies, err := s.policy.Policies(ctx, zrule.NewEqualOperator("owner_id", user.ID), zrule.NewEqualOperator("_id", objectID))
+	if err != nil {
+		err = fmt.Errorf("failed to fetch policies by owner id")
+		s.logger.WithError(err).Errorln()
+		s.writeResponse(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	if len(policies) == 0 {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("failed to locate a policy with ID of %s", policyID))
+		return
+	}
+
+	result, err := s.backtest.Backtest(ctx, policies[0], params)
+	if err != nil {
+		s.logger.WithError(err).WithField("policyID", policyID).Error("failed to backtest policy")
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, result)
+
+}
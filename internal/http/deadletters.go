@@ -0,0 +1,83 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/eveisesi/zrule/internal/dispatcher"
+	"github.com/go-chi/chi"
+)
+
+func (s *server) handleGetDeadLetters(w http.ResponseWriter, r *http.Request) {
+
+	letters, err := s.dispatcher.DeadLetters(r.Context())
+	if err != nil {
+		s.logger.WithError(err).Error("failed to fetch dead letters")
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to fetch dead letters"))
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, letters)
+
+}
+
+func (s *server) handlePostDeadLetterReplay(w http.ResponseWriter, r *http.Request) {
+
+	id := chi.URLParam(r, "deadLetterID")
+	if id == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("deadLetterID is required"))
+		return
+	}
+
+	err := s.dispatcher.ReplayDeadLetter(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, dispatcher.ErrDeadLetterNotFound) {
+			s.writeError(w, http.StatusNotFound, err)
+			return
+		}
+		s.logger.WithError(err).WithField("deadLetterID", id).Error("failed to replay dead letter")
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to replay dead letter"))
+		return
+	}
+
+	s.writeResponse(w, http.StatusNoContent, nil)
+
+}
+
+func (s *server) handleDeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+
+	id := chi.URLParam(r, "deadLetterID")
+	if id == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("deadLetterID is required"))
+		return
+	}
+
+	count, err := s.dispatcher.PurgeDeadLetters(r.Context(), id)
+	if err != nil {
+		s.logger.WithError(err).WithField("deadLetterID", id).Error("failed to purge dead letter")
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to purge dead letter"))
+		return
+	}
+
+	if count == 0 {
+		s.writeError(w, http.StatusNotFound, dispatcher.ErrDeadLetterNotFound)
+		return
+	}
+
+	s.writeResponse(w, http.StatusNoContent, nil)
+
+}
+
+func (s *server) handleDeleteDeadLetters(w http.ResponseWriter, r *http.Request) {
+
+	count, err := s.dispatcher.PurgeDeadLetters(r.Context())
+	if err != nil {
+		s.logger.WithError(err).Error("failed to purge dead letters")
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to purge dead letters"))
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, map[string]int64{"purged": count})
+
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/eveisesi/zrule"
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *server) handleGetActionDeliveries(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	actionID := chi.URLParam(r, "actionID")
+	if actionID == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("actionID is required"))
+		return
+	}
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		err := fmt.Errorf("ctx does not contain a user")
+		s.logger.WithError(err).Errorln()
+		s.writeError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(actionID)
+	if err != nil {
+		msg := "provided action id is invalid"
+		s.logger.WithError(err).Error(msg)
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf(msg))
+		return
+	}
+
+	pagination, err := paginationOperators(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	timeRange, err := timeRangeOperators(r, "created_at")
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	operators := []*zrule.Operator{
+		zrule.NewEqualOperator("owner_id", user.ID),
+		zrule.NewEqualOperator("action_id", objectID),
+		zrule.NewOrderOperator("created_at", zrule.SortDesc),
+	}
+	operators = append(operators, timeRange...)
+	operators = append(operators, pagination...)
+
+	deliveries, err := s.delivery.Deliveries(ctx, operators...)
+	if err != nil {
+		s.logger.WithError(err).WithField("actionID", actionID).Error("failed to fetch deliveries for action")
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to fetch deliveries for action"))
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, deliveries)
+
+}
@@ -0,0 +1,5 @@
+package http
+
+// exampleKillmail is a killmail as it is received from the zkillboard websocket. It is served at /example
+// so that rules can be tried out against it, and templates are validated and previewed against it
+const exampleKillmail = `{"attackers":[{"alliance_id":99005381,"character_id":1950120006,"corporation_id":994783381,"damage_done":5132,"final_blow":true,"security_status":-9.9,"ship_type_id":29340,"weapon_type_id":2205}],"killmail_id":88486806,"killmail_time":"2020-11-09T00:39:43Z","solar_system_id":30002758,"victim":{"alliance_id":99008228,"character_id":1466390582,"corporation_id":795045209,"damage_taken":5132,"items":[{"flag":14,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":13,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":21,"item_type_id":4435,"quantity_dropped":1,"singleton":0},{"flag":92,"item_type_id":34268,"quantity_destroyed":1,"singleton":0},{"flag":20,"item_type_id":4435,"quantity_dropped":1,"singleton":0},{"flag":12,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":11,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":16,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":15,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":19,"item_type_id":35657,"quantity_dropped":1,"singleton":0},{"flag":22,"item_type_id":4435,"quantity_dropped":1,"singleton":0}],"position":{"x":108720300043.89568,"y":-311116434845.82336,"z":-12767733739.166466},"ship_type_id":19744},"zkb":{"locationID":40175118,"hash":"3c9ed419c00f123ff8d46475b05b70616d1381d8","fittedValue":1511799.92,"totalValue":2807175.66,"points":6,"npc":false,"solo":true,"awox":false,"esi":"https://esi.evetech.net/latest/killmails/88486806/3c9ed419c00f123ff8d46475b05b70616d1381d8/","url":"https://zkillboard.com/kill/88486806/"}}`
@@ -11,12 +11,19 @@ import (
 	"github.com/eveisesi/zrule/pkg/ruler"
 
 	"github.com/eveisesi/zrule/internal/action"
+	"github.com/eveisesi/zrule/internal/backtest"
+	"github.com/eveisesi/zrule/internal/delivery"
 	"github.com/eveisesi/zrule/internal/dispatcher"
+	"github.com/eveisesi/zrule/internal/email"
+	"github.com/eveisesi/zrule/internal/killmail"
+	"github.com/eveisesi/zrule/internal/match"
 	"github.com/eveisesi/zrule/internal/policy"
 	"github.com/eveisesi/zrule/internal/search"
+	"github.com/eveisesi/zrule/internal/subscription"
 	"github.com/eveisesi/zrule/internal/token"
 	"github.com/eveisesi/zrule/internal/universe"
 	"github.com/eveisesi/zrule/internal/user"
+	"github.com/eveisesi/zrule/internal/webpush"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-redis/redis/v8"
@@ -33,13 +40,31 @@ type server struct {
 	newrelic *newrelic.Application
 
 	action     action.Service
+	backtest   backtest.Service
+	delivery   delivery.Service
 	dispatcher dispatcher.Service
+	killmail   killmail.Service
+	match      match.Service
 	policy     policy.Service
 	search     search.Service
 	token      token.Service
 	universe   universe.Service
 	user       user.Service
 
+	subscription subscription.Service
+
+	// vapid is nil when web push is not configured
+	vapid *webpush.VAPID
+
+	// confirmer mails the addresses of email actions the link that opts them in
+	confirmer *email.Confirmer
+
+	// allowInternalEndpoints lets actions point at loopback, private, and link local addresses
+	allowInternalEndpoints bool
+
+	// admins are the character ids that are allowed to use the admin endpoints
+	admins []uint64
+
 	server *http.Server
 }
 
@@ -56,6 +81,15 @@ func NewServer(
 	universe universe.Service,
 	dispatcher dispatcher.Service,
 	search search.Service,
+	backtest backtest.Service,
+	match match.Service,
+	delivery delivery.Service,
+	killmail killmail.Service,
+	subscription subscription.Service,
+	vapid *webpush.VAPID,
+	confirmer *email.Confirmer,
+	allowInternalEndpoints bool,
+	admins []uint64,
 ) *server {
 
 	s := &server{
@@ -71,6 +105,17 @@ func NewServer(
 		universe:   universe,
 		dispatcher: dispatcher,
 		search:     search,
+		backtest:   backtest,
+		match:      match,
+		delivery:   delivery,
+		killmail:   killmail,
+		admins:     admins,
+
+		subscription: subscription,
+		vapid:        vapid,
+		confirmer:    confirmer,
+
+		allowInternalEndpoints: allowInternalEndpoints,
 	}
 
 	s.server = &http.Server{
@@ -111,6 +156,8 @@ func (s *server) buildRouter() *chi.Mux {
 		r.Post(newrelic.WrapHandleFunc(s.newrelic, "/auth/login", s.handlePostAuthLogin))
 		r.Get(newrelic.WrapHandleFunc(s.newrelic, "/auth/login/{state}", s.handleGetAuthLogin))
 		r.Get(newrelic.WrapHandleFunc(s.newrelic, "/auth/url/{state}", s.handleGetAuthURL))
+		// Addresses of email actions confirm from the link that they were mailed, without logging in
+		r.Get(newrelic.WrapHandleFunc(s.newrelic, "/actions/{actionID}/confirm/{token}", s.handleGetActionConfirm))
 		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 
 			err := s.db.Client().Ping(r.Context(), nil)
@@ -128,6 +175,8 @@ func (s *server) buildRouter() *chi.Mux {
 			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/policies", s.handleGetPolicies))
 			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/policies/{policyID}", s.handleGetPolicyByID))
 			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/policies/{policyID}/actions", s.handleGetPolicyActions))
+			r.Post(newrelic.WrapHandleFunc(s.newrelic, "/policies/{policyID}/backtest", s.handlePostPolicyBacktest))
+			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/policies/{policyID}/matches", s.handleGetPolicyMatches))
 			r.Post(newrelic.WrapHandleFunc(s.newrelic, "/policies", s.handleCreatePolicy))
 			r.Patch(newrelic.WrapHandleFunc(s.newrelic, "/policies/{policyID}", s.handleUpdatePolicy))
 			r.Delete(newrelic.WrapHandleFunc(s.newrelic, "/policies/{policyID}", s.handleDeletePolicy))
@@ -135,13 +184,31 @@ func (s *server) buildRouter() *chi.Mux {
 			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/actions", s.handleGetActions))
 			r.Post(newrelic.WrapHandleFunc(s.newrelic, "/actions", s.handleCreateAction))
 			r.Post(newrelic.WrapHandleFunc(s.newrelic, "/actions/{actionID}/test", s.handlePostActionTest))
+			r.Post(newrelic.WrapHandleFunc(s.newrelic, "/actions/{actionID}/enable", s.handlePostActionEnable))
+			r.Post(newrelic.WrapHandleFunc(s.newrelic, "/actions/{actionID}/confirmations", s.handlePostActionConfirmations))
+			r.Put(newrelic.WrapHandleFunc(s.newrelic, "/actions/{actionID}/fallbacks", s.handlePutActionFallbacks))
+			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/actions/{actionID}/deliveries", s.handleGetActionDeliveries))
 			// r.Patch(newrelic.WrapHandleFunc(s.newrelic, "/actions/{actionID}", s.handleUpdateAction))
 			r.Delete(newrelic.WrapHandleFunc(s.newrelic, "/actions/{actionID}", s.handleDeleteAction))
 			r.Post(newrelic.WrapHandleFunc(s.newrelic, "/rules/validate", s.handlePostValidateRules))
+			r.Post(newrelic.WrapHandleFunc(s.newrelic, "/templates/preview", s.handlePostTemplatePreview))
+
+			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/webpush/key", s.handleGetWebPushKey))
+			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/webpush/subscriptions", s.handleGetWebPushSubscriptions))
+			r.Post(newrelic.WrapHandleFunc(s.newrelic, "/webpush/subscriptions", s.handlePostWebPushSubscription))
+			r.Delete(newrelic.WrapHandleFunc(s.newrelic, "/webpush/subscriptions/{subscriptionID}", s.handleDeleteWebPushSubscription))
 
 			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/search", s.handleGetSearchName))
 			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/search/categories", s.handleGetSearchCategories))
 			r.Post(newrelic.WrapHandleFunc(s.newrelic, "/search/{category}/{id}", s.handleNewCategoryEntity))
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(s.admin)
+				r.Get(newrelic.WrapHandleFunc(s.newrelic, "/deadletters", s.handleGetDeadLetters))
+				r.Post(newrelic.WrapHandleFunc(s.newrelic, "/deadletters/{deadLetterID}/replay", s.handlePostDeadLetterReplay))
+				r.Delete(newrelic.WrapHandleFunc(s.newrelic, "/deadletters", s.handleDeleteDeadLetters))
+				r.Delete(newrelic.WrapHandleFunc(s.newrelic, "/deadletters/{deadLetterID}", s.handleDeleteDeadLetter))
+			})
+
 			r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
 				s.writeResponse(w, http.StatusNoContent, nil)
 			})
@@ -151,12 +218,7 @@ func (s *server) buildRouter() *chi.Mux {
 			}))
 
 			r.Get(newrelic.WrapHandleFunc(s.newrelic, "/example", func(w http.ResponseWriter, r *http.Request) {
-
-				_, _ = w.Write([]byte(`
-				{"attackers":[{"alliance_id":99005381,"character_id":1950120006,"corporation_id":994783381,"damage_done":5132,"final_blow":true,"security_status":-9.9,"ship_type_id":29340,"weapon_type_id":2205}],"killmail_id":88486806,"killmail_time":"2020-11-09T00:39:43Z","solar_system_id":30002758,"victim":{"alliance_id":99008228,"character_id":1466390582,"corporation_id":795045209,"damage_taken":5132,"items":[{"flag":14,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":13,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":21,"item_type_id":4435,"quantity_dropped":1,"singleton":0},{"flag":92,"item_type_id":34268,"quantity_destroyed":1,"singleton":0},{"flag":20,"item_type_id":4435,"quantity_dropped":1,"singleton":0},{"flag":12,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":11,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":16,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":15,"item_type_id":8263,"quantity_dropped":1,"singleton":0},{"flag":19,"item_type_id":35657,"quantity_dropped":1,"singleton":0},{"flag":22,"item_type_id":4435,"quantity_dropped":1,"singleton":0}],"position":{"x":108720300043.89568,"y":-311116434845.82336,"z":-12767733739.166466},"ship_type_id":19744},"zkb":{"locationID":40175118,"hash":"3c9ed419c00f123ff8d46475b05b70616d1381d8","fittedValue":1511799.92,"totalValue":2807175.66,"points":6,"npc":false,"solo":true,"awox":false,"esi":"https://esi.evetech.net/latest/killmails/88486806/3c9ed419c00f123ff8d46475b05b70616d1381d8/","url":"https://zkillboard.com/kill/88486806/"}}
-
-				`))
-
+				_, _ = w.Write([]byte(exampleKillmail))
 			}))
 
 		})
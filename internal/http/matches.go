@@ -0,0 +1,67 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/eveisesi/zrule"
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *server) handleGetPolicyMatches(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	policyID := chi.URLParam(r, "policyID")
+	if policyID == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("policyID is required"))
+		return
+	}
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		err := fmt.Errorf("ctx does not contain a user")
+		s.logger.WithError(err).Errorln()
+		s.writeError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(policyID)
+	if err != nil {
+		msg := "provided policy id is invalid"
+		s.logger.WithError(err).Error(msg)
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf(msg))
+		return
+	}
+
+	pagination, err := paginationOperators(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	timeRange, err := timeRangeOperators(r, "created_at")
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	operators := []*zrule.Operator{
+		zrule.NewEqualOperator("owner_id", user.ID),
+		zrule.NewEqualOperator("policy_id", objectID),
+		zrule.NewOrderOperator("created_at", zrule.SortDesc),
+	}
+	operators = append(operators, timeRange...)
+	operators = append(operators, pagination...)
+
+	matches, err := s.match.Matches(ctx, operators...)
+	if err != nil {
+		s.logger.WithError(err).WithField("policyID", policyID).Error("failed to fetch matches for policy")
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to fetch matches for policy"))
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, matches)
+
+}
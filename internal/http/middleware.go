@@ -187,6 +187,30 @@ func (s *server) auth(next http.Handler) http.Handler {
 	})
 }
 
+// admin rejects requests from users whose character is not configured as an admin.
+// It must be used after the auth middleware so that the user is available on the context
+func (s *server) admin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		user := UserFromContext(r.Context())
+		if user == nil {
+			s.writeError(w, http.StatusUnauthorized, nil)
+			return
+		}
+
+		for _, characterID := range s.admins {
+			if characterID == user.CharacterID {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		s.logger.WithField("characterID", user.CharacterID).Error("rejecting request: user is not an admin")
+		s.writeError(w, http.StatusForbidden, nil)
+
+	})
+}
+
 func UserFromContext(ctx context.Context) *zrule.User {
 	user, ok := ctx.Value(userCtxKey{}).(*zrule.User)
 	if !ok {
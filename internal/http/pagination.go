@@ -0,0 +1,73 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+const (
+	defaultPageLimit int64 = 50
+	maxPageLimit     int64 = 100
+)
+
+// paginationOperators builds limit and skip operators from the page and limit query parameters.
+// Pages start at 1 and limit defaults to defaultPageLimit, capped at maxPageLimit
+func paginationOperators(r *http.Request) ([]*zrule.Operator, error) {
+
+	var page, limit int64 = 1, defaultPageLimit
+	var err error
+
+	if v := r.URL.Query().Get("page"); v != "" {
+		page, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("page must be a positive integer")
+		}
+	}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("limit must be a positive integer")
+		}
+	}
+
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	return []*zrule.Operator{
+		zrule.NewLimitOperator(limit),
+		zrule.NewSkipOperator((page - 1) * limit),
+	}, nil
+
+}
+
+// timeRangeOperators builds operators against column from the from and to query parameters,
+// both of which are optional and expected to be RFC3339 timestamps
+func timeRangeOperators(r *http.Request, column string) ([]*zrule.Operator, error) {
+
+	operators := make([]*zrule.Operator, 0, 2)
+
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("from must be an RFC3339 timestamp")
+		}
+		operators = append(operators, zrule.NewGreaterThanEqualToOperator(column, from))
+	}
+
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("to must be an RFC3339 timestamp")
+		}
+		operators = append(operators, zrule.NewLessThanOperator(column, to))
+	}
+
+	return operators, nil
+
+}
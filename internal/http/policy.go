@@ -1,9 +1,11 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/eveisesi/zrule"
 	"github.com/eveisesi/zrule/pkg/ruler"
@@ -106,6 +108,24 @@ func (s *server) handleCreatePolicy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = validatePolicySettings(policy)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = s.validateTemplate(ctx, policy.Template)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = s.validatePolicyActions(ctx, user, policy)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	policy, err = s.policy.CreatePolicy(ctx, policy)
 	if err != nil {
 		msg := "failed to insert policy document into datastore"
@@ -194,19 +214,25 @@ func (s *server) handleUpdatePolicy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rulerRules := make([][]*ruler.Rule, len(policy.Rules))
+	err = validateRules(policy.Rules)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
 
-	for i, or := range policy.Rules {
-		rulerRulesInner := make([]*ruler.Rule, 0)
-		err = copier.Copy(&rulerRulesInner, &or)
-		if err != nil {
-			s.writeError(w, http.StatusBadRequest, err)
-			return
-		}
-		rulerRules[i] = rulerRulesInner
+	err = validatePolicySettings(policy)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = s.validateTemplate(ctx, policy.Template)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
 	}
 
-	err = ruler.Validate(rulerRules)
+	err = s.validatePolicyActions(ctx, user, policy)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
@@ -329,3 +355,137 @@ func (s *server) handleDeletePolicy(w http.ResponseWriter, r *http.Request) {
 
 	s.writeResponse(w, http.StatusNoContent, nil)
 }
+
+// validatePolicySettings verifies the schedule of a policy, if it has one, that an expiry, if provided, has not
+// already passed, that a priority and mode, if provided, are known, and that the battle window is not too long
+func validatePolicySettings(policy *zrule.Policy) error {
+
+	if policy.Schedule != nil {
+		if err := policy.Schedule.IsValid(); err != nil {
+			return err
+		}
+	}
+
+	if policy.ExpiresAt != nil && !policy.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("policy expiry must be in the future")
+	}
+
+	if policy.Priority != "" && !policy.Priority.IsValid() {
+		return fmt.Errorf("invalid priority %s, expected one of %v", policy.Priority, zrule.AllPriorities)
+	}
+
+	if !policy.Mode.IsValid() {
+		return fmt.Errorf("invalid mode %s, expected one of %v", policy.Mode, zrule.AllPolicyModes)
+	}
+
+	if policy.BattleWindow > zrule.MaxBattleWindow {
+		return fmt.Errorf("battle window cannot be longer than %d minutes", zrule.MaxBattleWindow)
+	}
+
+	return nil
+
+}
+
+// validatePolicyActions verifies the escalation tiers, links and mentions of a policy, and that the actions that
+// the policy notifies, for every match or through an escalation, belong to the owner of the policy
+func (s *server) validatePolicyActions(ctx context.Context, user *zrule.User, policy *zrule.Policy) error {
+
+	if len(policy.Escalations) > zrule.MaxEscalations {
+		return fmt.Errorf("a policy can have at most %d escalations", zrule.MaxEscalations)
+	}
+
+	actionIDs := append([]primitive.ObjectID{}, policy.Actions...)
+	for _, escalation := range policy.Escalations {
+		if escalation == nil {
+			return fmt.Errorf("escalations cannot be null")
+		}
+		if err := escalation.IsValid(); err != nil {
+			return err
+		}
+		actionIDs = append(actionIDs, escalation.Actions...)
+	}
+
+	err := s.validateOwnedActions(ctx, user, actionIDs)
+	if err != nil {
+		return err
+	}
+
+	linked := make(map[primitive.ObjectID]bool, len(policy.Links))
+	for _, link := range policy.Links {
+		if link == nil {
+			return fmt.Errorf("links cannot be null")
+		}
+		if !policy.HasAction(link.ActionID) {
+			return fmt.Errorf("link to action %s must be for an action of the policy", link.ActionID.Hex())
+		}
+		if linked[link.ActionID] {
+			return fmt.Errorf("action %s is linked more than once", link.ActionID.Hex())
+		}
+		linked[link.ActionID] = true
+
+		err = validateRules(link.Rules)
+		if err != nil {
+			return fmt.Errorf("invalid rules on link to action %s: %w", link.ActionID.Hex(), err)
+		}
+
+		err = s.validateMentions(ctx, link)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// validateMentions verifies that the mentions of a link can be rendered on the platform of the action that it links to
+func (s *server) validateMentions(ctx context.Context, link *zrule.ActionLink) error {
+
+	if link.MentionMinValue < 0 {
+		return fmt.Errorf("minimum value of mentions on link to action %s cannot be negative", link.ActionID.Hex())
+	}
+
+	if len(link.Mentions) == 0 {
+		return nil
+	}
+
+	if len(link.Mentions) > zrule.MaxMentions {
+		return fmt.Errorf("a link can have at most %d mentions", zrule.MaxMentions)
+	}
+
+	action, err := s.action.Action(ctx, link.ActionID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to fetch action of link")
+		return fmt.Errorf("failed to verify actions")
+	}
+
+	for _, mention := range link.Mentions {
+		if mention == nil {
+			return fmt.Errorf("mentions cannot be null")
+		}
+		if err := mention.IsValid(action.Platform); err != nil {
+			return fmt.Errorf("invalid mention on link to action %s: %w", link.ActionID.Hex(), err)
+		}
+	}
+
+	return nil
+
+}
+
+// validateRules validates rules the way the ruler will evaluate them
+func validateRules(rules [][]*zrule.Rule) error {
+
+	rulerRules := make([][]*ruler.Rule, len(rules))
+
+	for i, or := range rules {
+		rulerRulesInner := make([]*ruler.Rule, 0)
+		err := copier.Copy(&rulerRulesInner, &or)
+		if err != nil {
+			return err
+		}
+		rulerRules[i] = rulerRulesInner
+	}
+
+	return ruler.Validate(rulerRules)
+
+}
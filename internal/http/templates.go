@@ -0,0 +1,90 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/eveisesi/zrule"
+	"github.com/eveisesi/zrule/internal/render"
+)
+
+type templatePreviewRequest struct {
+	Template string `json:"template"`
+}
+
+type templatePreviewResponse struct {
+	Message string `json:"message"`
+}
+
+func (s *server) handlePostTemplatePreview(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	var body = new(templatePreviewRequest)
+	defer r.Body.Close()
+	err := json.NewDecoder(r.Body).Decode(body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read body: %w", err))
+		return
+	}
+
+	if body.Template == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("template is required"))
+		return
+	}
+
+	notification, err := s.exampleNotification(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to build example notification")
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to build example notification"))
+		return
+	}
+
+	message, err := render.Render(body.Template, notification)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, templatePreviewResponse{Message: message})
+
+}
+
+// validateTemplate verifies that a template parses and renders against the example killmail. Executing the
+// template catches mistakes that parsing alone does not, such as fields that do not exist on the data model
+func (s *server) validateTemplate(ctx context.Context, text string) error {
+
+	if text == "" {
+		return nil
+	}
+
+	notification, err := s.exampleNotification(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to build example notification: %w", err)
+	}
+
+	_, err = render.Render(text, notification)
+	return err
+
+}
+
+// exampleNotification builds a notification for the example killmail, hydrated exactly as the dispatcher would
+func (s *server) exampleNotification(ctx context.Context) (*zrule.Notification, error) {
+
+	var killmail = new(zrule.Killmail)
+	err := json.Unmarshal([]byte(exampleKillmail), killmail)
+	if err != nil {
+		return nil, err
+	}
+
+	s.killmail.Hydrate(ctx, killmail)
+
+	return &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "Example Policy"},
+		Killmail: killmail,
+		Details:  s.killmail.Details(ctx, killmail),
+	}, nil
+
+}
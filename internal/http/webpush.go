@@ -0,0 +1,154 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/eveisesi/zrule"
+	"github.com/eveisesi/zrule/internal/netguard"
+	"github.com/eveisesi/zrule/internal/webpush"
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// handleGetWebPushKey returns the VAPID public key that the frontend subscribes browsers with
+func (s *server) handleGetWebPushKey(w http.ResponseWriter, r *http.Request) {
+
+	if s.vapid == nil {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("web push is not configured on this server"))
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, map[string]interface{}{
+		"public_key": s.vapid.PublicKey(),
+	})
+
+}
+
+func (s *server) handleGetWebPushSubscriptions(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		err := fmt.Errorf("ctx does not contain a user")
+		s.logger.WithError(err).Errorln()
+		s.writeError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	subscriptions, err := s.subscription.PushSubscriptions(ctx, zrule.NewEqualOperator("user_id", user.ID))
+	if err != nil {
+		err = fmt.Errorf("failed to fetch push subscriptions by user id")
+		s.logger.WithError(err).Errorln()
+		s.writeResponse(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, subscriptions)
+
+}
+
+func (s *server) handlePostWebPushSubscription(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	var subscription = new(zrule.PushSubscription)
+	defer r.Body.Close()
+	err := json.NewDecoder(r.Body).Decode(subscription)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read body: %w", err))
+		return
+	}
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		err := fmt.Errorf("ctx does not contain a user")
+		s.logger.WithError(err).Errorln()
+		s.writeError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	endpoint, err := url.Parse(subscription.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("endpoint must be the https url of a push service"))
+		return
+	}
+
+	if !s.allowInternalEndpoints {
+		if err := netguard.CheckEndpoint(ctx, subscription.Endpoint); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	err = webpush.ValidateKeys(subscription.Keys)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	subscription.ID = primitive.NilObjectID
+	subscription.UserID = user.ID
+	subscription.UserAgent = r.UserAgent()
+
+	subscription, err = s.subscription.SavePushSubscription(ctx, subscription)
+	if err != nil {
+		msg := "failed to save push subscription"
+		s.logger.WithError(err).Error(msg)
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf(msg))
+		return
+	}
+
+	s.writeResponse(w, http.StatusCreated, subscription)
+
+}
+
+func (s *server) handleDeleteWebPushSubscription(w http.ResponseWriter, r *http.Request) {
+
+	var ctx = r.Context()
+
+	subscriptionID := chi.URLParam(r, "subscriptionID")
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		err := fmt.Errorf("ctx does not contain a user")
+		s.logger.WithError(err).Errorln()
+		s.writeError(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(subscriptionID)
+	if err != nil {
+		msg := "provided subscription id is invalid"
+		s.logger.WithError(err).Error(msg)
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf(msg))
+		return
+	}
+
+	subscriptions, err := s.subscription.PushSubscriptions(ctx, zrule.NewEqualOperator("user_id", user.ID), zrule.NewEqualOperator("_id", objectID))
+	if err != nil {
+		err = fmt.Errorf("failed to fetch push subscriptions by user id")
+		s.logger.WithError(err).Errorln()
+		s.writeResponse(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	if len(subscriptions) == 0 {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("failed to locate a push subscription with ID of %s", subscriptionID))
+		return
+	}
+
+	err = s.subscription.DeletePushSubscription(ctx, objectID)
+	if err != nil {
+		msg := "failed to delete push subscription"
+		s.logger.WithError(err).Error(msg)
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf(msg))
+		return
+	}
+
+	s.writeResponse(w, http.StatusNoContent, nil)
+
+}
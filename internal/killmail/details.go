@@ -0,0 +1,126 @@
+package killmail
+
+import (
+	"context"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/sirupsen/logrus"
+)
+
+// maxDetailedAttackers is the number of attackers whose names are looked up. Large fights can have hundreds
+// of attackers, more than any platform is able to display, so only the first attackers and the final blow are
+// detailed. zkillboard orders attackers by damage done, so these are the attackers that mattered the most
+const maxDetailedAttackers = 25
+
+// Details looks up the names of the entities, ships, and locations on a killmail.
+// Lookups that fail are logged and left nil, so the details are always as complete as they can be
+func (s *service) Details(ctx context.Context, killmail *zrule.Killmail) *zrule.KillmailDetails {
+
+	seg := newrelic.FromContext(ctx).StartSegment("lookup killmail details")
+	defer seg.End()
+
+	entry := s.logger.WithField("killmail_id", killmail.ID)
+
+	details := &zrule.KillmailDetails{
+		AttackerCount: len(killmail.Attackers),
+		Attackers:     make([]*zrule.KillmailParticipant, 0, len(killmail.Attackers)),
+	}
+
+	if killmail.Meta != nil {
+		details.TotalValue = killmail.Meta.TotalValue
+	}
+
+	system, err := s.universe.SolarSystem(ctx, killmail.SolarSystemID)
+	if err != nil {
+		entry.WithError(err).WithField("SolarSystemID", killmail.SolarSystemID).Debug("failed to look up solar system")
+	} else {
+		details.SolarSystem = system
+	}
+
+	if killmail.RegionID > 0 {
+		region, err := s.universe.Region(ctx, killmail.RegionID)
+		if err != nil {
+			entry.WithError(err).WithField("RegionID", killmail.RegionID).Debug("failed to look up region")
+		} else {
+			details.Region = region
+		}
+	}
+
+	if killmail.Victim != nil {
+		victim := killmail.Victim
+		details.Victim = s.participant(ctx, entry, victim.CharacterID, victim.CorporationID, victim.AllianceID, victim.FactionID, &victim.ShipTypeID, nil)
+		details.Victim.Damage = victim.DamageTaken
+	}
+
+	for i, attacker := range killmail.Attackers {
+		if i >= maxDetailedAttackers && !attacker.FinalBlow {
+			continue
+		}
+
+		participant := s.participant(ctx, entry, attacker.CharacterID, attacker.CorporationID, attacker.AllianceID, attacker.FactionID, attacker.ShipTypeID, attacker.WeaponTypeID)
+		participant.Damage = attacker.DamageDone
+		participant.FinalBlow = attacker.FinalBlow
+
+		if attacker.FinalBlow {
+			details.FinalBlow = participant
+		}
+		if i < maxDetailedAttackers {
+			details.Attackers = append(details.Attackers, participant)
+		}
+	}
+
+	return details
+
+}
+
+func (s *service) participant(ctx context.Context, entry *logrus.Entry, characterID *uint64, corporationID, allianceID, factionID, shipTypeID, weaponTypeID *uint) *zrule.KillmailParticipant {
+
+	var participant = new(zrule.KillmailParticipant)
+	var err error
+
+	if characterID != nil {
+		participant.Character, err = s.universe.Character(ctx, *characterID)
+		if err != nil {
+			entry.WithError(err).WithField("CharacterID", *characterID).Debug("failed to look up character")
+		}
+	}
+
+	if corporationID != nil {
+		participant.Corporation, err = s.universe.Corporation(ctx, *corporationID)
+		if err != nil {
+			entry.WithError(err).WithField("CorporationID", *corporationID).Debug("failed to look up corporation")
+		}
+	}
+
+	if allianceID != nil {
+		participant.Alliance, err = s.universe.Alliance(ctx, *allianceID)
+		if err != nil {
+			entry.WithError(err).WithField("AllianceID", *allianceID).Debug("failed to look up alliance")
+		}
+	}
+
+	if factionID != nil {
+		participant.Faction, err = s.universe.Faction(ctx, *factionID)
+		if err != nil {
+			entry.WithError(err).WithField("FactionID", *factionID).Debug("failed to look up faction")
+		}
+	}
+
+	if shipTypeID != nil {
+		participant.Ship, err = s.universe.Item(ctx, *shipTypeID)
+		if err != nil {
+			entry.WithError(err).WithField("ShipTypeID", *shipTypeID).Debug("failed to look up ship")
+		}
+	}
+
+	if weaponTypeID != nil {
+		participant.Weapon, err = s.universe.Item(ctx, *weaponTypeID)
+		if err != nil {
+			entry.WithError(err).WithField("WeaponTypeID", *weaponTypeID).Debug("failed to look up weapon")
+		}
+	}
+
+	return participant
+
+}
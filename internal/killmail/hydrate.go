@@ -0,0 +1,78 @@
+package killmail
+
+import (
+	"context"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// Hydrate populates the fields of a killmail that zkillboard does not provide, but that policies are able
+// to match against: the Constellation and Region of the Solar System, and the groups of the ships and weapons
+// involved. Lookups that fail are logged and skipped, so a killmail is always as hydrated as it can be
+func (s *service) Hydrate(ctx context.Context, killmail *zrule.Killmail) {
+
+	entry := s.logger.WithField("killmail_id", killmail.ID)
+	if killmail.Meta != nil {
+		killmail.Hash = killmail.Meta.Hash
+		entry = entry.WithField("killmail_hash", killmail.Hash)
+	}
+
+	system, err := s.universe.SolarSystem(ctx, killmail.SolarSystemID)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		entry.WithError(err).WithField("SolarSystemID", killmail.SolarSystemID).Debug("failed to look up solar system for solar system")
+		return
+	}
+
+	constellation, err := s.universe.Constellation(ctx, system.ConstellationID)
+	if err != nil {
+		newrelic.FromContext(ctx).NoticeError(err)
+		entry.WithError(err).WithField("SolarSystemID", killmail.SolarSystemID).WithField("ConstellationID", system.ConstellationID).Debug("failed to look up constellation for solar system")
+		return
+	}
+
+	killmail.ConstellationID = constellation.ID
+	killmail.RegionID = constellation.RegionID
+
+	if killmail.Victim != nil {
+		victimShip, err := s.universe.Item(ctx, killmail.Victim.ShipTypeID)
+		if err != nil {
+			newrelic.FromContext(ctx).NoticeError(err)
+			entry.WithError(err).WithField("Victim.ShipTypeID", killmail.Victim.ShipTypeID).Debug("failed to lookup victim ship")
+		} else {
+			killmail.Victim.ShipGroupID = victimShip.GroupID
+		}
+	}
+
+	if len(killmail.Attackers) > 0 {
+		for i, attacker := range killmail.Attackers {
+
+			if attacker.ShipTypeID != nil {
+				attackerShip, err := s.universe.Item(ctx, *attacker.ShipTypeID)
+				if err != nil {
+					newrelic.FromContext(ctx).NoticeError(err)
+					entry.WithError(err).WithField("attackerID", i).Debug("failed to lookup attacker ship")
+					continue
+				}
+
+				attacker.ShipGroupID = &attackerShip.GroupID
+
+			}
+
+			if attacker.WeaponTypeID != nil {
+				attackerWeapon, err := s.universe.Item(ctx, *attacker.WeaponTypeID)
+				if err != nil {
+					newrelic.FromContext(ctx).NoticeError(err)
+					entry.WithError(err).WithField("attackerID", i).Debug("failed to lookup attacker ship")
+					continue
+				}
+
+				attacker.WeaponGroupID = &attackerWeapon.GroupID
+
+			}
+
+		}
+	}
+
+}
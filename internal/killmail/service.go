@@ -0,0 +1,104 @@
+package killmail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/eveisesi/zrule/internal/universe"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type Service interface {
+	Hydrate(ctx context.Context, killmail *zrule.Killmail)
+	Details(ctx context.Context, killmail *zrule.Killmail) *zrule.KillmailDetails
+	KillmailsByTimeRange(ctx context.Context, start, end time.Time, operators ...*zrule.Operator) ([]*zrule.Killmail, error)
+	KillmailsBySolarSystem(ctx context.Context, id uint, operators ...*zrule.Operator) ([]*zrule.Killmail, error)
+	KillmailsByEntity(ctx context.Context, category zrule.PathCategory, id uint64, operators ...*zrule.Operator) ([]*zrule.Killmail, error)
+
+	zrule.KillmailRepository
+}
+
+type service struct {
+	logger   *logrus.Logger
+	universe universe.Service
+
+	zrule.KillmailRepository
+}
+
+func NewService(logger *logrus.Logger, universe universe.Service, killmail zrule.KillmailRepository) Service {
+	return &service{
+		logger:   logger,
+		universe: universe,
+
+		KillmailRepository: killmail,
+	}
+}
+
+func (s *service) Killmails(ctx context.Context, operators ...*zrule.Operator) ([]*zrule.Killmail, error) {
+
+	killmails, err := s.KillmailRepository.Killmails(ctx, operators...)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return killmails, err
+	}
+
+	return killmails, nil
+
+}
+
+// KillmailsByTimeRange returns archived killmails that occurred at or after start and before end. The killmails
+// are not sorted, so callers that page through them must supply order operators that sort them uniquely
+func (s *service) KillmailsByTimeRange(ctx context.Context, start, end time.Time, operators ...*zrule.Operator) ([]*zrule.Killmail, error) {
+
+	operators = append([]*zrule.Operator{
+		zrule.NewGreaterThanEqualToOperator("killmail_time", start),
+		zrule.NewLessThanOperator("killmail_time", end),
+	}, operators...)
+
+	return s.Killmails(ctx, operators...)
+
+}
+
+func (s *service) KillmailsBySolarSystem(ctx context.Context, id uint, operators ...*zrule.Operator) ([]*zrule.Killmail, error) {
+
+	operators = append([]*zrule.Operator{
+		zrule.NewEqualOperator("solar_system_id", id),
+		zrule.NewOrderOperator("killmail_time", zrule.SortDesc),
+	}, operators...)
+
+	return s.Killmails(ctx, operators...)
+
+}
+
+// KillmailsByEntity returns archived killmails where the entity was either the victim or one of the attackers.
+// Supported categories are characters, corporations, alliances, and factions
+func (s *service) KillmailsByEntity(ctx context.Context, category zrule.PathCategory, id uint64, operators ...*zrule.Operator) ([]*zrule.Killmail, error) {
+
+	var column string
+	switch category {
+	case zrule.PathCategoryCharacter:
+		column = "character_id"
+	case zrule.PathCategoryCorporation:
+		column = "corporation_id"
+	case zrule.PathCategoryAlliance:
+		column = "alliance_id"
+	case zrule.PathCategoryFaction:
+		column = "faction_id"
+	default:
+		return nil, fmt.Errorf("unsupported entity category %s, expected one of character, corporation, alliance, or faction", category)
+	}
+
+	operators = append([]*zrule.Operator{
+		zrule.NewOrOperator(
+			zrule.NewEqualOperator(fmt.Sprintf("victim.%s", column), id),
+			zrule.NewEqualOperator(fmt.Sprintf("attackers.%s", column), id),
+		),
+		zrule.NewOrderOperator("killmail_time", zrule.SortDesc),
+	}, operators...)
+
+	return s.Killmails(ctx, operators...)
+
+}
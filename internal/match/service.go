@@ -0,0 +1,34 @@
+package match
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type Service interface {
+	zrule.MatchRepository
+}
+
+type service struct {
+	zrule.MatchRepository
+}
+
+func NewService(match zrule.MatchRepository) Service {
+	return &service{
+		MatchRepository: match,
+	}
+}
+
+func (s *service) Matches(ctx context.Context, operators ...*zrule.Operator) ([]*zrule.Match, error) {
+
+	matches, err := s.MatchRepository.Matches(ctx, operators...)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return matches, err
+	}
+
+	return matches, nil
+
+}
@@ -0,0 +1,63 @@
+package matrix
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/eveisesi/zrule"
+)
+
+// message formats a notification as a plain text body, for clients that do not render HTML, and
+// the equivalent formatted body
+func message(notification *zrule.Notification, layout zrule.Layout) (string, string) {
+
+	killmail, details := notification.Killmail, notification.Details
+
+	zkill := killmail.ZKillboardURL()
+	link := fmt.Sprintf(`<a href="%s">zKillboard</a>`, html.EscapeString(zkill))
+
+	if details == nil || details.Victim == nil {
+		return fmt.Sprintf("Match Found with %s %s\n%s", notification.PolicyNoun(), notification.PolicyNames(), zkill),
+			fmt.Sprintf("Match Found with %s <strong>%s</strong><br>%s", notification.PolicyNoun(), html.EscapeString(notification.PolicyNames()), link)
+	}
+
+	victim := details.Victim
+	system := details.Location()
+
+	if layout == zrule.LayoutCompact {
+		return fmt.Sprintf(
+				"%s lost a %s in %s worth %s\n%s",
+				victim.Label(), victim.ShipName(), system, zrule.FormatISK(details.TotalValue), zkill,
+			), fmt.Sprintf(
+				"%s lost a <strong>%s</strong> in %s worth %s<br>%s",
+				html.EscapeString(victim.Label()), html.EscapeString(victim.ShipName()),
+				html.EscapeString(system), zrule.FormatISK(details.TotalValue), link,
+			)
+	}
+
+	lines := [][2]string{{"Victim", victim.Label()}}
+	if details.Region != nil {
+		lines = append(lines, [2]string{"Region", details.Region.Name})
+	}
+	lines = append(lines,
+		[2]string{"Value", zrule.FormatISK(details.TotalValue)},
+		[2]string{"Attackers", fmt.Sprintf("%d", details.AttackerCount)},
+	)
+	if details.FinalBlow != nil {
+		lines = append(lines, [2]string{"Final Blow", fmt.Sprintf("%s (%s)", details.FinalBlow.Label(), details.FinalBlow.ShipName())})
+	}
+
+	var plain, formatted strings.Builder
+	fmt.Fprintf(&plain, "%s destroyed in %s\n", victim.ShipName(), system)
+	fmt.Fprintf(&formatted, "<strong>%s destroyed in %s</strong><br>", html.EscapeString(victim.ShipName()), html.EscapeString(system))
+	for _, line := range lines {
+		fmt.Fprintf(&plain, "%s: %s\n", line[0], line[1])
+		fmt.Fprintf(&formatted, "%s: %s<br>", line[0], html.EscapeString(line[1]))
+	}
+	fmt.Fprintf(&plain, "Matched %s %s\n%s", notification.PolicyNoun(), notification.PolicyNames(), zkill)
+	fmt.Fprintf(&formatted, "<em>Matched %s %s</em><br>%s", notification.PolicyNoun(), html.EscapeString(notification.PolicyNames()), link)
+
+	return plain.String(), formatted.String()
+
+}
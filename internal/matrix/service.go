@@ -0,0 +1,146 @@
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/eveisesi/zrule/internal/ratelimit"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+type service struct {
+	client   *http.Client
+	actionID string
+	endpoint string
+	token    string
+	roomID   string
+	layout   zrule.Layout
+}
+
+func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformMatrix {
+		return nil, fmt.Errorf("invalid platform for matrix service constructor")
+	}
+
+	if action.Token == "" || action.Recipient == "" {
+		return nil, fmt.Errorf("matrix actions require an access token and room id")
+	}
+
+	return &service{
+		client:   client,
+		actionID: action.ID.Hex(),
+		endpoint: strings.TrimSuffix(action.Endpoint, "/"),
+		token:    action.Token,
+		roomID:   action.Recipient,
+		layout:   action.Layout,
+	}, nil
+
+}
+
+// roomMessage is the content of an m.room.message event
+// https://spec.matrix.org/v1.1/client-server-api/#mroommessage
+type roomMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+type errorResponse struct {
+	ErrCode      string `json:"errcode"`
+	Error        string `json:"error"`
+	RetryAfterMS int64  `json:"retry_after_ms"`
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send matrix message")
+	defer seg.End()
+
+	var content *roomMessage
+	if notification.Message != "" {
+		content = &roomMessage{MsgType: "m.text", Body: notification.Message}
+	} else {
+		plain, formatted := message(notification, s.layout)
+		content = &roomMessage{MsgType: "m.text", Body: plain, Format: "org.matrix.custom.html", FormattedBody: formatted}
+	}
+
+	// The homeserver drops events that reuse a transaction id, so a notification that is retried after
+	// the homeserver accepted it, but before we saw the response, is not posted to the room twice
+	return s.sendMessage(ctx, s.transactionID(notification.Policy.ID.Hex(), notification.Killmail.ID), content)
+
+}
+
+func (s *service) SendTest(ctx context.Context, message string) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send matrix test message")
+	defer seg.End()
+
+	txnID := s.transactionID("test", uint(time.Now().UnixNano()))
+
+	return s.sendMessage(ctx, txnID, &roomMessage{MsgType: "m.notice", Body: message})
+
+}
+
+// transactionID derives the id of the transaction that an event is sent with from what the event is about,
+// so that every attempt at delivering the same notification to this action uses the same id
+func (s *service) transactionID(scope string, id uint) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", s.actionID, scope, id)))
+	return "zrule-" + hex.EncodeToString(sum[:16])
+}
+
+func (s *service) sendMessage(ctx context.Context, txnID string, content *roomMessage) error {
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request body to send to matrix: %w", err)
+	}
+
+	uri := fmt.Sprintf(
+		"%s/_matrix/client/r0/rooms/%s/send/m.room.message/%s",
+		s.endpoint, url.PathEscape(s.roomID), url.PathEscape(txnID),
+	)
+
+	// Every event is sent to a url of its own, so the rate limit is kept per room instead
+	ctx = ratelimit.WithBucket(ctx, fmt.Sprintf("%s/rooms/%s", s.endpoint, s.roomID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uri, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to prepare request to matrix: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.token))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request to matrix: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	data, _ = ioutil.ReadAll(res.Body)
+
+	var result = new(errorResponse)
+	_ = json.Unmarshal(data, result)
+
+	if result.RetryAfterMS > 0 {
+		return &zrule.RateLimitError{RetryAfter: time.Duration(result.RetryAfterMS) * time.Millisecond}
+	}
+
+	return fmt.Errorf("matrix rejected message with status code %d: %s %s", res.StatusCode, result.ErrCode, result.Error)
+
+}
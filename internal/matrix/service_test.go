@@ -0,0 +1,95 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSendReusesTransactionID(t *testing.T) {
+
+	var paths []string
+	var received *roomMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("expected PUT, got %s", r.Method)
+		}
+		if r.Header.Get("Authorization") != "Bearer syt_token" {
+			t.Errorf("unexpected authorization header %s", r.Header.Get("Authorization"))
+		}
+
+		paths = append(paths, r.URL.EscapedPath())
+
+		received = new(roomMessage)
+		_ = json.NewDecoder(r.Body).Decode(received)
+
+		_, _ = w.Write([]byte(`{"event_id":"$abc"}`))
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{ID: primitive.NewObjectID(), Platform: zrule.PlatformMatrix, Endpoint: server.URL, Token: "syt_token", Recipient: "!room:example.com"}
+	dispatcher, err := NewService(action, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	notification := &zrule.Notification{
+		Policy:   &zrule.Policy{ID: primitive.NewObjectID(), Name: "<Test>"},
+		Killmail: &zrule.Killmail{ID: 88486806, Hash: "3c9ed419c00f123ff8d46475b05b70616d1381d8"},
+	}
+
+	for i := 0; i < 2; i++ {
+		err = dispatcher.Send(context.Background(), notification)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if !strings.HasPrefix(paths[0], "/_matrix/client/r0/rooms/%21room:example.com/send/m.room.message/zrule-") {
+		t.Errorf("unexpected path %s", paths[0])
+	}
+
+	if paths[0] != paths[1] {
+		t.Errorf("expected retries to reuse the transaction id, got %s and %s", paths[0], paths[1])
+	}
+
+	if received.Format != "org.matrix.custom.html" || !strings.Contains(received.FormattedBody, "&lt;Test&gt;") || !strings.Contains(received.Body, "<Test>") {
+		t.Errorf("unexpected message received: %+v", received)
+	}
+
+}
+
+func TestSendReturnsRateLimitError(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"errcode":"M_LIMIT_EXCEEDED","error":"Too many requests","retry_after_ms":2500}`))
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformMatrix, Endpoint: server.URL, Token: "syt_token", Recipient: "!room:example.com"}
+	dispatcher, err := NewService(action, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.SendTest(context.Background(), "test")
+
+	var rateLimited *zrule.RateLimitError
+	if !errors.As(err, &rateLimited) {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+
+	if rateLimited.RetryAfter != 2500*time.Millisecond {
+		t.Errorf("expected retry after of 2.5s, got %s", rateLimited.RetryAfter)
+	}
+
+}
@@ -0,0 +1,101 @@
+package mattermost
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eveisesi/zrule"
+)
+
+// Limits imposed by Mattermost on webhook posts
+// https://developers.mattermost.com/integrate/admin-guide/admin-message-attachments/
+const (
+	maxTextLength    = 16383
+	maxAttackerLines = 10
+	color            = "#c0392b"
+)
+
+type attachment map[string]interface{}
+
+func field(title, value string, short bool) attachment {
+	return attachment{"title": title, "value": value, "short": short}
+}
+
+// escape escapes the characters that Mattermost treats as markdown, so that names are shown as written
+func escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "[", `\[`, "]", `\]`).Replace(text)
+}
+
+// attachments builds the message attachment for a notification. The fallback of the attachment is
+// the plain text that Mattermost displays in notifications
+func attachments(notification *zrule.Notification, layout zrule.Layout) []attachment {
+
+	killmail, details := notification.Killmail, notification.Details
+
+	footer := fmt.Sprintf("Matched %s %s", notification.PolicyNoun(), notification.PolicyNames())
+	links := fmt.Sprintf("[View on zKillboard](%s)", killmail.ZKillboardURL())
+	if notification.PolicyURL != "" {
+		links = fmt.Sprintf("%s | [View Policy](%s)", links, notification.PolicyURL)
+	}
+
+	if details == nil || details.Victim == nil {
+		text := fmt.Sprintf("Match Found with %s %s", notification.PolicyNoun(), notification.PolicyNames())
+		return []attachment{{
+			"fallback": text,
+			"color":    color,
+			"text":     fmt.Sprintf("Match Found with %s **%s**\n%s", notification.PolicyNoun(), escape(notification.PolicyNames()), links),
+			"footer":   footer,
+		}}
+	}
+
+	victim := details.Victim
+	system := details.Location()
+
+	text := fmt.Sprintf("%s lost a %s in %s worth %s", victim.Label(), victim.ShipName(), system, zrule.FormatISK(details.TotalValue))
+
+	a := attachment{
+		"fallback":   text,
+		"color":      color,
+		"title":      fmt.Sprintf("%s destroyed in %s", victim.ShipName(), system),
+		"title_link": killmail.ZKillboardURL(),
+		"footer":     footer,
+	}
+	if victim.Ship != nil {
+		a["thumb_url"] = zrule.ShipRenderURL(victim.Ship.ID, 64)
+	}
+
+	if layout == zrule.LayoutCompact {
+		a["text"] = fmt.Sprintf("%s lost a **%s** worth %s\n%s", escape(victim.Label()), escape(victim.ShipName()), zrule.FormatISK(details.TotalValue), links)
+		return []attachment{a}
+	}
+
+	fields := []attachment{field("Victim", escape(victim.Label()), true)}
+	if details.Region != nil {
+		fields = append(fields, field("Region", escape(details.Region.Name), true))
+	}
+	fields = append(fields,
+		field("Value", zrule.FormatISK(details.TotalValue), true),
+		field("Attackers", fmt.Sprintf("%d", details.AttackerCount), true),
+	)
+	if details.FinalBlow != nil {
+		fields = append(fields, field("Final Blow", fmt.Sprintf("%s (%s)", escape(details.FinalBlow.Label()), escape(details.FinalBlow.ShipName())), false))
+	}
+
+	if len(details.Attackers) > 0 {
+		lines := make([]string, 0, maxAttackerLines+1)
+		for i, attacker := range details.Attackers {
+			if i == maxAttackerLines {
+				lines = append(lines, fmt.Sprintf("and %d more", details.AttackerCount-maxAttackerLines))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("%s, %s, %d damage", escape(attacker.Label()), escape(attacker.ShipName()), attacker.Damage))
+		}
+		fields = append(fields, field("Top Attackers", strings.Join(lines, "\n"), false))
+	}
+
+	a["fields"] = fields
+	a["text"] = links
+
+	return []attachment{a}
+
+}
@@ -0,0 +1,94 @@
+package mattermost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+type service struct {
+	client  *http.Client
+	webhook string
+	layout  zrule.Layout
+}
+
+func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformMattermost {
+		return nil, fmt.Errorf("invalid platform for mattermost service constructor")
+	}
+
+	return &service{
+		client:  client,
+		webhook: action.Endpoint,
+		layout:  action.Layout,
+	}, nil
+
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send mattermost message")
+	defer seg.End()
+
+	payload := map[string]interface{}{
+		"attachments": attachments(notification, s.layout),
+	}
+
+	// A rendered template is posted as the message above the attachment
+	if notification.Message != "" {
+		payload["text"] = zrule.Truncate(notification.Message, maxTextLength)
+	}
+
+	return s.post(ctx, payload)
+
+}
+
+func (s *service) SendTest(ctx context.Context, message string) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send mattermost test message")
+	defer seg.End()
+
+	return s.post(ctx, map[string]interface{}{
+		"text": message,
+	})
+
+}
+
+func (s *service) post(ctx context.Context, payload map[string]interface{}) error {
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request body to post to mattermost: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhook, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to prepare request to mattermost: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request to mattermost: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		data, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decode error response: %w", err)
+		}
+
+		return fmt.Errorf("invalid response code %d received from mattermost: %s", res.StatusCode, string(data))
+	}
+
+	return nil
+
+}
@@ -0,0 +1,63 @@
+package mattermost
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestSendPostsAttachment(t *testing.T) {
+
+	var received struct {
+		Text        string                   `json:"text"`
+		Attachments []map[string]interface{} `json:"attachments"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	dispatcher, err := NewService(&zrule.Action{Platform: zrule.PlatformMattermost, Endpoint: server.URL}, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.Send(context.Background(), &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "Capital_Losses"},
+		Killmail: &zrule.Killmail{ID: 88486806, Hash: "3c9ed419c00f123ff8d46475b05b70616d1381d8"},
+		Details: &zrule.KillmailDetails{
+			Victim:        &zrule.KillmailParticipant{Character: &zrule.Character{Name: "Some_Pilot"}},
+			AttackerCount: 3,
+			TotalValue:    2810000,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received.Text != "" || len(received.Attachments) != 1 {
+		t.Fatalf("unexpected message received: %+v", received)
+	}
+
+	attachment := received.Attachments[0]
+	if attachment["title_link"] != "https://zkillboard.com/kill/88486806/" {
+		t.Errorf("expected the title to link to zkillboard, got %v", attachment["title_link"])
+	}
+
+	fields, _ := attachment["fields"].([]interface{})
+	if len(fields) == 0 || fields[0].(map[string]interface{})["value"] != `Some\_Pilot` {
+		t.Errorf("expected the victim to be escaped, got %v", fields)
+	}
+
+	if !strings.HasPrefix(attachment["fallback"].(string), "Some_Pilot lost a Unknown") {
+		t.Errorf("unexpected fallback %v", attachment["fallback"])
+	}
+
+}
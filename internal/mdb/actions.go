@@ -24,9 +24,19 @@ func NewActionRepository(d *mongo.Database) (zrule.ActionRepository, error) {
 		return nil, fmt.Errorf("failed to initialize action repository. Error encountered configuring ownerIDIdx on collection: %w", err)
 	}
 
-	_, err = actions.Indexes().CreateOne(context.Background(), mongo.IndexModel{Keys: bsonx.Doc{{Key: "endpoint", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("endpointUniqueIdx"), Unique: newBool(true)}})
+	// Endpoints used to be unique on their own, and then by their endpoint and recipient together. Platforms like
+	// Telegram and Matrix share an endpoint between every action, and a chat or room can be posted to by any number of
+	// bots and users, so actions are now unique by their endpoint, recipient, token and owner together
+	for _, name := range []string{"endpointUniqueIdx", "endpointRecipientUniqueIdx"} {
+		err = dropIndex(context.Background(), actions, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize action repository. Error encountered dropping %s on collection: %w", name, err)
+		}
+	}
+
+	_, err = actions.Indexes().CreateOne(context.Background(), mongo.IndexModel{Keys: bsonx.Doc{{Key: "endpoint", Value: bsonx.Int32(1)}, {Key: "recipient", Value: bsonx.Int32(1)}, {Key: "token", Value: bsonx.Int32(1)}, {Key: "owner_id", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("endpointRecipientTokenOwnerUniqueIdx"), Unique: newBool(true)}})
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize action repository. Error encountered configuring endpointUniqueIdx on collection: %w", err)
+		return nil, fmt.Errorf("failed to initialize action repository. Error encountered configuring endpointRecipientTokenOwnerUniqueIdx on collection: %w", err)
 	}
 
 	return &actionRepository{
@@ -85,6 +95,150 @@ func (r *actionRepository) UpdateAction(ctx context.Context, id primitive.Object
 	return action, err
 }
 
+func (r *actionRepository) AddInfraction(ctx context.Context, id primitive.ObjectID, infraction *zrule.Infraction, keep int) (*zrule.Action, error) {
+
+	update := primitive.D{
+		primitive.E{Key: "$push", Value: primitive.D{
+			primitive.E{Key: "infractions", Value: primitive.D{
+				primitive.E{Key: "$each", Value: primitive.A{infraction}},
+				primitive.E{Key: "$slice", Value: -keep},
+			}},
+		}},
+		primitive.E{Key: "$set", Value: primitive.D{primitive.E{Key: "updated_at", Value: time.Now()}}},
+	}
+
+	action := new(zrule.Action)
+	err := r.actions.FindOneAndUpdate(ctx, primitive.D{primitive.E{Key: "_id", Value: id}}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(action)
+
+	return action, err
+
+}
+
+func (r *actionRepository) ClearInfractions(ctx context.Context, id primitive.ObjectID) error {
+
+	update := primitive.D{
+		primitive.E{Key: "$set", Value: primitive.D{
+			primitive.E{Key: "infractions", Value: primitive.A{}},
+			primitive.E{Key: "updated_at", Value: time.Now()},
+		}},
+	}
+
+	_, err := r.actions.UpdateOne(ctx, primitive.D{primitive.E{Key: "_id", Value: id}}, update)
+
+	return err
+
+}
+
+func (r *actionRepository) DisableAction(ctx context.Context, id primitive.ObjectID, reason string) error {
+
+	update := primitive.D{
+		primitive.E{Key: "$set", Value: primitive.D{
+			primitive.E{Key: "is_disabled", Value: true},
+			primitive.E{Key: "disabled_reason", Value: reason},
+			primitive.E{Key: "updated_at", Value: time.Now()},
+		}},
+	}
+
+	_, err := r.actions.UpdateOne(ctx, primitive.D{primitive.E{Key: "_id", Value: id}}, update)
+
+	return err
+
+}
+
+func (r *actionRepository) SetActionSecret(ctx context.Context, id primitive.ObjectID, secret string) (bool, error) {
+
+	filter := primitive.D{
+		primitive.E{Key: "_id", Value: id},
+		primitive.E{Key: "$or", Value: primitive.A{
+			primitive.D{primitive.E{Key: "secret", Value: primitive.D{primitive.E{Key: "$exists", Value: false}}}},
+			primitive.D{primitive.E{Key: "secret", Value: ""}},
+		}},
+	}
+
+	update := primitive.D{
+		primitive.E{Key: "$set", Value: primitive.D{
+			primitive.E{Key: "secret", Value: secret},
+			primitive.E{Key: "updated_at", Value: time.Now()},
+		}},
+	}
+
+	result, err := r.actions.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+
+}
+
+func (r *actionRepository) SetEmailConfirmations(ctx context.Context, id primitive.ObjectID, confirmations []*zrule.EmailConfirmation) (bool, error) {
+
+	filter := primitive.D{
+		primitive.E{Key: "_id", Value: id},
+		primitive.E{Key: "confirmations", Value: primitive.D{primitive.E{Key: "$exists", Value: false}}},
+	}
+
+	update := primitive.D{
+		primitive.E{Key: "$set", Value: primitive.D{
+			primitive.E{Key: "confirmations", Value: confirmations},
+			primitive.E{Key: "is_disabled", Value: true},
+			primitive.E{Key: "disabled_reason", Value: zrule.ReasonAwaitingConfirmation},
+			primitive.E{Key: "updated_at", Value: time.Now()},
+		}},
+	}
+
+	result, err := r.actions.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+
+}
+
+func (r *actionRepository) ConfirmEmail(ctx context.Context, id primitive.ObjectID, token string) (*zrule.Action, error) {
+
+	filter := primitive.D{
+		primitive.E{Key: "_id", Value: id},
+		primitive.E{Key: "confirmations.token", Value: token},
+	}
+
+	update := primitive.D{
+		primitive.E{Key: "$set", Value: primitive.D{
+			primitive.E{Key: "confirmations.$.confirmed_at", Value: time.Now()},
+			primitive.E{Key: "updated_at", Value: time.Now()},
+		}},
+	}
+
+	action := new(zrule.Action)
+	err := r.actions.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(action)
+
+	return action, err
+
+}
+
+func (r *actionRepository) EnableAction(ctx context.Context, id primitive.ObjectID, reason string) error {
+
+	filter := primitive.D{
+		primitive.E{Key: "_id", Value: id},
+		primitive.E{Key: "is_disabled", Value: true},
+		primitive.E{Key: "disabled_reason", Value: reason},
+	}
+
+	update := primitive.D{
+		primitive.E{Key: "$set", Value: primitive.D{
+			primitive.E{Key: "is_disabled", Value: false},
+			primitive.E{Key: "disabled_reason", Value: nil},
+			primitive.E{Key: "updated_at", Value: time.Now()},
+		}},
+	}
+
+	_, err := r.actions.UpdateOne(ctx, filter, update)
+
+	return err
+
+}
+
 func (r *actionRepository) DeleteAction(ctx context.Context, id primitive.ObjectID) error {
 
 	_, err := r.actions.DeleteOne(ctx, primitive.D{primitive.E{Key: "_id", Value: id}})
@@ -0,0 +1,70 @@
+package mdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+type battleRepository struct {
+	battles *mongo.Collection
+}
+
+func NewBattleRepository(d *mongo.Database, retention time.Duration) (zrule.BattleRepository, error) {
+
+	battles := d.Collection("battles")
+	_, err := battles.Indexes().CreateOne(context.Background(), mongo.IndexModel{Keys: bsonx.Doc{{Key: "policy_id", Value: bsonx.Int32(1)}, {Key: "action_id", Value: bsonx.Int32(1)}, {Key: "solar_system_id", Value: bsonx.Int32(1)}, {Key: "ended_at", Value: bsonx.Int32(-1)}}, Options: &options.IndexOptions{Name: newString("policyIDActionIDSolarSystemIDEndedAtIdx")}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize battle repository. Error encountered configuring policyIDActionIDSolarSystemIDEndedAtIdx on collection: %w", err)
+	}
+
+	err = ensureTTLIndex(context.Background(), battles, "updated_at", "battleRetentionIdx", retention)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize battle repository. Error encountered configuring battleRetentionIdx on collection: %w", err)
+	}
+
+	return &battleRepository{
+		battles: battles,
+	}, nil
+
+}
+
+func (r *battleRepository) Battle(ctx context.Context, policyID, actionID primitive.ObjectID, solarSystemID uint, t time.Time, window time.Duration) (*zrule.Battle, error) {
+
+	filter := primitive.D{
+		primitive.E{Key: "policy_id", Value: policyID},
+		primitive.E{Key: "action_id", Value: actionID},
+		primitive.E{Key: "solar_system_id", Value: solarSystemID},
+		primitive.E{Key: "started_at", Value: primitive.D{primitive.E{Key: "$lte", Value: t.Add(window)}}},
+		primitive.E{Key: "ended_at", Value: primitive.D{primitive.E{Key: "$gte", Value: t.Add(-window)}}},
+	}
+
+	battle := new(zrule.Battle)
+	err := r.battles.FindOne(ctx, filter, options.FindOne().SetSort(primitive.D{primitive.E{Key: "ended_at", Value: -1}})).Decode(battle)
+	return battle, err
+
+}
+
+func (r *battleRepository) SaveBattle(ctx context.Context, battle *zrule.Battle) (*zrule.Battle, error) {
+
+	now := time.Now()
+	battle.UpdatedAt = now
+
+	if battle.ID.IsZero() {
+		battle.ID = primitive.NewObjectID()
+		battle.CreatedAt = now
+		_, err := r.battles.InsertOne(ctx, battle)
+		return battle, err
+	}
+
+	_, err := r.battles.ReplaceOne(ctx, primitive.D{primitive.E{Key: "_id", Value: battle.ID}}, battle)
+
+	return battle, err
+
+}
@@ -0,0 +1,67 @@
+package mdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+type deliveryRepository struct {
+	deliveries *mongo.Collection
+}
+
+func NewDeliveryRepository(d *mongo.Database, retention time.Duration) (zrule.DeliveryRepository, error) {
+
+	deliveries := d.Collection("deliveries")
+	_, err := deliveries.Indexes().CreateOne(context.Background(), mongo.IndexModel{Keys: bsonx.Doc{{Key: "action_id", Value: bsonx.Int32(1)}, {Key: "created_at", Value: bsonx.Int32(-1)}}, Options: &options.IndexOptions{Name: newString("actionIDCreatedAtIdx")}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize delivery repository. Error encountered configuring actionIDCreatedAtIdx on collection: %w", err)
+	}
+
+	err = ensureTTLIndex(context.Background(), deliveries, "created_at", "deliveryRetentionIdx", retention)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize delivery repository. Error encountered configuring deliveryRetentionIdx on collection: %w", err)
+	}
+
+	return &deliveryRepository{
+		deliveries: deliveries,
+	}, nil
+
+}
+
+func (r *deliveryRepository) Deliveries(ctx context.Context, operators ...*zrule.Operator) ([]*zrule.Delivery, error) {
+
+	filters := BuildFilters(operators...)
+	options := BuildFindOptions(operators...)
+
+	var deliveries = make([]*zrule.Delivery, 0)
+	result, err := r.deliveries.Find(ctx, filters, options)
+	if err != nil {
+		return deliveries, err
+	}
+
+	err = result.All(ctx, &deliveries)
+	return deliveries, err
+
+}
+
+func (r *deliveryRepository) CreateDelivery(ctx context.Context, delivery *zrule.Delivery) (*zrule.Delivery, error) {
+
+	delivery.CreatedAt = time.Now()
+
+	result, err := r.deliveries.InsertOne(ctx, delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.ID = result.InsertedID.(primitive.ObjectID)
+
+	return delivery, nil
+
+}
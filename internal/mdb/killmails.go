@@ -0,0 +1,88 @@
+package mdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+type killmailRepository struct {
+	killmails *mongo.Collection
+}
+
+func NewKillmailRepository(d *mongo.Database, retention time.Duration) (zrule.KillmailRepository, error) {
+
+	killmails := d.Collection("killmails")
+	_, err := killmails.Indexes().CreateOne(context.Background(), mongo.IndexModel{Keys: bsonx.Doc{{Key: "killmail_id", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("uniqueKillmailID"), Unique: newBool(true)}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize killmail repository. Error encountered configuring uniqueKillmailID on collection: %w", err)
+	}
+
+	_, err = killmails.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bsonx.Doc{{Key: "killmail_time", Value: bsonx.Int32(-1)}}, Options: &options.IndexOptions{Name: newString("killmailTimeIdx")}},
+		{Keys: bsonx.Doc{{Key: "killmail_time", Value: bsonx.Int32(1)}, {Key: "killmail_id", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("killmailTimeKillmailIDIdx")}},
+		{Keys: bsonx.Doc{{Key: "solar_system_id", Value: bsonx.Int32(1)}, {Key: "killmail_time", Value: bsonx.Int32(-1)}}, Options: &options.IndexOptions{Name: newString("solarSystemIDIdx")}},
+		{Keys: bsonx.Doc{{Key: "victim.character_id", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("victimCharacterIDIdx")}},
+		{Keys: bsonx.Doc{{Key: "victim.corporation_id", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("victimCorporationIDIdx")}},
+		{Keys: bsonx.Doc{{Key: "victim.alliance_id", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("victimAllianceIDIdx")}},
+		{Keys: bsonx.Doc{{Key: "attackers.character_id", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("attackersCharacterIDIdx")}},
+		{Keys: bsonx.Doc{{Key: "attackers.corporation_id", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("attackersCorporationIDIdx")}},
+		{Keys: bsonx.Doc{{Key: "attackers.alliance_id", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("attackersAllianceIDIdx")}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize killmail repository. Error encountered configuring lookup indexes on collection: %w", err)
+	}
+
+	err = ensureTTLIndex(context.Background(), killmails, "created_at", "killmailRetentionIdx", retention)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize killmail repository. Error encountered configuring killmailRetentionIdx on collection: %w", err)
+	}
+
+	return &killmailRepository{
+		killmails: killmails,
+	}, nil
+
+}
+
+func (r *killmailRepository) Killmail(ctx context.Context, id uint) (*zrule.Killmail, error) {
+
+	killmail := new(zrule.Killmail)
+	err := r.killmails.FindOne(ctx, primitive.D{primitive.E{Key: "killmail_id", Value: id}}).Decode(killmail)
+	return killmail, err
+
+}
+
+func (r *killmailRepository) Killmails(ctx context.Context, operators ...*zrule.Operator) ([]*zrule.Killmail, error) {
+
+	filters := BuildFilters(operators...)
+	options := BuildFindOptions(operators...)
+
+	var killmails = make([]*zrule.Killmail, 0)
+	result, err := r.killmails.Find(ctx, filters, options)
+	if err != nil {
+		return killmails, err
+	}
+
+	err = result.All(ctx, &killmails)
+	return killmails, err
+
+}
+
+func (r *killmailRepository) CreateKillmail(ctx context.Context, killmail *zrule.Killmail) error {
+
+	killmail.CreatedAt = time.Now()
+
+	_, err := r.killmails.InsertOne(ctx, killmail)
+	if err != nil && !IsUniqueConstrainViolation(err) {
+		return err
+	}
+
+	return nil
+
+}
@@ -0,0 +1,88 @@
+package mdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+type matchRepository struct {
+	matches *mongo.Collection
+}
+
+func NewMatchRepository(d *mongo.Database, retention time.Duration) (zrule.MatchRepository, error) {
+
+	matches := d.Collection("matches")
+	_, err := matches.Indexes().CreateOne(context.Background(), mongo.IndexModel{Keys: bsonx.Doc{{Key: "policy_id", Value: bsonx.Int32(1)}, {Key: "created_at", Value: bsonx.Int32(-1)}}, Options: &options.IndexOptions{Name: newString("policyIDCreatedAtIdx")}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize match repository. Error encountered configuring policyIDCreatedAtIdx on collection: %w", err)
+	}
+
+	err = ensureTTLIndex(context.Background(), matches, "created_at", "matchRetentionIdx", retention)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize match repository. Error encountered configuring matchRetentionIdx on collection: %w", err)
+	}
+
+	return &matchRepository{
+		matches: matches,
+	}, nil
+
+}
+
+func (r *matchRepository) Match(ctx context.Context, id primitive.ObjectID) (*zrule.Match, error) {
+
+	match := new(zrule.Match)
+	err := r.matches.FindOne(ctx, primitive.D{primitive.E{Key: "_id", Value: id}}).Decode(match)
+	return match, err
+
+}
+
+func (r *matchRepository) Matches(ctx context.Context, operators ...*zrule.Operator) ([]*zrule.Match, error) {
+
+	filters := BuildFilters(operators...)
+	options := BuildFindOptions(operators...)
+
+	var matches = make([]*zrule.Match, 0)
+	result, err := r.matches.Find(ctx, filters, options)
+	if err != nil {
+		return matches, err
+	}
+
+	err = result.All(ctx, &matches)
+	return matches, err
+
+}
+
+func (r *matchRepository) CreateMatch(ctx context.Context, match *zrule.Match) (*zrule.Match, error) {
+
+	match.CreatedAt = time.Now()
+	if match.Deliveries == nil {
+		match.Deliveries = make([]*zrule.MatchDelivery, 0)
+	}
+
+	result, err := r.matches.InsertOne(ctx, match)
+	if err != nil {
+		return nil, err
+	}
+
+	match.ID = result.InsertedID.(primitive.ObjectID)
+
+	return match, nil
+
+}
+
+func (r *matchRepository) CreateMatchDelivery(ctx context.Context, id primitive.ObjectID, delivery *zrule.MatchDelivery) error {
+
+	update := primitive.D{primitive.E{Key: "$push", Value: primitive.D{primitive.E{Key: "deliveries", Value: delivery}}}}
+
+	_, err := r.matches.UpdateOne(ctx, primitive.D{primitive.E{Key: "_id", Value: id}}, update)
+
+	return err
+
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
 )
 
 func Connect(ctx context.Context, uri *url.URL) (*mongo.Client, error) {
@@ -124,8 +126,12 @@ func BuildFilters(operators ...*zrule.Operator) primitive.D {
 
 }
 
+// BuildFindOptions builds the limit, skip, and sort of a find from the operators. Order operators are sorted by in
+// the order that they are provided, so that a later order breaks the ties of an earlier one. An order operator
+// for a column that is already sorted by replaces the earlier order of that column
 func BuildFindOptions(ops ...*zrule.Operator) *options.FindOptions {
 	var opts = options.Find()
+	var sort = make(primitive.D, 0)
 	for _, a := range ops {
 		switch a.Operation {
 		case zrule.LimitOp:
@@ -133,14 +139,104 @@ func BuildFindOptions(ops ...*zrule.Operator) *options.FindOptions {
 		case zrule.SkipOp:
 			opts.SetSkip(a.Value.(int64))
 		case zrule.OrderOp:
-			opts.SetSort(primitive.D{primitive.E{Key: a.Column, Value: a.Value}})
+			sort = appendSort(sort, primitive.E{Key: a.Column, Value: a.Value})
 		}
 	}
 
+	if len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+
 	return opts
 }
 
+func appendSort(sort primitive.D, order primitive.E) primitive.D {
+	for i, e := range sort {
+		if e.Key == order.Key {
+			sort[i] = order
+			return sort
+		}
+	}
+	return append(sort, order)
+}
+
 const duplicateKeyError = 11000
+const indexNotFoundError = 27
+const namespaceNotFoundError = 26
+
+// ensureTTLIndex creates a TTL index on the provided key of the collection. If the index
+// already exists with a different expiry, the expiry is updated in place with collMod so that
+// retention can be changed between deployments without manually dropping the index
+func ensureTTLIndex(ctx context.Context, collection *mongo.Collection, key, name string, ttl time.Duration) error {
+
+	if ttl <= 0 {
+		return fmt.Errorf("invalid ttl %s provided for index %s, ttl must be greater than zero", ttl, name)
+	}
+
+	seconds := int32(ttl.Seconds())
+
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var indexes = make([]primitive.M, 0)
+	err = cursor.All(ctx, &indexes)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		if index["name"] != name {
+			continue
+		}
+
+		var current int64
+		switch v := index["expireAfterSeconds"].(type) {
+		case int32:
+			current = int64(v)
+		case int64:
+			current = v
+		case float64:
+			current = int64(v)
+		}
+
+		if current == int64(seconds) {
+			return nil
+		}
+
+		return collection.Database().RunCommand(ctx, primitive.D{
+			primitive.E{Key: "collMod", Value: collection.Name()},
+			primitive.E{Key: "index", Value: primitive.D{
+				primitive.E{Key: "name", Value: name},
+				primitive.E{Key: "expireAfterSeconds", Value: seconds},
+			}},
+		}).Err()
+	}
+
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bsonx.Doc{{Key: key, Value: bsonx.Int32(1)}},
+		Options: options.Index().SetName(name).SetExpireAfterSeconds(seconds),
+	})
+
+	return err
+
+}
+
+// dropIndex drops the index with the provided name, if it exists. It is used to
+// remove indexes that have been replaced so that they stop being enforced
+func dropIndex(ctx context.Context, collection *mongo.Collection, name string) error {
+
+	_, err := collection.Indexes().DropOne(ctx, name)
+
+	var ce mongo.CommandError
+	if errors.As(err, &ce) && (ce.Code == indexNotFoundError || ce.Code == namespaceNotFoundError) {
+		return nil
+	}
+
+	return err
+
+}
 
 func IsUniqueConstrainViolation(exception error) bool {
 
@@ -79,6 +79,20 @@ func (r *policyRepository) UpdatePolicy(ctx context.Context, id primitive.Object
 	return policy, err
 }
 
+func (r *policyRepository) PausePolicy(ctx context.Context, id primitive.ObjectID) error {
+
+	update := primitive.D{
+		primitive.E{Key: "$set", Value: primitive.D{
+			primitive.E{Key: "paused", Value: true},
+			primitive.E{Key: "updated_at", Value: time.Now()},
+		}},
+	}
+
+	_, err := r.policies.UpdateOne(ctx, primitive.D{primitive.E{Key: "_id", Value: id}}, update)
+
+	return err
+}
+
 func (r *policyRepository) DeletePolicy(ctx context.Context, id primitive.ObjectID) error {
 
 	_, err := r.policies.DeleteOne(ctx, primitive.D{primitive.E{Key: "_id", Value: id}})
@@ -0,0 +1,89 @@
+package mdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+type pushSubscriptionRepository struct {
+	subscriptions *mongo.Collection
+}
+
+func NewPushSubscriptionRepository(d *mongo.Database) (zrule.PushSubscriptionRepository, error) {
+
+	subscriptions := d.Collection("push_subscriptions")
+	_, err := subscriptions.Indexes().CreateOne(context.Background(), mongo.IndexModel{Keys: bsonx.Doc{{Key: "endpoint", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("endpointUniqueIdx"), Unique: newBool(true)}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize push subscription repository. Error encountered configuring endpointUniqueIdx on collection: %w", err)
+	}
+
+	_, err = subscriptions.Indexes().CreateOne(context.Background(), mongo.IndexModel{Keys: bsonx.Doc{{Key: "user_id", Value: bsonx.Int32(1)}}, Options: &options.IndexOptions{Name: newString("userIDIdx")}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize push subscription repository. Error encountered configuring userIDIdx on collection: %w", err)
+	}
+
+	return &pushSubscriptionRepository{
+		subscriptions: subscriptions,
+	}, nil
+
+}
+
+func (r *pushSubscriptionRepository) PushSubscriptions(ctx context.Context, operators ...*zrule.Operator) ([]*zrule.PushSubscription, error) {
+
+	filters := BuildFilters(operators...)
+	options := BuildFindOptions(operators...)
+
+	var subscriptions = make([]*zrule.PushSubscription, 0)
+	result, err := r.subscriptions.Find(ctx, filters, options)
+	if err != nil {
+		return subscriptions, err
+	}
+
+	err = result.All(ctx, &subscriptions)
+	return subscriptions, err
+
+}
+
+// SavePushSubscription stores a subscription by its endpoint. A browser that subscribes again, possibly
+// after a different user logged into it, replaces the subscription that it had before
+func (r *pushSubscriptionRepository) SavePushSubscription(ctx context.Context, subscription *zrule.PushSubscription) (*zrule.PushSubscription, error) {
+
+	now := time.Now()
+	subscription.UpdatedAt = now
+
+	update := primitive.D{
+		primitive.E{Key: "$set", Value: primitive.D{
+			primitive.E{Key: "user_id", Value: subscription.UserID},
+			primitive.E{Key: "keys", Value: subscription.Keys},
+			primitive.E{Key: "user_agent", Value: subscription.UserAgent},
+			primitive.E{Key: "updated_at", Value: now},
+		}},
+		primitive.E{Key: "$setOnInsert", Value: primitive.D{
+			primitive.E{Key: "created_at", Value: now},
+		}},
+	}
+
+	after := options.After
+	err := r.subscriptions.FindOneAndUpdate(
+		ctx,
+		primitive.D{primitive.E{Key: "endpoint", Value: subscription.Endpoint}},
+		update,
+		&options.FindOneAndUpdateOptions{Upsert: newBool(true), ReturnDocument: &after},
+	).Decode(subscription)
+
+	return subscription, err
+
+}
+
+func (r *pushSubscriptionRepository) DeletePushSubscription(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.subscriptions.DeleteOne(ctx, primitive.D{primitive.E{Key: "_id", Value: id}})
+
+	return err
+}
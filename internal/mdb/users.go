@@ -36,6 +36,13 @@ func (r *userRepository) User(ctx context.Context, id uint64) (*zrule.User, erro
 	return user, err
 }
 
+func (r *userRepository) UserByID(ctx context.Context, id primitive.ObjectID) (*zrule.User, error) {
+	user := new(zrule.User)
+	err := r.users.FindOne(ctx, primitive.D{primitive.E{Key: "_id", Value: id}}).Decode(user)
+
+	return user, err
+}
+
 func (r *userRepository) CreateUser(ctx context.Context, user *zrule.User) (*zrule.User, error) {
 
 	user.CreatedAt = time.Now()
@@ -61,11 +68,27 @@ func (r *userRepository) UpdateUser(ctx context.Context, id uint64, user *zrule.
 
 	update := primitive.D{primitive.E{Key: "$set", Value: user}}
 
-	_, err := r.users.UpdateOne(ctx, primitive.D{primitive.E{Key: "characterID", Value: id}}, update)
+	_, err := r.users.UpdateOne(ctx, primitive.D{primitive.E{Key: "character_id", Value: id}}, update)
 
 	return user, err
 }
 
+func (r *userRepository) UpdateUserToken(ctx context.Context, id uint64, accessToken, refreshToken string, expires time.Time) error {
+
+	update := primitive.D{
+		primitive.E{Key: "$set", Value: primitive.D{
+			primitive.E{Key: "access_token", Value: accessToken},
+			primitive.E{Key: "refresh_token", Value: refreshToken},
+			primitive.E{Key: "expires", Value: expires},
+			primitive.E{Key: "updated_at", Value: time.Now()},
+		}},
+	}
+
+	_, err := r.users.UpdateOne(ctx, primitive.D{primitive.E{Key: "character_id", Value: id}}, update)
+
+	return err
+}
+
 func (r *userRepository) DeleteUser(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.users.DeleteOne(ctx, primitive.D{primitive.E{Key: "_id", Value: id}})
 
@@ -0,0 +1,136 @@
+package netguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// internalNetworks are the addresses that the network zrule runs in, or the host itself, can be reached on,
+// which the endpoints of actions are kept away from so that users cannot read its services through their actions
+var internalNetworks = parseNetworks(
+	"0.0.0.0/8",      // this network
+	"10.0.0.0/8",     // private
+	"100.64.0.0/10",  // carrier grade nat
+	"127.0.0.0/8",    // loopback
+	"169.254.0.0/16", // link local, including cloud metadata services
+	"172.16.0.0/12",  // private
+	"192.0.0.0/24",   // ietf protocol assignments
+	"192.168.0.0/16", // private
+	"198.18.0.0/15",  // benchmarking
+	"224.0.0.0/4",    // multicast
+	"240.0.0.0/4",    // reserved and broadcast
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"fc00::/7",       // unique local
+	"fe80::/10",      // link local
+	"ff00::/8",       // multicast
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(fmt.Sprintf("invalid internal network %s: %s", cidr, err))
+		}
+		networks = append(networks, network)
+	}
+
+	return networks
+
+}
+
+// IsInternal reports whether ip is a loopback, private, link local, or otherwise non public address
+func IsInternal(ip net.IP) bool {
+
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// CheckEndpoint verifies that the host of an endpoint is not internal. Hosts are resolved, and a host that fails
+// to resolve is let through, since the addresses that a host resolves to are checked again when it is dialed.
+// Endpoints without a host, such as mailto addresses, are not dialed and are let through as well
+func CheckEndpoint(ctx context.Context, endpoint string) error {
+
+	uri, err := url.Parse(endpoint)
+	if err != nil || uri.Hostname() == "" {
+		return nil
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(uri.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("endpoint %s is an internal address", host)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if IsInternal(ip) {
+			return fmt.Errorf("endpoint %s is an internal address", host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+
+	for _, addr := range addrs {
+		if IsInternal(addr.IP) {
+			return fmt.Errorf("endpoint %s resolves to an internal address", host)
+		}
+	}
+
+	return nil
+
+}
+
+// Control refuses to connect to an internal address. It is called with the address that a host resolved to
+// right before it is connected to, so a host that resolves to an internal address after it was checked is refused
+func Control(network, address string, _ syscall.RawConn) error {
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse address %s: %w", address, err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || IsInternal(ip) {
+		return fmt.Errorf("refusing to connect to internal address %s", host)
+	}
+
+	return nil
+
+}
+
+// Transport returns a copy of the default transport that refuses to connect to internal addresses
+func Transport() *http.Transport {
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   Control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+
+	return transport
+
+}
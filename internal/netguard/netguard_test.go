@@ -0,0 +1,78 @@
+package netguard
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsInternal(t *testing.T) {
+
+	cases := map[string]bool{
+		"127.0.0.1":        true,
+		"10.1.2.3":         true,
+		"172.20.0.1":       true,
+		"192.168.1.10":     true,
+		"169.254.169.254":  true,
+		"100.64.0.1":       true,
+		"0.0.0.0":          true,
+		"::1":              true,
+		"fd00::1":          true,
+		"fe80::1":          true,
+		"::ffff:127.0.0.1": true,
+		"8.8.8.8":          false,
+		"162.159.135.232":  false,
+		"2606:4700::1111":  false,
+	}
+
+	for address, internal := range cases {
+		if IsInternal(net.ParseIP(address)) != internal {
+			t.Errorf("expected IsInternal(%s) to be %t", address, internal)
+		}
+	}
+
+}
+
+func TestCheckEndpoint(t *testing.T) {
+
+	cases := map[string]bool{
+		"http://127.0.0.1:8080/hook":                 true,
+		"http://[::1]/hook":                          true,
+		"http://169.254.169.254/latest/meta-data/":   true,
+		"http://localhost:9200/":                     true,
+		"http://api.localhost/":                      true,
+		"https://162.159.135.232/api/webhooks/1/abc": false,
+		"mailto:fc@example.com":                      false,
+	}
+
+	for endpoint, internal := range cases {
+		err := CheckEndpoint(context.Background(), endpoint)
+		if (err != nil) != internal {
+			t.Errorf("expected CheckEndpoint(%s) to refuse it to be %t, got %v", endpoint, internal, err)
+		}
+	}
+
+}
+
+func TestTransportRefusesInternalAddresses(t *testing.T) {
+
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: Transport()}
+	res, err := client.Get(server.URL)
+	if err == nil {
+		res.Body.Close()
+		t.Fatal("expected a request to a loopback address to be refused")
+	}
+
+	if called {
+		t.Error("expected nothing to reach the loopback server")
+	}
+
+}
@@ -0,0 +1,126 @@
+package ntfy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// priorities maps a priority onto the scale of ntfy, from 1 (min) to 5 (max)
+// https://docs.ntfy.sh/publish/#message-priority
+var priorities = map[zrule.Priority]int{
+	zrule.PriorityMin:     1,
+	zrule.PriorityLow:     2,
+	zrule.PriorityDefault: 3,
+	zrule.PriorityHigh:    4,
+	zrule.PriorityUrgent:  5,
+}
+
+type service struct {
+	client *http.Client
+	server string
+	topic  string
+	token  string
+}
+
+func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformNtfy {
+		return nil, fmt.Errorf("invalid platform for ntfy service constructor")
+	}
+
+	uri, err := url.Parse(action.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ntfy topic url: %w", err)
+	}
+
+	return &service{
+		client: client,
+		server: fmt.Sprintf("%s://%s", uri.Scheme, uri.Host),
+		topic:  strings.Trim(uri.Path, "/"),
+		token:  action.Token,
+	}, nil
+
+}
+
+// message is a message published as JSON to the root of an ntfy server
+// https://docs.ntfy.sh/publish/#publish-as-json
+type message struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title,omitempty"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Click    string   `json:"click,omitempty"`
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send ntfy message")
+	defer seg.End()
+
+	return s.publish(ctx, &message{
+		Topic:    s.topic,
+		Title:    notification.Title(),
+		Message:  notification.Summary(),
+		Priority: priorities[notification.Priority()],
+		Tags:     []string{"skull"},
+		Click:    notification.Killmail.ZKillboardURL(),
+	})
+
+}
+
+func (s *service) SendTest(ctx context.Context, msg string) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send ntfy test message")
+	defer seg.End()
+
+	return s.publish(ctx, &message{
+		Topic:   s.topic,
+		Message: msg,
+	})
+
+}
+
+func (s *service) publish(ctx context.Context, msg *message) error {
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request body to publish to ntfy: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.server, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to prepare request to ntfy: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.token))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request to ntfy: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		data, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decode error response: %w", err)
+		}
+
+		return fmt.Errorf("invalid response code %d received from ntfy: %s", res.StatusCode, string(data))
+	}
+
+	return nil
+
+}
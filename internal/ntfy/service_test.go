@@ -0,0 +1,47 @@
+package ntfy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestSendPublishesToTopic(t *testing.T) {
+
+	var received *message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			t.Errorf("expected message to be published to the root of the server, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer tk_abc" {
+			t.Errorf("unexpected authorization header %s", r.Header.Get("Authorization"))
+		}
+
+		received = new(message)
+		_ = json.NewDecoder(r.Body).Decode(received)
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformNtfy, Endpoint: server.URL + "/zrule-alerts", Token: "tk_abc"}
+	dispatcher, err := NewService(action, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.Send(context.Background(), &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "Capitals", Priority: zrule.PriorityUrgent},
+		Killmail: &zrule.Killmail{ID: 88486806},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received == nil || received.Topic != "zrule-alerts" || received.Priority != 5 || received.Click != "https://zkillboard.com/kill/88486806/" {
+		t.Errorf("unexpected message received: %+v", received)
+	}
+
+}
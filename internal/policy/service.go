@@ -3,10 +3,12 @@ package policy
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/eveisesi/zrule"
 	"github.com/eveisesi/zrule/internal/universe"
 	"github.com/newrelic/go-agent/v3/newrelic"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -27,6 +29,19 @@ func NewService(universe universe.Service, policy zrule.PolicyRepository) Servic
 	}
 }
 
+func (s *service) Policy(ctx context.Context, id primitive.ObjectID) (*zrule.Policy, error) {
+
+	policy, err := s.PolicyRepository.Policy(ctx, id)
+	if err != nil {
+		return policy, err
+	}
+
+	setActivation(policy, time.Now())
+
+	return policy, nil
+
+}
+
 func (s *service) Policies(ctx context.Context, operators ...*zrule.Operator) ([]*zrule.Policy, error) {
 
 	policies, err := s.PolicyRepository.Policies(ctx, operators...)
@@ -34,98 +49,115 @@ func (s *service) Policies(ctx context.Context, operators ...*zrule.Operator) ([
 		return policies, err
 	}
 
+	now := time.Now()
 	for _, policy := range policies {
-		for _, rule := range policy.Rules {
-			for _, and := range rule {
-				for _, pathObj := range zrule.AllPaths {
-					if and.Path.String() == pathObj.Path.String() {
-						if !pathObj.Searchable {
-							break
-						}
-						and.Entities = make([]*zrule.SearchResult, len(and.Values))
-						for i, v := range and.Values {
-							switch t := v.(type) {
-							case float64:
-								switch pathObj.Category {
-								case zrule.PathCategorySystems:
-									system, err := s.universe.SolarSystem(ctx, uint(t))
-									if err != nil {
-										newrelic.FromContext(ctx).NoticeError(err)
-										continue
-									}
-
-									and.Entities[i] = &zrule.SearchResult{
-										ID:   uint64(system.ID),
-										Name: system.Name,
-									}
-								case zrule.PathCategoryConstellations:
-									constellation, err := s.universe.Constellation(ctx, uint(t))
-									if err != nil {
-										newrelic.FromContext(ctx).NoticeError(err)
-										continue
-									}
-
-									and.Entities[i] = &zrule.SearchResult{
-										ID:   uint64(constellation.ID),
-										Name: constellation.Name,
-									}
-								case zrule.PathCategoryRegions:
-									region, err := s.universe.Region(ctx, uint(t))
-									if err != nil {
-										newrelic.FromContext(ctx).NoticeError(err)
-										continue
-									}
-
-									and.Entities[i] = &zrule.SearchResult{
-										ID:   uint64(region.ID),
-										Name: region.Name,
-									}
-
-								case zrule.PathCategoryCorporation:
-									corporation, err := s.universe.Corporation(ctx, uint(t))
-									if err != nil {
-										newrelic.FromContext(ctx).NoticeError(err)
-										continue
-									}
-
-									and.Entities[i] = &zrule.SearchResult{
-										ID:   uint64(corporation.ID),
-										Name: corporation.Name,
-									}
-								case zrule.PathCategoryAlliance:
-									alliance, err := s.universe.Alliance(ctx, uint(t))
-									if err != nil {
-										newrelic.FromContext(ctx).NoticeError(err)
-										continue
-									}
-
-									and.Entities[i] = &zrule.SearchResult{
-										ID:   uint64(alliance.ID),
-										Name: alliance.Name,
-									}
-
-								case zrule.PathCategoryCharacter:
-									character, err := s.universe.Character(ctx, uint64(t))
-									if err != nil {
-										newrelic.FromContext(ctx).NoticeError(err)
-										continue
-									}
-
-									and.Entities[i] = &zrule.SearchResult{
-										ID:   uint64(character.ID),
-										Name: character.Name,
-									}
-								case zrule.PathCategoryItems:
-									item, err := s.universe.Item(ctx, uint(t))
-									if err != nil {
-										newrelic.FromContext(ctx).NoticeError(err)
-										continue
-									}
-
-									and.Entities[i] = &zrule.SearchResult{
-										ID:   uint64(item.ID),
-										Name: item.Name,
-									}
+		setActivation(policy, now)
+	}
+
+	for _, policy := range policies {
+		s.hydrateRules(ctx, policy.Rules)
+		for _, link := range policy.Links {
+			s.hydrateRules(ctx, link.Rules)
+		}
+	}
+
+	return policies, nil
+
+}
+
+// hydrateRules looks up the names of the entities that searchable rules compare against, so that they can be displayed
+func (s *service) hydrateRules(ctx context.Context, rules [][]*zrule.Rule) {
+
+	for _, rule := range rules {
+		for _, and := range rule {
+			for _, pathObj := range zrule.AllPaths {
+				if and.Path.String() == pathObj.Path.String() {
+					if !pathObj.Searchable {
+						break
+					}
+					and.Entities = make([]*zrule.SearchResult, len(and.Values))
+					for i, v := range and.Values {
+						switch t := v.(type) {
+						case float64:
+							switch pathObj.Category {
+							case zrule.PathCategorySystems:
+								system, err := s.universe.SolarSystem(ctx, uint(t))
+								if err != nil {
+									newrelic.FromContext(ctx).NoticeError(err)
+									continue
+								}
+
+								and.Entities[i] = &zrule.SearchResult{
+									ID:   uint64(system.ID),
+									Name: system.Name,
+								}
+							case zrule.PathCategoryConstellations:
+								constellation, err := s.universe.Constellation(ctx, uint(t))
+								if err != nil {
+									newrelic.FromContext(ctx).NoticeError(err)
+									continue
+								}
+
+								and.Entities[i] = &zrule.SearchResult{
+									ID:   uint64(constellation.ID),
+									Name: constellation.Name,
+								}
+							case zrule.PathCategoryRegions:
+								region, err := s.universe.Region(ctx, uint(t))
+								if err != nil {
+									newrelic.FromContext(ctx).NoticeError(err)
+									continue
+								}
+
+								and.Entities[i] = &zrule.SearchResult{
+									ID:   uint64(region.ID),
+									Name: region.Name,
+								}
+
+							case zrule.PathCategoryCorporation:
+								corporation, err := s.universe.Corporation(ctx, uint(t))
+								if err != nil {
+									newrelic.FromContext(ctx).NoticeError(err)
+									continue
+								}
+
+								and.Entities[i] = &zrule.SearchResult{
+									ID:   uint64(corporation.ID),
+									Name: corporation.Name,
+								}
+							case zrule.PathCategoryAlliance:
+								alliance, err := s.universe.Alliance(ctx, uint(t))
+								if err != nil {
+									newrelic.FromContext(ctx).NoticeError(err)
+									continue
+								}
+
+								and.Entities[i] = &zrule.SearchResult{
+									ID:   uint64(alliance.ID),
+									Name: alliance.Name,
+								}
+
+							case zrule.PathCategoryCharacter:
+								character, err := s.universe.Character(ctx, uint64(t))
+								if err != nil {
+									newrelic.FromContext(ctx).NoticeError(err)
+									continue
+								}
+
+								and.Entities[i] = &zrule.SearchResult{
+									ID:   uint64(character.ID),
+									Name: character.Name,
+								}
+							case zrule.PathCategoryItems:
+								item, err := s.universe.Item(ctx, uint(t))
+								if err != nil {
+									newrelic.FromContext(ctx).NoticeError(err)
+									continue
+								}
+
+								and.Entities[i] = &zrule.SearchResult{
+									ID:   uint64(item.ID),
+									Name: item.Name,
 								}
 							}
 						}
@@ -135,6 +167,10 @@ func (s *service) Policies(ctx context.Context, operators ...*zrule.Operator) ([
 		}
 	}
 
-	return policies, nil
+}
 
+// setActivation populates the computed schedule fields of the policy relative to now
+func setActivation(policy *zrule.Policy, now time.Time) {
+	policy.Active = policy.IsActive(now)
+	policy.NextActivationAt = policy.NextActivation(now)
 }
@@ -6,7 +6,8 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/eveisesi/zrule/internal/universe"
+	"github.com/eveisesi/zrule/internal/killmail"
+	"github.com/eveisesi/zrule/internal/match"
 
 	"github.com/eveisesi/zrule/pkg/ruler"
 
@@ -39,7 +40,8 @@ type service struct {
 	newrelic *newrelic.Application
 	trackers *policyTracker
 
-	universe universe.Service
+	killmail killmail.Service
+	match    match.Service
 	policy   policy.Service
 }
 
@@ -48,8 +50,9 @@ func NewService(
 	logger *logrus.Logger,
 	newrelic *newrelic.Application,
 
+	killmail killmail.Service,
+	match match.Service,
 	policy policy.Service,
-	universe universe.Service,
 
 ) Service {
 
@@ -57,8 +60,9 @@ func NewService(
 		redis:    redis,
 		logger:   logger,
 		newrelic: newrelic,
+		killmail: killmail,
+		match:    match,
 		policy:   policy,
-		universe: universe,
 	}
 
 	err := s.initializeTracker(context.Background())
@@ -78,23 +82,23 @@ func (s *service) initializeTracker(ctx context.Context) error {
 		return err
 	}
 
-	trackers := make([]tracker, len(policies))
+	trackers := make([]tracker, 0, len(policies))
 
-	for i, policy := range policies {
+	for _, policy := range policies {
 		if len(policy.Rules) == 0 {
 			continue
 		}
 
-		data, err := json.Marshal(policy.Rules)
+		ruler, err := policy.Ruler()
 		if err != nil {
-			panic(fmt.Errorf("failed to marsahl policy rules: %w", err))
+			s.logger.WithError(err).WithField("policyID", policy.ID.Hex()).Error("failed to build ruler for policy, skipping")
+			continue
 		}
 
-		ruler := ruler.NewRuler()
-		ruler.SetRulesWithJSON(data)
-
-		trackers[i].policy = policy
-		trackers[i].ruler = ruler
+		trackers = append(trackers, tracker{
+			policy: policy,
+			ruler:  ruler,
+		})
 
 	}
 
@@ -242,9 +246,28 @@ func (s *service) handleMessage(ctx context.Context, message []byte) {
 
 	// Hydrate the Killmail with Constellation and Region ID based on the SolarSystem
 
-	s.hydrateKillmail(ctx, killmail)
+	s.killmail.Hydrate(ctx, killmail)
+
+	// Archive the hydrated killmail so that it is available after it leaves the processing queue.
+	// A failure here is not fatal, the killmail can still be matched against the trackers
+	err = s.killmail.CreateKillmail(ctx, killmail)
+	if err != nil {
+		txn.NoticeError(err)
+		s.logger.WithError(err).WithField("killmailID", killmail.ID).Error("failed to archive killmail")
+	}
 
+	now := time.Now()
 	for _, tracker := range s.trackers.trackers {
+		if tracker.policy.IsExpired(now) && !tracker.policy.Paused {
+			s.expirePolicy(ctx, tracker.policy)
+		}
+
+		// Schedules and expiries are evaluated against every killmail so that
+		// a policy starts and stops matching without waiting for the trackers to be rebuilt
+		if !tracker.policy.IsActive(now) {
+			continue
+		}
+
 		seg := txn.StartSegment("run trackers")
 		seg.AddAttribute("trackerID", tracker.policy.ID.Hex())
 
@@ -264,6 +287,22 @@ func (s *service) handleMessage(ctx context.Context, message []byte) {
 			Hash:     killmail.Hash,
 		}
 
+		// Record the match so that it shows up in the policies match history. The killmail is
+		// still dispatched if the match cannot be recorded, it just won't have any history
+		match, err := s.match.CreateMatch(ctx, &zrule.Match{
+			PolicyID:     tracker.policy.ID,
+			OwnerID:      tracker.policy.OwnerID,
+			KillmailID:   killmail.ID,
+			KillmailHash: killmail.Hash,
+			KillmailTime: killmail.KillmailTime,
+		})
+		if err != nil {
+			txn.NoticeError(err)
+			entry.WithError(err).Error("failed to record match")
+		} else {
+			payload.MatchID = match.ID
+		}
+
 		data, err := json.Marshal(payload)
 		if err != nil {
 			txn.NoticeError(err)
@@ -282,68 +321,21 @@ func (s *service) handleMessage(ctx context.Context, message []byte) {
 
 }
 
-func (s *service) hydrateKillmail(ctx context.Context, killmail *zrule.Killmail) {
-
-	entry := s.logger.WithField("killmail_id", killmail.ID)
-	if killmail.Meta != nil {
-		killmail.Hash = killmail.Meta.Hash
-		entry = entry.WithField("killmail_hash", killmail.Hash)
-	}
+// expirePolicy pauses a policy that has passed its expiry so that it stays paused in the datastore and is
+// excluded the next time the trackers are built. The tracker holds a copy of the policy from when the trackers
+// were built, so only the paused flag is written, which keeps any edits that were made to the policy since
+func (s *service) expirePolicy(ctx context.Context, policy *zrule.Policy) {
 
-	system, err := s.universe.SolarSystem(ctx, killmail.SolarSystemID)
-	if err != nil {
-		newrelic.FromContext(ctx).NoticeError(err)
-		entry.WithError(err).WithField("SolarSystemID", killmail.SolarSystemID).Debug("failed to look up solar system for solar system")
-		return
-	}
+	entry := s.logger.WithField("policyID", policy.ID.Hex()).WithField("policyName", policy.Name)
 
-	constellation, err := s.universe.Constellation(ctx, system.ConstellationID)
+	policy.Paused = true
+	err := s.policy.PausePolicy(ctx, policy.ID)
 	if err != nil {
 		newrelic.FromContext(ctx).NoticeError(err)
-		entry.WithError(err).WithField("SolarSystemID", killmail.SolarSystemID).WithField("ConstellationID", system.ConstellationID).Debug("failed to look up constellation for solar system")
+		entry.WithError(err).Error("failed to pause expired policy")
 		return
 	}
 
-	killmail.ConstellationID = constellation.ID
-	killmail.RegionID = constellation.RegionID
-
-	if killmail.Victim != nil {
-		victimShip, err := s.universe.Item(ctx, killmail.Victim.ShipTypeID)
-		if err != nil {
-			newrelic.FromContext(ctx).NoticeError(err)
-			entry.WithError(err).WithField("Victim.ShipTypeID", killmail.Victim.ShipTypeID).Debug("failed to lookup victim ship")
-		}
-		killmail.Victim.ShipGroupID = victimShip.GroupID
-	}
-
-	if len(killmail.Attackers) > 0 {
-		for i, attacker := range killmail.Attackers {
-
-			if attacker.ShipTypeID != nil {
-				attackerShip, err := s.universe.Item(ctx, *attacker.ShipTypeID)
-				if err != nil {
-					newrelic.FromContext(ctx).NoticeError(err)
-					entry.WithError(err).WithField("attackerID", i).Debug("failed to lookup attacker ship")
-					continue
-				}
-
-				attacker.ShipGroupID = &attackerShip.GroupID
-
-			}
-
-			if attacker.WeaponTypeID != nil {
-				attackerWeapon, err := s.universe.Item(ctx, *attacker.WeaponTypeID)
-				if err != nil {
-					newrelic.FromContext(ctx).NoticeError(err)
-					entry.WithError(err).WithField("attackerID", i).Debug("failed to lookup attacker ship")
-					continue
-				}
-
-				attacker.WeaponGroupID = &attackerWeapon.GroupID
-
-			}
-
-		}
-	}
+	entry.Info("policy has expired and has been paused")
 
 }
@@ -0,0 +1,141 @@
+package pushover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// Limits imposed by Pushover on messages
+// https://pushover.net/api#limits
+const (
+	maxTitleLength   = 250
+	maxMessageLength = 1024
+
+	// Emergency priority messages are repeated every emergencyRetry seconds until they are
+	// acknowledged, or emergencyExpire seconds have passed
+	emergencyRetry  = 60
+	emergencyExpire = 1800
+)
+
+// priorities maps a priority onto the scale of Pushover, from -2 to 2
+// https://pushover.net/api#priority
+var priorities = map[zrule.Priority]int{
+	zrule.PriorityMin:     -2,
+	zrule.PriorityLow:     -1,
+	zrule.PriorityDefault: 0,
+	zrule.PriorityHigh:    1,
+	zrule.PriorityUrgent:  2,
+}
+
+type service struct {
+	client   *http.Client
+	endpoint string
+	token    string
+	user     string
+}
+
+func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformPushover {
+		return nil, fmt.Errorf("invalid platform for pushover service constructor")
+	}
+
+	if action.Token == "" || action.Recipient == "" {
+		return nil, fmt.Errorf("pushover actions require an application token and user key")
+	}
+
+	return &service{
+		client:   client,
+		endpoint: strings.TrimSuffix(action.Endpoint, "/"),
+		token:    action.Token,
+		user:     action.Recipient,
+	}, nil
+
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send pushover message")
+	defer seg.End()
+
+	// Emergencies repeat until they are acknowledged, so they are only sent for policies that ask for them
+	priority := priorities[notification.Priority()]
+	emergency := notification.Policy != nil && notification.Policy.Priority == zrule.PriorityUrgent
+	if !emergency && priority > priorities[zrule.PriorityHigh] {
+		priority = priorities[zrule.PriorityHigh]
+	}
+
+	form := url.Values{
+		"title":     {zrule.Truncate(notification.Title(), maxTitleLength)},
+		"message":   {zrule.Truncate(notification.Summary(), maxMessageLength)},
+		"priority":  {strconv.Itoa(priority)},
+		"url":       {notification.Killmail.ZKillboardURL()},
+		"url_title": {"View on zKillboard"},
+	}
+	if !notification.Killmail.KillmailTime.IsZero() {
+		form.Set("timestamp", strconv.FormatInt(notification.Killmail.KillmailTime.Unix(), 10))
+	}
+	if emergency {
+		form.Set("retry", strconv.Itoa(emergencyRetry))
+		form.Set("expire", strconv.Itoa(emergencyExpire))
+	}
+
+	return s.post(ctx, form)
+
+}
+
+func (s *service) SendTest(ctx context.Context, message string) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send pushover test message")
+	defer seg.End()
+
+	return s.post(ctx, url.Values{
+		"message": {zrule.Truncate(message, maxMessageLength)},
+	})
+
+}
+
+func (s *service) post(ctx context.Context, form url.Values) error {
+
+	form.Set("token", s.token)
+	form.Set("user", s.user)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/1/messages.json", s.endpoint), strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to prepare request to pushover: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request to pushover: %w", err)
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response from pushover: %w", err)
+	}
+
+	var result struct {
+		Status int      `json:"status"`
+		Errors []string `json:"errors"`
+	}
+	_ = json.Unmarshal(data, &result)
+
+	if res.StatusCode != http.StatusOK || result.Status != 1 {
+		return fmt.Errorf("invalid response code %d received from pushover: %s", res.StatusCode, strings.Join(result.Errors, ", "))
+	}
+
+	return nil
+
+}
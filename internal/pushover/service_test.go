@@ -0,0 +1,77 @@
+package pushover
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eveisesi/zrule"
+)
+
+const (
+	token = "azGDORePK8gMaC0QOYAMyEEuzJnyUi"
+	user  = "uQiRzpo4DXghDmr9QzzfQu27cmVRsG"
+)
+
+func TestSendPostsEmergencyMessage(t *testing.T) {
+
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1/messages.json" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		_ = r.ParseForm()
+		form = r.PostForm
+
+		_, _ = w.Write([]byte(`{"status":1,"request":"647d2300-702c-4b38-8b2f-d56326ae460b"}`))
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformPushover, Endpoint: server.URL, Token: token, Recipient: user}
+	if err := action.IsValid(); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+
+	dispatcher, err := NewService(action, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.Send(context.Background(), &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "Titans", Priority: zrule.PriorityUrgent},
+		Killmail: &zrule.Killmail{ID: 88486806},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for key, expected := range map[string]string{"token": token, "user": user, "priority": "2", "retry": "60", "url": "https://zkillboard.com/kill/88486806/"} {
+		if values := form[key]; len(values) != 1 || values[0] != expected {
+			t.Errorf("expected %s to be %s, got %v", key, expected, values)
+		}
+	}
+
+}
+
+func TestSendReturnsPushoverErrors(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"user":"invalid","errors":["user identifier is invalid"],"status":0}`))
+	}))
+	defer server.Close()
+
+	dispatcher, err := NewService(&zrule.Action{Platform: zrule.PlatformPushover, Endpoint: server.URL, Token: token, Recipient: user}, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.SendTest(context.Background(), "test")
+	if err == nil || !strings.Contains(err.Error(), "user identifier is invalid") {
+		t.Errorf("expected the error from pushover, got %v", err)
+	}
+
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/go-redis/redis/v8"
+)
+
+// Limiter is a token bucket per key that is stored in Redis so that every dispatcher
+// instance shares the same view of how much of an endpoints rate limit has been used.
+// Besides the steady refill of the bucket, a key can be blocked until a point in time
+// when the remote end tells us that it is rate limiting us
+type Limiter struct {
+	redis *redis.Client
+	rate  float64
+	burst int
+}
+
+// NewLimiter returns a Limiter whose buckets refill at rate tokens per second and hold at most burst tokens
+func NewLimiter(redis *redis.Client, rate float64, burst int) *Limiter {
+	return &Limiter{
+		redis: redis,
+		rate:  rate,
+		burst: burst,
+	}
+}
+
+// takeScript refills the bucket for the time that has passed since it was last touched and takes
+// a token from it. It returns the number of milliseconds to wait before a token is available, or 0
+// when a token was taken
+var takeScript = redis.NewScript(`
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", key, "tokens", "ts", "blocked")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+local blocked = tonumber(state[3]) or 0
+
+if blocked > now then
+	return blocked - now
+end
+
+tokens = math.min(burst, tokens + ((now - ts) / 1000) * rate)
+
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil(((1 - tokens) / rate) * 1000)
+end
+
+redis.call("HSET", key, "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", key, math.max(60000, math.ceil((burst / rate) * 1000)))
+
+return wait
+`)
+
+// blockScript blocks the bucket until the provided time, unless it is already blocked for longer
+var blockScript = redis.NewScript(`
+local key = KEYS[1]
+local blockUntil = tonumber(ARGV[1])
+local now = tonumber(ARGV[2])
+
+local blocked = tonumber(redis.call("HGET", key, "blocked")) or 0
+if blockUntil > blocked then
+	redis.call("HSET", key, "blocked", blockUntil)
+end
+
+local ttl = redis.call("PTTL", key)
+if ttl < (blockUntil - now) then
+	redis.call("PEXPIRE", key, blockUntil - now)
+end
+
+return 0
+`)
+
+// Take takes a token from the bucket for key. When no token is available,
+// the duration to wait before trying again is returned
+func (l *Limiter) Take(ctx context.Context, key string) (time.Duration, error) {
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	wait, err := takeScript.Run(ctx, l.redis, []string{fmt.Sprintf(zrule.CACHE_RATELIMIT_BUCKET, key)}, l.rate, l.burst, now).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to take token from bucket: %w", err)
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+
+}
+
+// Block prevents tokens from being taken from the bucket for key until the provided time
+func (l *Limiter) Block(ctx context.Context, key string, until time.Time) error {
+
+	now := time.Now()
+	if !until.After(now) {
+		return nil
+	}
+
+	_, err := blockScript.Run(
+		ctx, l.redis, []string{fmt.Sprintf(zrule.CACHE_RATELIMIT_BUCKET, key)},
+		until.UnixNano()/int64(time.Millisecond), now.UnixNano()/int64(time.Millisecond),
+	).Result()
+	if err != nil {
+		return fmt.Errorf("failed to block bucket: %w", err)
+	}
+
+	return nil
+
+}
@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+// maxRateLimitBody is the number of bytes of a 429 response that are read looking for how long to wait
+const maxRateLimitBody = 4096
+
+// defaultRetryAfter is used when the remote end responds with a 429 without telling us how long to wait
+const defaultRetryAfter = time.Second * 5
+
+// Transport is an http.RoundTripper that takes a token from the Limiter before every request and returns a
+// *zrule.RateLimitError instead of making the request when no token is available. The Retry-After and
+// X-RateLimit-* headers that Discord and Slack respond with are used to block the bucket until the
+// remote end is ready for the next request, and a 429 is returned as a *zrule.RateLimitError
+type Transport struct {
+	limiter *Limiter
+	next    http.RoundTripper
+}
+
+func NewTransport(limiter *Limiter, next http.RoundTripper) *Transport {
+	return &Transport{
+		limiter: limiter,
+		next:    next,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	ctx := req.Context()
+	key := bucketKey(req)
+
+	wait, err := t.limiter.Take(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return nil, &zrule.RateLimitError{RetryAfter: wait}
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+
+	now := time.Now()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxRateLimitBody))
+		_ = res.Body.Close()
+
+		retryAfter := parseRetryAfter(res.Header)
+		if retryAfter <= 0 {
+			retryAfter = parseBodyRetryAfter(body)
+		}
+		if retryAfter <= 0 {
+			retryAfter = defaultRetryAfter
+		}
+
+		_ = t.limiter.Block(ctx, key, now.Add(retryAfter))
+
+		return nil, &zrule.RateLimitError{RetryAfter: retryAfter}
+	}
+
+	// Discord tells us how many requests are left in the current window. When it has been
+	// used up, hold every other request to this endpoint until the window resets
+	if remaining := res.Header.Get("X-RateLimit-Remaining"); remaining == "0" {
+		if resetAfter := parseSeconds(res.Header.Get("X-RateLimit-Reset-After")); resetAfter > 0 {
+			_ = t.limiter.Block(ctx, key, now.Add(resetAfter))
+		}
+	}
+
+	return res, nil
+
+}
+
+type bucketContextKey struct{}
+
+// WithBucket returns a copy of ctx whose requests share the bucket of key rather than the bucket of their URL.
+// Platforms whose URLs are unique per message, such as those that carry a transaction id, use it so that
+// their requests are limited per destination
+func WithBucket(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, bucketContextKey{}, key)
+}
+
+// bucketKey identifies the endpoint that a request is for. Webhook URLs carry their credentials
+// in the path, so the URL is hashed rather than being used in the Redis key as is
+func bucketKey(req *http.Request) string {
+
+	key := req.URL.Host + req.URL.Path
+	if bucket, ok := req.Context().Value(bucketContextKey{}).(string); ok && bucket != "" {
+		key = bucket
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+
+}
+
+// parseRetryAfter reads the Retry-After header, which is either a number of (possibly fractional)
+// seconds or an HTTP date. Discord also reports the same value in X-RateLimit-Reset-After
+func parseRetryAfter(header http.Header) time.Duration {
+
+	if d := parseSeconds(header.Get("Retry-After")); d > 0 {
+		return d
+	}
+
+	if t, err := http.ParseTime(header.Get("Retry-After")); err == nil {
+		return time.Until(t)
+	}
+
+	return parseSeconds(header.Get("X-RateLimit-Reset-After"))
+
+}
+
+// parseBodyRetryAfter reads how long to wait from the body of a 429 for APIs that do not send a
+// Retry-After header. Telegram reports it in parameters.retry_after, Matrix in retry_after_ms, others at the top level
+func parseBodyRetryAfter(body []byte) time.Duration {
+
+	var payload struct {
+		RetryAfter   float64 `json:"retry_after"`
+		RetryAfterMS float64 `json:"retry_after_ms"`
+		Parameters   struct {
+			RetryAfter float64 `json:"retry_after"`
+		} `json:"parameters"`
+	}
+
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return 0
+	}
+
+	seconds := payload.Parameters.RetryAfter
+	if seconds <= 0 {
+		seconds = payload.RetryAfter
+	}
+	if seconds <= 0 {
+		seconds = payload.RetryAfterMS / 1000
+	}
+
+	return parseSeconds(strconv.FormatFloat(seconds, 'f', -1, 64))
+
+}
+
+func parseSeconds(value string) time.Duration {
+
+	if value == "" {
+		return 0
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+		return 0
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+
+}
@@ -0,0 +1,177 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+// maxTemplateLength is the longest template that can be saved on an action or policy
+const maxTemplateLength = 4000
+
+// Data is the data model that templates are executed against. It is documented in the README,
+// so fields must not be removed or renamed
+type Data struct {
+	Policy        *zrule.Policy
+	Killmail      *zrule.Killmail
+	Details       *zrule.KillmailDetails
+	ZKillboardURL string
+	PolicyURL     string
+	// Policies are every policy that matched the killmail, which is only Policy unless the matches of several policies were coalesced
+	Policies []*zrule.Policy
+}
+
+// NewData builds the data model of a notification
+func NewData(notification *zrule.Notification) *Data {
+
+	policies := notification.Policies
+	if len(policies) == 0 {
+		policies = []*zrule.Policy{notification.Policy}
+	}
+
+	return &Data{
+		Policy:        notification.Policy,
+		Killmail:      notification.Killmail,
+		Details:       notification.Details,
+		ZKillboardURL: notification.Killmail.ZKillboardURL(),
+		PolicyURL:     notification.PolicyURL,
+		Policies:      policies,
+	}
+
+}
+
+// Funcs are the helper functions that are available to templates
+var Funcs = template.FuncMap{
+	"isk":     zrule.FormatISK,
+	"timeAgo": timeAgo,
+	"zkill":   zkill,
+	"evewho":  evewho,
+}
+
+// Parse parses a template, verifying that it is not too long and only uses known functions
+func Parse(text string) (*template.Template, error) {
+
+	if len(text) > maxTemplateLength {
+		return nil, fmt.Errorf("template must be at most %d characters", maxTemplateLength)
+	}
+
+	tmpl, err := template.New("message").Funcs(Funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	return tmpl, nil
+
+}
+
+// Render executes the template against the notification and returns the resulting message
+func Render(text string, notification *zrule.Notification) (string, error) {
+
+	tmpl, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, NewData(notification))
+	if err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return strings.TrimSpace(b.String()), nil
+
+}
+
+// timeAgo describes how long ago t was in the largest whole unit, ie. 3 hours ago
+func timeAgo(t time.Time) string {
+
+	d := time.Since(t)
+	if d < time.Minute {
+		return "just now"
+	}
+
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{time.Hour * 24, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	}
+
+	for _, unit := range units {
+		if d >= unit.size {
+			n := int(d / unit.size)
+			if n == 1 {
+				return fmt.Sprintf("1 %s ago", unit.name)
+			}
+			return fmt.Sprintf("%d %ss ago", n, unit.name)
+		}
+	}
+
+	return "just now"
+
+}
+
+// zkill returns the zkillboard page of a kill, character, corporation, alliance, ship, or system
+func zkill(kind string, id interface{}) (string, error) {
+
+	value, err := identifier(id)
+	if err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case "kill", "character", "corporation", "alliance", "system":
+		return fmt.Sprintf("https://zkillboard.com/%s/%d/", kind, value), nil
+	case "ship":
+		return fmt.Sprintf("https://zkillboard.com/ship/%d/", value), nil
+	default:
+		return "", fmt.Errorf("unsupported zkill link %s, expected one of kill, character, corporation, alliance, ship, or system", kind)
+	}
+
+}
+
+// evewho returns the evewho page of a character, corporation, or alliance
+func evewho(kind string, id interface{}) (string, error) {
+
+	value, err := identifier(id)
+	if err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case "character", "corporation", "alliance":
+		return fmt.Sprintf("https://evewho.com/%s/%d", kind, value), nil
+	default:
+		return "", fmt.Errorf("unsupported evewho link %s, expected one of character, corporation, or alliance", kind)
+	}
+
+}
+
+// identifier unwraps the ids of the killmail, which are a mix of integer types and pointers to them
+func identifier(id interface{}) (uint64, error) {
+
+	v := reflect.ValueOf(id)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, fmt.Errorf("id is nil")
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	default:
+		return 0, fmt.Errorf("unsupported id type %T", id)
+	}
+
+}
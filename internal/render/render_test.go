@@ -0,0 +1,43 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestRender(t *testing.T) {
+
+	characterID := uint64(1466390582)
+	notification := &zrule.Notification{
+		Policy: &zrule.Policy{Name: "Capitals"},
+		Killmail: &zrule.Killmail{
+			ID:           88486806,
+			KillmailTime: time.Now().Add(-time.Hour * 3),
+			Victim:       &zrule.KillmailVictim{CharacterID: &characterID},
+		},
+		Details: &zrule.KillmailDetails{
+			Victim:     &zrule.KillmailParticipant{Character: &zrule.Character{Name: "Victim"}},
+			TotalValue: 2807175.66,
+		},
+	}
+
+	message, err := Render(
+		`{{ .Details.Victim.Name }} lost {{ isk .Details.TotalValue }} {{ timeAgo .Killmail.KillmailTime }} {{ evewho "character" .Killmail.Victim.CharacterID }} ({{ .Policy.Name }})`,
+		notification,
+	)
+	if err != nil {
+		t.Fatalf("failed to render template: %s", err)
+	}
+
+	expected := "Victim lost 2.81m ISK 3 hours ago https://evewho.com/character/1466390582 (Capitals)"
+	if message != expected {
+		t.Errorf("expected %q, got %q", expected, message)
+	}
+
+	if _, err := Render(`{{ .Killmail.Missing }}`, notification); err == nil {
+		t.Error("expected an error rendering a field that does not exist")
+	}
+
+}
@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PayloadVersion is the version of the payload that is posted to REST actions. It is incremented
+// whenever a field is removed or changes meaning, so that receivers can reject payloads they do not understand
+const PayloadVersion = 1
+
+const (
+	EventKillmailMatched = "killmail.matched"
+	EventTest            = "test"
+)
+
+// Payload is the body of every request that is made to a REST action. The format is documented in the README
+type Payload struct {
+	Version int            `json:"version"`
+	Event   string         `json:"event"`
+	SentAt  time.Time      `json:"sent_at"`
+	Policy  *PayloadPolicy `json:"policy,omitempty"`
+	// Policies are every policy that matched the killmail, when the matches of several policies were coalesced
+	Policies []*PayloadPolicy `json:"policies,omitempty"`
+	Killmail *PayloadKillmail `json:"killmail,omitempty"`
+	Message  string           `json:"message,omitempty"`
+}
+
+type PayloadPolicy struct {
+	ID   primitive.ObjectID `json:"id"`
+	Name string             `json:"name"`
+	// Rules summarizes the rules that the killmail matched. The policy matches when every
+	// rule of any one group matches, so each entry is a group of rules joined by AND
+	Rules []string `json:"rules"`
+	URL   string   `json:"url,omitempty"`
+}
+
+type PayloadKillmail struct {
+	ID            uint                   `json:"id"`
+	Hash          string                 `json:"hash"`
+	ZKillboardURL string                 `json:"zkillboard_url"`
+	Killmail      *zrule.Killmail        `json:"killmail,omitempty"`
+	Details       *zrule.KillmailDetails `json:"details,omitempty"`
+}
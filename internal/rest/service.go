@@ -3,50 +3,181 @@ package rest
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+const (
+	HeaderSignature = "X-ZRule-Signature"
+	HeaderTimestamp = "X-ZRule-Timestamp"
+	HeaderEvent     = "X-ZRule-Event"
 )
 
 type service struct {
-	client *http.Client
-	action *zrule.Action
+	client  *http.Client
+	action  *zrule.Action
+	timeout time.Duration
 }
 
-func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
+func NewService(action *zrule.Action, client *http.Client, timeout time.Duration) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformRest {
+		return nil, fmt.Errorf("invalid platform for rest service constructor")
+	}
+
 	return &service{
-		client: client,
-		action: action,
+		client:  client,
+		action:  action,
+		timeout: timeout,
 	}, nil
 }
 
-func (s *service) Send(ctx context.Context, policy *zrule.Policy, id uint, hash string) error {
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
 
-	fmt.Println("Rest", id)
+	seg := newrelic.FromContext(ctx).StartSegment("send rest message")
+	defer seg.End()
+
+	policy, killmail := notification.Policy, notification.Killmail
+
+	payload := &Payload{
+		Version: PayloadVersion,
+		Event:   EventKillmailMatched,
+		SentAt:  time.Now().UTC(),
+		Policy: &PayloadPolicy{
+			ID:    policy.ID,
+			Name:  policy.Name,
+			Rules: summarizeRules(policy.Rules),
+			URL:   notification.PolicyURL,
+		},
+		Killmail: &PayloadKillmail{
+			ID:            killmail.ID,
+			Hash:          killmail.Hash,
+			ZKillboardURL: killmail.ZKillboardURL(),
+			Details:       notification.Details,
+		},
+		Message: notification.Message,
+	}
+
+	for _, coalesced := range notification.Policies {
+		payload.Policies = append(payload.Policies, &PayloadPolicy{
+			ID:    coalesced.ID,
+			Name:  coalesced.Name,
+			Rules: summarizeRules(coalesced.Rules),
+		})
+	}
+
+	// Notifications for killmails that are missing from the archive only carry the id and hash
+	if !killmail.KillmailTime.IsZero() {
+		payload.Killmail.Killmail = killmail
+	}
+
+	return s.post(ctx, payload)
 
-	return nil
 }
 
 func (s *service) SendTest(ctx context.Context, message string) error {
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.action.Endpoint, bytes.NewBuffer([]byte(fmt.Sprintf(`{"message": %s}`, message))))
+	seg := newrelic.FromContext(ctx).StartSegment("send rest test message")
+	defer seg.End()
+
+	return s.post(ctx, &Payload{
+		Version: PayloadVersion,
+		Event:   EventTest,
+		SentAt:  time.Now().UTC(),
+		Message: message,
+	})
+
+}
+
+// post signs the payload with the actions secret and posts it to the actions endpoint. Any 2xx is a success
+func (s *service) post(ctx context.Context, payload *Payload) error {
+
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	// Anyone can forge a signature made with an empty key, so nothing is sent until the action has a secret
+	if s.action.Secret == "" {
+		return fmt.Errorf("rest action does not have a secret to sign requests with")
+	}
+
+	data, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("Failed to build request for test message: %w", err)
+		return fmt.Errorf("failed to prepare request body: %w", err)
 	}
 
-	res, err := s.client.Do(req)
+	timestamp := strconv.FormatInt(payload.SentAt.Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.action.Endpoint, bytes.NewReader(data))
 	if err != nil {
-		return fmt.Errorf("failed to execute request for test message: %w", err)
+		return fmt.Errorf("failed to build request: %w", err)
 	}
 
-	if res.StatusCode > http.StatusBadRequest {
-		return fmt.Errorf("Endpoint returned an invalid status code %d", res.StatusCode)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "zrule")
+	req.Header.Set(HeaderEvent, payload.Event)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(s.action.Secret, timestamp, data))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
 	}
+	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("Endpoint did not return the required status code of 204 No Content")
+	// The body is left out of the error, which is shown to the owner of the action when a test message fails.
+	// Test messages are made with the client of the dispatcher, which refuses to connect to internal addresses
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 512))
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("endpoint returned an invalid status code %d", res.StatusCode)
 	}
 
 	return nil
+
+}
+
+// Sign returns the value of the signature header for a request. The signature is the hex encoded
+// HMAC-SHA256 of the timestamp and the body joined by a period, keyed with the actions secret
+func Sign(secret, timestamp string, body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp))
+	_, _ = mac.Write([]byte("."))
+	_, _ = mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+}
+
+// summarizeRules describes each group of rules of a policy in a single line, ie. Victim.AllianceID eq [1 2] AND SolarSystemID eq [3]
+func summarizeRules(groups [][]*zrule.Rule) []string {
+
+	summary := make([]string, 0, len(groups))
+	for _, group := range groups {
+		rules := make([]string, 0, len(group))
+		for _, rule := range group {
+			if rule == nil {
+				continue
+			}
+			rules = append(rules, fmt.Sprintf("%s %s %v", rule.Path, rule.Comparator, rule.Values))
+		}
+		summary = append(summary, strings.Join(rules, " AND "))
+	}
+
+	return summary
+
 }
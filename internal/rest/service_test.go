@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSendSignsPayload(t *testing.T) {
+
+	const secret = "super-secret"
+
+	var received *Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		expected := Sign(secret, r.Header.Get(HeaderTimestamp), body)
+		if r.Header.Get(HeaderSignature) != expected {
+			t.Errorf("expected signature %s, got %s", expected, r.Header.Get(HeaderSignature))
+		}
+
+		received = new(Payload)
+		_ = json.Unmarshal(body, received)
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformRest, Endpoint: server.URL, Secret: secret}
+	dispatcher, err := NewService(action, server.Client(), time.Second)
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.Send(context.Background(), &zrule.Notification{
+		Policy:   &zrule.Policy{ID: primitive.NewObjectID(), Name: "Test"},
+		Killmail: &zrule.Killmail{ID: 88486806, Hash: "3c9ed419c00f123ff8d46475b05b70616d1381d8"},
+	})
+	if err != nil {
+		t.Fatalf("expected a 202 to be treated as success, got %s", err)
+	}
+
+	if received == nil || received.Version != PayloadVersion || received.Event != EventKillmailMatched || received.Killmail.ID != 88486806 {
+		t.Errorf("unexpected payload received: %+v", received)
+	}
+
+}
+
+func TestSendRejectsNon2xx(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMovedPermanently)
+		_, _ = w.Write([]byte("internal secrets"))
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformRest, Endpoint: server.URL, Secret: "s3cr3t"}
+	client := server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	dispatcher, _ := NewService(action, client, time.Second)
+	err := dispatcher.SendTest(context.Background(), "Is this thing on?!?!")
+	if err == nil {
+		t.Fatal("expected a 301 to be treated as a failure")
+	}
+
+	if strings.Contains(err.Error(), "internal secrets") {
+		t.Errorf("expected the response body to be left out of the error, got %q", err)
+	}
+
+}
+
+func TestSendRefusesWithoutSecret(t *testing.T) {
+
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformRest, Endpoint: server.URL}
+	dispatcher, _ := NewService(action, server.Client(), time.Second)
+	if err := dispatcher.SendTest(context.Background(), "Is this thing on?!?!"); err == nil {
+		t.Error("expected an action without a secret to be refused")
+	}
+
+	if called {
+		t.Error("expected nothing to be sent to an action without a secret")
+	}
+
+}
+
+func TestSummarizeRules(t *testing.T) {
+
+	groups := [][]*zrule.Rule{
+		{
+			{Path: zrule.PathVictimShipGroupID.Path, Comparator: "eq", Values: []interface{}{547, 485}},
+			{Path: zrule.PathRegionID.Path, Comparator: "eq", Values: []interface{}{10000060}},
+		},
+	}
+
+	summary := summarizeRules(groups)
+	expected := "Victim.ShipGroupID eq [547 485] AND RegionID eq [10000060]"
+	if len(summary) != 1 || summary[0] != expected {
+		t.Errorf("expected summary [%q], got %q", expected, summary)
+	}
+
+}
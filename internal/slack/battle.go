@@ -0,0 +1,70 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eveisesi/zrule"
+)
+
+// maxBattleSides is the number of sides that are listed on a battle report, the rest are summed up on a single line
+const maxBattleSides = 10
+
+// battleMessage builds the body of the message that reports a battle, with the ISK that each side has lost and the latest kill of the battle
+func battleMessage(battle *zrule.Battle, notification *zrule.Notification) map[string]interface{} {
+
+	title := battle.Title()
+	if notification.Details != nil && notification.Details.SolarSystem != nil {
+		title = fmt.Sprintf("%s (%s)", title, notification.Details.SecurityStatus())
+	}
+
+	summary := fmt.Sprintf("%d kills, %s destroyed", len(battle.Kills), zrule.FormatISK(battle.TotalValue()))
+
+	var b strings.Builder
+	b.WriteString("*Losses*")
+	sides := battle.Sides()
+	for i, side := range sides {
+		if i == maxBattleSides {
+			var losses int
+			var value float64
+			for _, rest := range sides[i:] {
+				losses += rest.Losses
+				value += rest.Value
+			}
+			fmt.Fprintf(&b, "\n_…and %d more, %d losses, %s_", len(sides)-i, losses, zrule.FormatISK(value))
+			break
+		}
+		fmt.Fprintf(&b, "\n• %s: %d losses, %s", escape(side.Name), side.Losses, zrule.FormatISK(side.Value))
+	}
+
+	blocks := []block{
+		{"type": "header", "text": plainText(zrule.Truncate(title, maxHeaderLength))},
+		{"type": "section", "text": mrkdwn(summary)},
+		{"type": "section", "text": mrkdwn(zrule.Truncate(b.String(), maxSectionLength))},
+	}
+
+	if details := notification.Details; details != nil && details.Victim != nil {
+		latest := fmt.Sprintf(
+			"*Latest Kill*\n<%s|%s lost a %s worth %s>",
+			notification.Killmail.ZKillboardURL(), escape(details.Victim.Label()), escape(details.Victim.ShipName()), zrule.FormatISK(details.TotalValue),
+		)
+		blocks = append(blocks, block{"type": "section", "text": mrkdwn(zrule.Truncate(latest, maxSectionLength))})
+	}
+
+	blocks = append(blocks,
+		block{"type": "actions", "elements": []interface{}{button("View on zKillboard", battle.ZKillboardURL())}},
+		block{
+			"type": "context",
+			"elements": []interface{}{mrkdwn(escape(zrule.Truncate(
+				fmt.Sprintf("Matched %s %s, started %s EVE", notification.PolicyNoun(), notification.PolicyNames(), battle.StartedAt.UTC().Format("15:04")),
+				maxFieldLength,
+			)))},
+		},
+	)
+
+	return map[string]interface{}{
+		"text":   fmt.Sprintf("%s: %s", title, summary),
+		"blocks": blocks,
+	}
+
+}
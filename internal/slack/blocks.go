@@ -0,0 +1,172 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eveisesi/zrule"
+)
+
+// Limits imposed by Slack on Block Kit messages
+// https://api.slack.com/reference/block-kit/blocks
+const (
+	maxHeaderLength  = 150
+	maxSectionLength = 3000
+	maxFieldLength   = 2000
+	maxAttackerLines = 20
+)
+
+type block map[string]interface{}
+
+func mrkdwn(text string) block {
+	return block{"type": "mrkdwn", "text": text}
+}
+
+func plainText(text string) block {
+	return block{"type": "plain_text", "text": text, "emoji": true}
+}
+
+func button(text, url string) block {
+	return block{"type": "button", "text": plainText(text), "url": url}
+}
+
+// escape escapes the characters that Slack uses for links and mentions in mrkdwn, which
+// otherwise mangle alliance tickers like <ALLY>
+func escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// mentions renders the mentions of a notification in the syntax that Slack notifies them with, ie. <!subteam^SAZ94GDB8>
+func mentions(notification *zrule.Notification) string {
+
+	text := make([]string, 0, len(notification.Mentions))
+	for _, mention := range notification.Mentions {
+		switch mention.Type {
+		case zrule.MentionEveryone:
+			text = append(text, "<!channel>")
+		case zrule.MentionHere:
+			text = append(text, "<!here>")
+		case zrule.MentionUser:
+			text = append(text, fmt.Sprintf("<@%s>", mention.ID))
+		case zrule.MentionUserGroup:
+			text = append(text, fmt.Sprintf("<!subteam^%s>", mention.ID))
+		}
+	}
+
+	return strings.Join(text, " ")
+
+}
+
+// blocks builds the Block Kit layout for a notification along with the plain text
+// that Slack displays in notifications and clients that do not support blocks
+func blocks(notification *zrule.Notification, layout zrule.Layout) (string, []block) {
+
+	killmail, details, policy := notification.Killmail, notification.Details, notification.Policy
+
+	buttons := []interface{}{button("View on zKillboard", killmail.ZKillboardURL())}
+	if notification.PolicyURL != "" {
+		buttons = append(buttons, button("View Policy", notification.PolicyURL))
+	}
+	actions := block{"type": "actions", "elements": buttons}
+
+	footer := block{
+		"type":     "context",
+		"elements": []interface{}{mrkdwn(escape(zrule.Truncate(fmt.Sprintf("Matched %s %s", notification.PolicyNoun(), notification.PolicyNames()), maxFieldLength)))},
+	}
+
+	if details == nil || details.Victim == nil {
+		text := fmt.Sprintf("Match Found with Policy %s (%s)\n%s", policy.Name, policy.ID.Hex(), killmail.ZKillboardURL())
+		return text, []block{
+			{"type": "section", "text": mrkdwn(escape(zrule.Truncate(text, maxSectionLength)))},
+			actions,
+		}
+	}
+
+	victim := details.Victim
+	system := details.Location()
+
+	title := fmt.Sprintf("%s destroyed in %s", victim.ShipName(), system)
+
+	if layout == zrule.LayoutCompact {
+		summary := fmt.Sprintf(
+			"%s lost a %s in %s worth %s to %d attacker(s)",
+			victim.Label(), victim.ShipName(), system, zrule.FormatISK(details.TotalValue), details.AttackerCount,
+		)
+		return title, []block{
+			{"type": "section", "text": mrkdwn(escape(zrule.Truncate(summary, maxSectionLength)))},
+			actions,
+			footer,
+		}
+	}
+
+	victimSection := block{
+		"type": "section",
+		"text": mrkdwn(zrule.Truncate(fmt.Sprintf("*Victim*\n%s\n%s", escape(victim.Label()), escape(victim.ShipName())), maxSectionLength)),
+	}
+	if victim.Ship != nil {
+		victimSection["accessory"] = block{
+			"type":      "image",
+			"image_url": zrule.ShipRenderURL(victim.Ship.ID, 128),
+			"alt_text":  victim.ShipName(),
+		}
+	}
+
+	location := system
+	if details.Region != nil {
+		location = fmt.Sprintf("%s / %s", system, details.Region.Name)
+	}
+
+	fields := []interface{}{
+		mrkdwn(zrule.Truncate(fmt.Sprintf("*Location*\n%s", escape(location)), maxFieldLength)),
+		mrkdwn(fmt.Sprintf("*Value*\n%s", zrule.FormatISK(details.TotalValue))),
+		mrkdwn(fmt.Sprintf("*Attackers*\n%d", details.AttackerCount)),
+	}
+	if details.FinalBlow != nil {
+		fields = append(fields, mrkdwn(zrule.Truncate(
+			fmt.Sprintf("*Final Blow*\n%s\n%s", escape(details.FinalBlow.Label()), escape(details.FinalBlow.ShipName())),
+			maxFieldLength,
+		)))
+	}
+
+	return title, []block{
+		{"type": "header", "text": plainText(zrule.Truncate(title, maxHeaderLength))},
+		victimSection,
+		{"type": "section", "fields": fields},
+		{"type": "section", "text": mrkdwn(attackers(details))},
+		actions,
+		footer,
+	}
+
+}
+
+// attackers lists the attackers of the killmail, one per line. The list is cut short once it reaches
+// maxAttackerLines or would exceed the length of a section, and the number of attackers left off is noted
+func attackers(details *zrule.KillmailDetails) string {
+
+	var b strings.Builder
+	b.WriteString("*Top Attackers*")
+
+	listed := 0
+	for _, attacker := range details.Attackers {
+		if listed == maxAttackerLines {
+			break
+		}
+
+		line := fmt.Sprintf("\n• %s, %s (%d dmg)", escape(attacker.Label()), escape(attacker.ShipName()), attacker.Damage)
+
+		// Leave room for the line noting how many attackers were left off
+		if b.Len()+len(line) > maxSectionLength-64 {
+			break
+		}
+
+		b.WriteString(line)
+		listed++
+	}
+
+	if remaining := details.AttackerCount - listed; remaining > 0 {
+		fmt.Fprintf(&b, "\n_…and %d more_", remaining)
+	}
+
+	return b.String()
+
+}
@@ -0,0 +1,60 @@
+package slack
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestAttackersTruncation(t *testing.T) {
+
+	details := &zrule.KillmailDetails{AttackerCount: 300}
+	for i := 0; i < 25; i++ {
+		details.Attackers = append(details.Attackers, &zrule.KillmailParticipant{
+			Character:   &zrule.Character{Name: strings.Repeat("A", 200)},
+			Corporation: &zrule.Corporation{Ticker: "CORP"},
+			Alliance:    &zrule.Alliance{Ticker: "ALLY"},
+			Ship:        &zrule.Item{Name: "Rifter"},
+		})
+	}
+
+	text := attackers(details)
+	if len(text) > maxSectionLength {
+		t.Errorf("expected attackers section to be at most %d characters, got %d", maxSectionLength, len(text))
+	}
+
+	listed := strings.Count(text, "\n•")
+	if !strings.HasSuffix(text, "more_") || !strings.Contains(text, "and "+strconv.Itoa(300-listed)+" more") {
+		t.Errorf("expected attackers section to note the %d attackers that were left off, got %q", 300-listed, text)
+	}
+
+	if strings.Contains(text, "<ALLY>") {
+		t.Error("expected alliance tickers to be escaped")
+	}
+
+}
+
+func TestMessageLeadsWithMentions(t *testing.T) {
+
+	notification := &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "Titans"},
+		Killmail: &zrule.Killmail{ID: 88486806},
+		Mentions: []*zrule.Mention{
+			{Type: zrule.MentionHere},
+			{Type: zrule.MentionUserGroup, ID: "SAZ94GDB8"},
+		},
+	}
+
+	body := message(notification, zrule.LayoutFull)
+	if text := body["text"].(string); !strings.HasPrefix(text, "<!here> <!subteam^SAZ94GDB8> ") {
+		t.Errorf("expected text to lead with the mentions, got %q", text)
+	}
+
+	first := body["blocks"].([]block)[0]
+	if first["text"].(block)["text"] != "<!here> <!subteam^SAZ94GDB8>" {
+		t.Errorf("expected the first block to hold the mentions, got %v", first)
+	}
+
+}
@@ -0,0 +1,136 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// botService posts to a channel through the Slack Web API as the bot of a Slack app
+type botService struct {
+	*service
+	api, token, channel string
+}
+
+// apiResponse is the part of a Web API response that we use. Slack responds with a 200 to failed calls, setting ok to false
+type apiResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	TS    string `json:"ts"`
+}
+
+func (s *botService) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.StartSegment(newrelic.FromContext(ctx), "send slack message")
+	defer seg.End()
+
+	_, err := s.call(ctx, "chat.postMessage", message(notification, s.layout))
+
+	return err
+}
+
+func (s *botService) SendTest(ctx context.Context, message string) error {
+
+	seg := newrelic.StartSegment(newrelic.FromContext(ctx), "send slack test message")
+	defer seg.End()
+
+	_, err := s.call(ctx, "chat.postMessage", map[string]interface{}{
+		"text": message,
+	})
+
+	return err
+}
+
+// SendBattleReport posts the report of a battle and returns the timestamp of the message, which is how Slack identifies it
+func (s *botService) SendBattleReport(ctx context.Context, battle *zrule.Battle, notification *zrule.Notification) (string, error) {
+
+	seg := newrelic.StartSegment(newrelic.FromContext(ctx), "send slack battle report")
+	defer seg.End()
+
+	// Only the kill that starts the battle mentions anyone, editing the message would not ping them again
+	body := battleMessage(battle, notification)
+	if mentioned := mentions(notification); mentioned != "" {
+		body["text"] = fmt.Sprintf("%s %s", mentioned, body["text"])
+		body["blocks"] = append([]block{{"type": "section", "text": mrkdwn(mentioned)}}, body["blocks"].([]block)...)
+	}
+
+	res, err := s.call(ctx, "chat.postMessage", body)
+	if err != nil {
+		return "", err
+	}
+
+	return res.TS, nil
+
+}
+
+// UpdateBattleReport edits the message of a battle in place
+func (s *botService) UpdateBattleReport(ctx context.Context, battle *zrule.Battle, notification *zrule.Notification) error {
+
+	seg := newrelic.StartSegment(newrelic.FromContext(ctx), "update slack battle report")
+	defer seg.End()
+
+	body := battleMessage(battle, notification)
+	body["ts"] = battle.MessageID
+
+	res, err := s.call(ctx, "chat.update", body)
+	if err != nil && res != nil && res.Error == "message_not_found" {
+		return zrule.ErrMessageNotFound
+	}
+
+	return err
+
+}
+
+// call calls a method of the Web API with the channel of the action. The response is returned alongside
+// the error when Slack responded, so that the caller can tell why the call failed
+func (s *botService) call(ctx context.Context, method string, body map[string]interface{}) (*apiResponse, error) {
+
+	body["channel"] = s.channel
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare request body to post to slack: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s", s.api, method), bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare request to slack: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.token))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request to slack: %w", err)
+	}
+	defer res.Body.Close()
+
+	data, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from slack: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid response code received from slack: %s", string(data))
+	}
+
+	var response = new(apiResponse)
+	err = json.Unmarshal(data, response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response from slack: %w", err)
+	}
+
+	if !response.OK {
+		return response, fmt.Errorf("slack responded to %s with error %s", method, response.Error)
+	}
+
+	return response, nil
+
+}
@@ -0,0 +1,101 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestBattleReportIsPostedThenUpdated(t *testing.T) {
+
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-123" {
+			t.Errorf("unexpected authorization header %s", r.Header.Get("Authorization"))
+		}
+
+		body := make(map[string]interface{})
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["channel"] != "C0123" {
+			t.Errorf("expected message to be sent to channel C0123, got %v", body["channel"])
+		}
+
+		calls = append(calls, r.URL.Path)
+		switch r.URL.Path {
+		case "/api/chat.postMessage":
+			_, _ = w.Write([]byte(`{"ok":true,"channel":"C0123","ts":"1604428920.000100"}`))
+		case "/api/chat.update":
+			if body["ts"] != "1604428920.000100" {
+				_, _ = w.Write([]byte(`{"ok":false,"error":"message_not_found"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformSlack, Endpoint: server.URL + "/api", Token: "xoxb-123", Recipient: "C0123"}
+	if err := action.IsValid(); err != nil {
+		t.Fatalf("unexpected error validating action: %s", err)
+	}
+
+	dispatcher, err := NewService(action, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	reporter, ok := dispatcher.(zrule.BattleReporter)
+	if !ok {
+		t.Fatal("expected slack bot service to report battles")
+	}
+
+	notification := &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "Jita"},
+		Killmail: &zrule.Killmail{ID: 88486806, SolarSystemID: 30000142},
+	}
+	battle := &zrule.Battle{SolarSystemID: 30000142, SolarSystemName: "Jita"}
+	battle.AddKill(&zrule.BattleKill{KillmailID: 88486806, KillmailTime: time.Now(), Side: "Pandemic Horde", Value: 250e6})
+
+	battle.MessageID, err = reporter.SendBattleReport(context.Background(), battle, notification)
+	if err != nil {
+		t.Fatalf("unexpected error sending battle report: %s", err)
+	}
+	if battle.MessageID != "1604428920.000100" {
+		t.Errorf("expected the timestamp of the message to be returned, got %s", battle.MessageID)
+	}
+
+	err = reporter.UpdateBattleReport(context.Background(), battle, notification)
+	if err != nil {
+		t.Fatalf("unexpected error updating battle report: %s", err)
+	}
+
+	battle.MessageID = "1604428920.000200"
+	err = reporter.UpdateBattleReport(context.Background(), battle, notification)
+	if !errors.Is(err, zrule.ErrMessageNotFound) {
+		t.Errorf("expected a deleted message to be reported as not found, got %v", err)
+	}
+
+	if len(calls) != 3 {
+		t.Errorf("expected 3 calls to the web api, got %v", calls)
+	}
+
+}
+
+func TestWebhookDoesNotReportBattles(t *testing.T) {
+
+	dispatcher, err := NewService(&zrule.Action{Platform: zrule.PlatformSlack, Endpoint: "https://hooks.slack.com/services/T0/B0/abc"}, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	if _, ok := dispatcher.(zrule.BattleReporter); ok {
+		t.Error("expected slack webhook service to not report battles, since webhooks cannot edit their messages")
+	}
+
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"net/url"
 
 	"github.com/eveisesi/zrule"
 	"github.com/newrelic/go-agent/v3/newrelic"
@@ -16,32 +15,61 @@ import (
 type service struct {
 	client  *http.Client
 	webhook string
+	layout  zrule.Layout
 }
 
+// NewService returns the service for a Slack action. Actions with a bot token post through the Web API,
+// which can also edit the battle reports that it sends. Every other action is an incoming webhook
 func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
-	return &service{
+
+	s := &service{
 		client:  client,
 		webhook: action.Endpoint,
-	}, nil
+		layout:  action.Layout,
+	}
+
+	if action.Token != "" {
+		return &botService{
+			service: s,
+			api:     action.Endpoint,
+			token:   action.Token,
+			channel: action.Recipient,
+		}, nil
+	}
+
+	return s, nil
+
 }
 
-func (s *service) Send(ctx context.Context, policy *zrule.Policy, id uint, hash string) error {
+// message builds the body of the message for a notification
+func message(notification *zrule.Notification, layout zrule.Layout) map[string]interface{} {
 
-	seg := newrelic.StartSegment(newrelic.FromContext(ctx), "send slack message")
-	defer seg.End()
+	text, blocks := blocks(notification, layout)
 
-	uri := url.URL{
-		Scheme: "https",
-		Host:   "zkillboard.com",
-		Path:   fmt.Sprintf("/kill/%d", id),
+	// A rendered template leads the message and replaces the plain text that Slack shows in notifications
+	if notification.Message != "" {
+		text = notification.Message
+		blocks = append([]block{{"type": "section", "text": mrkdwn(zrule.Truncate(notification.Message, maxSectionLength))}}, blocks...)
 	}
 
-	content := fmt.Sprintf("Match Found with Policy %s (%s)\n%s", policy.Name, policy.ID.Hex(), uri.String())
+	if mentioned := mentions(notification); mentioned != "" {
+		text = fmt.Sprintf("%s %s", mentioned, text)
+		blocks = append([]block{{"type": "section", "text": mrkdwn(mentioned)}}, blocks...)
+	}
 
-	data, err := json.Marshal(map[string]interface{}{
-		"text":         content,
-		"unfurl_links": true,
-	})
+	return map[string]interface{}{
+		"text":   text,
+		"blocks": blocks,
+	}
+
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.StartSegment(newrelic.FromContext(ctx), "send slack message")
+	defer seg.End()
+
+	data, err := json.Marshal(message(notification, s.layout))
 	if err != nil {
 		return fmt.Errorf("failed to prepare request body to post to slack: %w", err)
 	}
@@ -0,0 +1,34 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type Service interface {
+	zrule.PushSubscriptionRepository
+}
+
+type service struct {
+	zrule.PushSubscriptionRepository
+}
+
+func NewService(subscription zrule.PushSubscriptionRepository) Service {
+	return &service{
+		PushSubscriptionRepository: subscription,
+	}
+}
+
+func (s *service) PushSubscriptions(ctx context.Context, operators ...*zrule.Operator) ([]*zrule.PushSubscription, error) {
+
+	subscriptions, err := s.PushSubscriptionRepository.PushSubscriptions(ctx, operators...)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return subscriptions, err
+	}
+
+	return subscriptions, nil
+
+}
@@ -0,0 +1,148 @@
+package teams
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eveisesi/zrule"
+)
+
+// maxAttackerFacts bounds the number of attackers that are listed on a full card
+const maxAttackerFacts = 10
+
+type element map[string]interface{}
+
+func textBlock(text string, options element) element {
+	e := element{"type": "TextBlock", "text": text, "wrap": true}
+	for k, v := range options {
+		e[k] = v
+	}
+	return e
+}
+
+func fact(title, value string) element {
+	return element{"title": title, "value": value}
+}
+
+func openURL(title, url string) element {
+	return element{"type": "Action.OpenUrl", "title": title, "url": url}
+}
+
+// escape escapes the characters that Adaptive Cards treat as markdown, so that names are shown as written
+func escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`).Replace(text)
+}
+
+// message wraps an Adaptive Card in the message that incoming webhooks accept
+// https://docs.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using#send-adaptive-cards-using-an-incoming-webhook
+func message(body []element, actions []element) element {
+
+	content := element{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+		"msteams": element{"width": "Full"},
+	}
+	if len(actions) > 0 {
+		content["actions"] = actions
+	}
+
+	return element{
+		"type": "message",
+		"attachments": []element{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"contentUrl":  nil,
+				"content":     content,
+			},
+		},
+	}
+
+}
+
+// card builds the Adaptive Card for a notification
+func card(notification *zrule.Notification, layout zrule.Layout) element {
+
+	killmail, details := notification.Killmail, notification.Details
+
+	actions := []element{openURL("View on zKillboard", killmail.ZKillboardURL())}
+	if notification.PolicyURL != "" {
+		actions = append(actions, openURL("View Policy", notification.PolicyURL))
+	}
+
+	body := make([]element, 0, 4)
+
+	// A rendered template leads the card
+	if notification.Message != "" {
+		body = append(body, textBlock(notification.Message, nil))
+	}
+
+	footer := textBlock(fmt.Sprintf("Matched %s %s", notification.PolicyNoun(), escape(notification.PolicyNames())), element{"isSubtle": true, "size": "Small", "spacing": "Medium"})
+
+	if details == nil || details.Victim == nil {
+		if notification.Message == "" {
+			body = append(body, textBlock(fmt.Sprintf("Match Found with %s **%s**", notification.PolicyNoun(), escape(notification.PolicyNames())), nil))
+		}
+		return message(append(body, footer), actions)
+	}
+
+	victim := details.Victim
+	system := details.Location()
+
+	if layout == zrule.LayoutCompact {
+		body = append(body, textBlock(fmt.Sprintf(
+			"%s lost a **%s** in %s worth %s",
+			escape(victim.Label()), escape(victim.ShipName()), escape(system), zrule.FormatISK(details.TotalValue),
+		), nil), footer)
+		return message(body, actions)
+	}
+
+	facts := []element{fact("Victim", escape(victim.Label()))}
+	if details.Region != nil {
+		facts = append(facts, fact("Region", escape(details.Region.Name)))
+	}
+	facts = append(facts,
+		fact("Value", zrule.FormatISK(details.TotalValue)),
+		fact("Attackers", fmt.Sprintf("%d", details.AttackerCount)),
+	)
+	if details.FinalBlow != nil {
+		facts = append(facts, fact("Final Blow", fmt.Sprintf("%s (%s)", escape(details.FinalBlow.Label()), escape(details.FinalBlow.ShipName()))))
+	}
+
+	columns := []element{}
+	if victim.Ship != nil {
+		columns = append(columns, element{
+			"type":  "Column",
+			"width": "auto",
+			"items": []element{{"type": "Image", "url": zrule.ShipRenderURL(victim.Ship.ID, 64), "size": "Medium", "altText": victim.ShipName()}},
+		})
+	}
+	columns = append(columns, element{
+		"type":  "Column",
+		"width": "stretch",
+		"items": []element{
+			textBlock(fmt.Sprintf("%s destroyed in %s", escape(victim.ShipName()), escape(system)), element{"weight": "Bolder", "size": "Medium"}),
+			{"type": "FactSet", "facts": facts},
+		},
+	})
+
+	body = append(body, element{"type": "ColumnSet", "columns": columns})
+
+	if len(details.Attackers) > 0 {
+		attackers := make([]element, 0, maxAttackerFacts)
+		for i, attacker := range details.Attackers {
+			if i == maxAttackerFacts {
+				break
+			}
+			attackers = append(attackers, fact(escape(attacker.Label()), fmt.Sprintf("%s, %d damage", escape(attacker.ShipName()), attacker.Damage)))
+		}
+		body = append(body,
+			textBlock("Top Attackers", element{"weight": "Bolder", "spacing": "Medium"}),
+			element{"type": "FactSet", "facts": attackers},
+		)
+	}
+
+	return message(append(body, footer), actions)
+
+}
@@ -0,0 +1,98 @@
+package teams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// throttledRetryAfter is how long to wait after Teams throttles a message, which it does without saying for how long
+const throttledRetryAfter = time.Second * 30
+
+type service struct {
+	client  *http.Client
+	webhook string
+	layout  zrule.Layout
+}
+
+func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformTeams {
+		return nil, fmt.Errorf("invalid platform for teams service constructor")
+	}
+
+	return &service{
+		client:  client,
+		webhook: action.Endpoint,
+		layout:  action.Layout,
+	}, nil
+
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send teams message")
+	defer seg.End()
+
+	return s.post(ctx, card(notification, s.layout))
+
+}
+
+func (s *service) SendTest(ctx context.Context, message string) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send teams test message")
+	defer seg.End()
+
+	return s.post(ctx, testCard(message))
+
+}
+
+func testCard(text string) element {
+	return message([]element{textBlock(text, nil)}, nil)
+}
+
+func (s *service) post(ctx context.Context, payload element) error {
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request body to post to teams: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhook, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to prepare request to teams: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request to teams: %w", err)
+	}
+	defer res.Body.Close()
+
+	data, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response from teams: %w", err)
+	}
+
+	// Incoming webhooks respond with 200, Power Automate workflows with 202
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("invalid response code %d received from teams: %s", res.StatusCode, string(data))
+	}
+
+	// Connectors report that Teams throttled the message in the body of a 200
+	if strings.Contains(string(data), "HTTP error 429") {
+		return &zrule.RateLimitError{RetryAfter: throttledRetryAfter}
+	}
+
+	return nil
+
+}
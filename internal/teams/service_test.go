@@ -0,0 +1,84 @@
+package teams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestSendPostsAdaptiveCard(t *testing.T) {
+
+	var received struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Type    string                   `json:"type"`
+				Body    []map[string]interface{} `json:"body"`
+				Actions []map[string]interface{} `json:"actions"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		_, _ = w.Write([]byte("1"))
+	}))
+	defer server.Close()
+
+	dispatcher, err := NewService(&zrule.Action{Platform: zrule.PlatformTeams, Endpoint: server.URL}, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.Send(context.Background(), &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "Test"},
+		Killmail: &zrule.Killmail{ID: 88486806, Hash: "3c9ed419c00f123ff8d46475b05b70616d1381d8"},
+		Message:  "A capital died",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received.Type != "message" || len(received.Attachments) != 1 {
+		t.Fatalf("unexpected message received: %+v", received)
+	}
+
+	attachment := received.Attachments[0]
+	if attachment.ContentType != "application/vnd.microsoft.card.adaptive" || attachment.Content.Type != "AdaptiveCard" {
+		t.Errorf("expected an adaptive card, got %+v", attachment)
+	}
+
+	if len(attachment.Content.Body) == 0 || attachment.Content.Body[0]["text"] != "A capital died" {
+		t.Errorf("expected the template to lead the card, got %+v", attachment.Content.Body)
+	}
+
+	if len(attachment.Content.Actions) != 1 || attachment.Content.Actions[0]["url"] != "https://zkillboard.com/kill/88486806/" {
+		t.Errorf("expected a link to zkillboard, got %+v", attachment.Content.Actions)
+	}
+
+}
+
+func TestSendDetectsThrottling(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Microsoft Teams endpoint returned HTTP error 429 with ContextId tcid=0"))
+	}))
+	defer server.Close()
+
+	dispatcher, err := NewService(&zrule.Action{Platform: zrule.PlatformTeams, Endpoint: server.URL}, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	var rateLimited *zrule.RateLimitError
+	if err := dispatcher.SendTest(context.Background(), "test"); !errors.As(err, &rateLimited) {
+		t.Errorf("expected a rate limit error, got %v", err)
+	}
+
+}
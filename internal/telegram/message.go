@@ -0,0 +1,58 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/eveisesi/zrule"
+)
+
+// maxFieldLength bounds each field of a message before it is escaped, so that a message stays within the limit
+// of the Bot API without the message itself being cut, which could split a tag or an entity in two
+const maxFieldLength = 256
+
+// field truncates a plain field of a message and escapes it
+func field(text string) string {
+	return html.EscapeString(zrule.Truncate(text, maxFieldLength))
+}
+
+// message formats a notification in the subset of HTML that the Bot API supports
+// https://core.telegram.org/bots/api#html-style
+func message(notification *zrule.Notification, layout zrule.Layout) string {
+
+	killmail, details := notification.Killmail, notification.Details
+
+	link := fmt.Sprintf(`<a href="%s">zKillboard</a>`, html.EscapeString(killmail.ZKillboardURL()))
+
+	if details == nil || details.Victim == nil {
+		return fmt.Sprintf("Match Found with %s <b>%s</b>\n%s", notification.PolicyNoun(), field(notification.PolicyNames()), link)
+	}
+
+	victim := details.Victim
+	system := details.Location()
+
+	if layout == zrule.LayoutCompact {
+		return fmt.Sprintf(
+			"%s lost a <b>%s</b> in %s worth %s\n%s",
+			field(victim.Label()), field(victim.ShipName()),
+			field(system), zrule.FormatISK(details.TotalValue), link,
+		)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>%s destroyed in %s</b>\n", field(victim.ShipName()), field(system))
+	fmt.Fprintf(&b, "Victim: %s\n", field(victim.Label()))
+	if details.Region != nil {
+		fmt.Fprintf(&b, "Region: %s\n", field(details.Region.Name))
+	}
+	fmt.Fprintf(&b, "Value: %s\n", zrule.FormatISK(details.TotalValue))
+	fmt.Fprintf(&b, "Attackers: %d\n", details.AttackerCount)
+	if details.FinalBlow != nil {
+		fmt.Fprintf(&b, "Final Blow: %s (%s)\n", field(details.FinalBlow.Label()), field(details.FinalBlow.ShipName()))
+	}
+	fmt.Fprintf(&b, "<i>Matched %s %s</i>\n%s", notification.PolicyNoun(), field(notification.PolicyNames()), link)
+
+	return b.String()
+
+}
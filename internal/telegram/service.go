@@ -0,0 +1,143 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// maxMessageLength is the longest message that the Bot API accepts
+const maxMessageLength = 4096
+
+type service struct {
+	client   *http.Client
+	endpoint string
+	token    string
+	chatID   string
+	layout   zrule.Layout
+}
+
+func NewService(action *zrule.Action, client *http.Client) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformTelegram {
+		return nil, fmt.Errorf("invalid platform for telegram service constructor")
+	}
+
+	if action.Token == "" || action.Recipient == "" {
+		return nil, fmt.Errorf("telegram actions require a bot token and chat id")
+	}
+
+	return &service{
+		client:   client,
+		endpoint: strings.TrimSuffix(action.Endpoint, "/"),
+		token:    action.Token,
+		chatID:   action.Recipient,
+		layout:   action.Layout,
+	}, nil
+
+}
+
+type sendMessageRequest struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode,omitempty"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+type sendMessageResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send telegram message")
+	defer seg.End()
+
+	body := &sendMessageRequest{
+		ChatID:    s.chatID,
+		Text:      message(notification, s.layout),
+		ParseMode: "HTML",
+	}
+
+	// Templates are written by the owner of the action in Telegram's HTML, so they are sent as is. A template
+	// that is too long cannot be cut without risking a broken tag, so it is cut and sent as plain text instead
+	if notification.Message != "" {
+		body.Text = notification.Message
+		if utf8.RuneCountInString(body.Text) > maxMessageLength {
+			body.Text, body.ParseMode = zrule.Truncate(body.Text, maxMessageLength), ""
+		}
+	}
+
+	return s.sendMessage(ctx, body)
+
+}
+
+func (s *service) SendTest(ctx context.Context, message string) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send telegram test message")
+	defer seg.End()
+
+	return s.sendMessage(ctx, &sendMessageRequest{
+		ChatID: s.chatID,
+		Text:   message,
+	})
+
+}
+
+func (s *service) sendMessage(ctx context.Context, body *sendMessageRequest) error {
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request body to post to telegram: %w", err)
+	}
+
+	uri := fmt.Sprintf("%s/bot%s/sendMessage", s.endpoint, s.token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to prepare request to telegram: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		// The request url contains the bot token, so it is stripped from the error
+		return fmt.Errorf("failed to execute request to telegram: %s", strings.Replace(err.Error(), s.token, "<token>", -1))
+	}
+	defer res.Body.Close()
+
+	data, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response from telegram: %w", err)
+	}
+
+	var result = new(sendMessageResponse)
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return fmt.Errorf("failed to decode response from telegram, received status code %d", res.StatusCode)
+	}
+
+	if result.Parameters.RetryAfter > 0 {
+		return &zrule.RateLimitError{RetryAfter: time.Duration(result.Parameters.RetryAfter) * time.Second}
+	}
+
+	if !result.OK {
+		return fmt.Errorf("telegram rejected message with code %d: %s", result.ErrorCode, result.Description)
+	}
+
+	return nil
+
+}
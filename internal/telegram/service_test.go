@@ -0,0 +1,107 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestSendPostsMessage(t *testing.T) {
+
+	var received *sendMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bot123:abc/sendMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		received = new(sendMessageRequest)
+		_ = json.NewDecoder(r.Body).Decode(received)
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformTelegram, Endpoint: server.URL, Token: "123:abc", Recipient: "-1001"}
+	dispatcher, err := NewService(action, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.Send(context.Background(), &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "<Test>"},
+		Killmail: &zrule.Killmail{ID: 88486806, Hash: "3c9ed419c00f123ff8d46475b05b70616d1381d8"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received == nil || received.ChatID != "-1001" || received.ParseMode != "HTML" {
+		t.Fatalf("unexpected request received: %+v", received)
+	}
+
+	if !strings.Contains(received.Text, "&lt;Test&gt;") {
+		t.Errorf("expected policy name to be escaped, got %s", received.Text)
+	}
+
+}
+
+func TestMessageTruncatesFieldsBeforeEscaping(t *testing.T) {
+
+	text := message(&zrule.Notification{
+		Policy:   &zrule.Policy{Name: strings.Repeat("&", maxMessageLength)},
+		Killmail: &zrule.Killmail{ID: 88486806},
+	}, zrule.LayoutFull)
+
+	if !strings.Contains(text, "<b>"+strings.Repeat("&amp;", maxFieldLength-1)+"…</b>") {
+		t.Errorf("expected the policy name to be cut before it was escaped, got %s", text)
+	}
+
+}
+
+func TestSendReturnsRateLimitError(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 7","parameters":{"retry_after":7}}`))
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformTelegram, Endpoint: server.URL, Token: "123:abc", Recipient: "-1001"}
+	dispatcher, err := NewService(action, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.SendTest(context.Background(), "test")
+
+	var rateLimited *zrule.RateLimitError
+	if !errors.As(err, &rateLimited) {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+
+	if rateLimited.RetryAfter != 7*time.Second {
+		t.Errorf("expected retry after of 7s, got %s", rateLimited.RetryAfter)
+	}
+
+}
+
+func TestValidateTelegramReadsBotURL(t *testing.T) {
+
+	action := &zrule.Action{Endpoint: "https://api.telegram.org/bot123:abc/sendMessage?chat_id=-1001"}
+	err := action.IsValid()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if action.Platform != zrule.PlatformTelegram || action.Token != "123:abc" || action.Recipient != "-1001" || action.Endpoint != "https://api.telegram.org" {
+		t.Errorf("unexpected action after validation: %+v", action)
+	}
+
+}
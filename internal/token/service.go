@@ -25,7 +25,9 @@ type Service interface {
 	UserIDFromToken(ctx context.Context, token *jwt.Token) (uint64, error)
 	OwnerHashFromToken(ctx context.Context, token *jwt.Token) (string, error)
 	ExpiresFromToken(ctx context.Context, token *jwt.Token) (time.Time, error)
+	ScopesFromToken(ctx context.Context, token *jwt.Token) ([]string, error)
 	RefreshToken(ctx context.Context, user *zrule.User, token *jwt.Token) (*jwt.Token, *string, error)
+	RefreshBearer(ctx context.Context, refreshToken string) (*oauth2.Token, error)
 }
 
 type service struct {
@@ -95,6 +97,24 @@ func (s *service) RefreshToken(ctx context.Context, user *zrule.User, token *jwt
 
 }
 
+// RefreshBearer exchanges a refresh token for a new access token. Unlike RefreshToken, it does not
+// need the previous access token, which is what background services such as the dispatcher have
+func (s *service) RefreshBearer(ctx context.Context, refreshToken string) (*oauth2.Token, error) {
+
+	if refreshToken == "" {
+		return nil, fmt.Errorf("invalid refresh token provided")
+	}
+
+	// An expired token forces the token source to use the refresh token
+	expired := &oauth2.Token{
+		RefreshToken: refreshToken,
+		Expiry:       time.Now().Add(-time.Minute),
+	}
+
+	return s.oauth.TokenSource(context.WithValue(ctx, oauth2.HTTPClient, s.client), expired).Token()
+
+}
+
 func (s *service) BearerForCode(ctx context.Context, code string) (*oauth2.Token, error) {
 
 	return s.oauth.Exchange(ctx, code)
@@ -194,6 +214,36 @@ func (s *service) ExpiresFromToken(ctx context.Context, token *jwt.Token) (time.
 
 }
 
+// ScopesFromToken returns the scopes that were granted to the token. The SSO sets scp to a string
+// when a single scope was granted, an array when several were, and omits it when none were
+func (s *service) ScopesFromToken(ctx context.Context, token *jwt.Token) ([]string, error) {
+
+	if _, ok := token.Claims.(jwt.MapClaims); !ok {
+		return nil, fmt.Errorf("invalid structure to claims, expected jwt.MapClaims, got %T", token.Claims)
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+
+	switch scp := claims["scp"].(type) {
+	case nil:
+		return []string{}, nil
+	case string:
+		return []string{scp}, nil
+	case []interface{}:
+		scopes := make([]string, 0, len(scp))
+		for _, scope := range scp {
+			if _, ok := scope.(string); !ok {
+				return nil, fmt.Errorf("unexpected type for scope, expected string, got %T", scope)
+			}
+			scopes = append(scopes, scope.(string))
+		}
+		return scopes, nil
+	default:
+		return nil, fmt.Errorf("unexpected type for scp, expected string or array, got %T", claims["scp"])
+	}
+
+}
+
 func (s *service) getSignatureKey(token *jwt.Token) (interface{}, error) {
 
 	ctx := context.Background()
@@ -0,0 +1,56 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+// accessTokenLeeway is how long before it expires that an access token is refreshed, so that
+// it does not expire while a request is in flight
+const accessTokenLeeway = time.Minute
+
+// AccessToken returns an access token for calling ESI on behalf of the user, refreshing the
+// stored token when it is about to expire. The user must have granted scope
+func (s *service) AccessToken(ctx context.Context, user *zrule.User, scope string) (string, error) {
+
+	if !user.HasScope(scope) {
+		return "", fmt.Errorf("character %d has not granted the %s scope", user.CharacterID, scope)
+	}
+
+	if user.AccessToken != "" && time.Now().Add(accessTokenLeeway).Before(user.Expires) {
+		return user.AccessToken, nil
+	}
+
+	bearer, err := s.token.RefreshBearer(ctx, user.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh token of character %d: %w", user.CharacterID, err)
+	}
+
+	token, err := s.token.ParseToken(bearer.AccessToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse refreshed token of character %d: %w", user.CharacterID, err)
+	}
+
+	expires, err := s.token.ExpiresFromToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	user.AccessToken = token.Raw
+	user.Expires = expires
+	if bearer.RefreshToken != "" {
+		user.RefreshToken = bearer.RefreshToken
+	}
+
+	// Only the token is written, the user may have been changed elsewhere since it was read
+	err = s.UpdateUserToken(ctx, user.CharacterID, user.AccessToken, user.RefreshToken, user.Expires)
+	if err != nil {
+		s.logger.WithError(err).WithField("characterID", user.CharacterID).Error("failed to store refreshed token")
+	}
+
+	return user.AccessToken, nil
+
+}
@@ -42,6 +42,11 @@ func (s *service) VerifyUserRegistrationByToken(ctx context.Context, bearer *oau
 		return err
 	}
 
+	scopes, err := s.token.ScopesFromToken(ctx, token)
+	if err != nil {
+		return err
+	}
+
 	user, err := s.User(ctx, userID)
 	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
 		s.logger.WithError(err).Error("encountered error searching user by token")
@@ -55,6 +60,7 @@ func (s *service) VerifyUserRegistrationByToken(ctx context.Context, bearer *oau
 			AccessToken:  token.Raw,
 			RefreshToken: bearer.RefreshToken,
 			Expires:      expires,
+			Scopes:       scopes,
 		})
 
 		return err
@@ -65,8 +71,16 @@ func (s *service) VerifyUserRegistrationByToken(ctx context.Context, bearer *oau
 		return fmt.Errorf("owner hash does not match token")
 	}
 
+	// Logging in without the scopes that were granted before, ie. a regular login after granting
+	// the mail scope, must not replace the tokens that actions depend on
+	if !(&zrule.User{Scopes: scopes}).HasScopes(user.Scopes) {
+		return nil
+	}
+
 	user.AccessToken = token.Raw
+	user.RefreshToken = bearer.RefreshToken
 	user.Expires = expires
+	user.Scopes = scopes
 
 	_, err = s.UpdateUser(ctx, user.CharacterID, user)
 	return err
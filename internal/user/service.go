@@ -13,6 +13,7 @@ import (
 
 type Service interface {
 	VerifyUserRegistrationByToken(ctx context.Context, token *oauth2.Token) error
+	AccessToken(ctx context.Context, user *zrule.User, scope string) (string, error)
 
 	zrule.UserRepository
 }
@@ -0,0 +1,144 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/eveisesi/zrule"
+)
+
+// recordSize is the record size that payloads are encrypted with. Payloads are always sent as a single record
+const recordSize = 4096
+
+// maxPayloadLength is the largest plaintext that fits in a single record, after the
+// header, the padding delimiter, and the authentication tag
+const maxPayloadLength = recordSize - 86 - 1 - 16
+
+// subscriptionKeys are the decoded keys of a subscription
+type subscriptionKeys struct {
+	public []byte
+	auth   []byte
+}
+
+func decodeKeys(keys zrule.PushSubscriptionKeys) (*subscriptionKeys, error) {
+
+	public, err := decodeBase64(keys.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+
+	if x, _ := elliptic.Unmarshal(elliptic.P256(), public); x == nil {
+		return nil, fmt.Errorf("invalid p256dh key, expected an uncompressed P-256 point")
+	}
+
+	auth, err := decodeBase64(keys.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+
+	if len(auth) != 16 {
+		return nil, fmt.Errorf("invalid auth secret, expected 16 bytes, got %d", len(auth))
+	}
+
+	return &subscriptionKeys{public: public, auth: auth}, nil
+
+}
+
+// ValidateKeys reports whether the keys of a subscription can be used to encrypt payloads
+func ValidateKeys(keys zrule.PushSubscriptionKeys) error {
+	_, err := decodeKeys(keys)
+	return err
+}
+
+// decodeBase64 decodes base64url, which browsers produce without padding, tolerating padding and the standard alphabet
+func decodeBase64(value string) ([]byte, error) {
+	value = string(bytes.TrimRight([]byte(value), "="))
+	if decoded, err := base64.RawURLEncoding.DecodeString(value); err == nil {
+		return decoded, nil
+	}
+	return base64.RawStdEncoding.DecodeString(value)
+}
+
+// hkdf derives length bytes, at most 32, from ikm with HKDF-SHA-256
+// https://tools.ietf.org/html/rfc5869
+func hkdf(salt, ikm, info []byte, length int) []byte {
+
+	extract := hmac.New(sha256.New, salt)
+	_, _ = extract.Write(ikm)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, prk)
+	_, _ = expand.Write(info)
+	_, _ = expand.Write([]byte{0x01})
+
+	return expand.Sum(nil)[:length]
+
+}
+
+// encrypt encrypts a payload for a subscription with the aes128gcm content encoding
+// https://tools.ietf.org/html/rfc8291
+func encrypt(payload []byte, keys *subscriptionKeys, random io.Reader) ([]byte, error) {
+
+	if len(payload) > maxPayloadLength {
+		return nil, fmt.Errorf("payload of %d bytes is larger than the %d bytes that can be pushed", len(payload), maxPayloadLength)
+	}
+
+	curve := elliptic.P256()
+
+	// A new key pair is generated for every message, the public half is sent in the header
+	ephemeral, err := ecdsa.GenerateKey(curve, random)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	asPublic := elliptic.Marshal(curve, ephemeral.X, ephemeral.Y)
+
+	uaX, uaY := elliptic.Unmarshal(curve, keys.public)
+	sharedX, _ := curve.ScalarMult(uaX, uaY, ephemeral.D.Bytes())
+	ecdhSecret := make([]byte, 32)
+	sharedX.FillBytes(ecdhSecret)
+
+	// The shared secret is combined with the auth secret of the subscription
+	keyInfo := append(append([]byte("WebPush: info\x00"), keys.public...), asPublic...)
+	ikm := hkdf(keys.auth, ecdhSecret, keyInfo, 32)
+
+	salt := make([]byte, 16)
+	_, err = io.ReadFull(random, salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+
+	// The payload is the last and only record, which is marked with a 0x02 delimiter
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	header := make([]byte, 0, 86)
+	header = append(header, salt...)
+	header = append(header, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(header[16:20], recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+
+}
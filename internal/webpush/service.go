@@ -0,0 +1,205 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/zrule"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ttl is how long a push service holds on to a notification for a browser that is offline.
+// Notifications about a fight are of little use after it is over
+const ttl = time.Hour
+
+// urgencies maps a priority onto the Urgency header, which devices use to decide whether to wake up
+// https://tools.ietf.org/html/rfc8030#section-5.3
+var urgencies = map[zrule.Priority]string{
+	zrule.PriorityMin:     "very-low",
+	zrule.PriorityLow:     "low",
+	zrule.PriorityDefault: "normal",
+	zrule.PriorityHigh:    "high",
+	zrule.PriorityUrgent:  "high",
+}
+
+// errSubscriptionGone is returned when the push service reports that a subscription no longer exists
+var errSubscriptionGone = errors.New("subscription no longer exists")
+
+// Pruner removes subscriptions that the push service reports no longer exist
+type Pruner interface {
+	DeletePushSubscription(ctx context.Context, id primitive.ObjectID) error
+}
+
+type service struct {
+	client        *http.Client
+	vapid         *VAPID
+	pruner        Pruner
+	subscriptions []*zrule.PushSubscription
+}
+
+func NewService(action *zrule.Action, subscriptions []*zrule.PushSubscription, vapid *VAPID, pruner Pruner, client *http.Client) (zrule.Dispatcher, error) {
+
+	if action.Platform != zrule.PlatformWebPush {
+		return nil, fmt.Errorf("invalid platform for web push service constructor")
+	}
+
+	if vapid == nil {
+		return nil, fmt.Errorf("web push is not configured on this server")
+	}
+
+	return &service{
+		client:        client,
+		vapid:         vapid,
+		pruner:        pruner,
+		subscriptions: subscriptions,
+	}, nil
+
+}
+
+// payload is what the service worker of the frontend receives in its push event
+type payload struct {
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	URL       string `json:"url,omitempty"`
+	Icon      string `json:"icon,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+}
+
+func (s *service) Send(ctx context.Context, notification *zrule.Notification) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send web push notification")
+	defer seg.End()
+
+	p := &payload{
+		Title: notification.Title(),
+		Body:  notification.Summary(),
+		URL:   notification.Killmail.ZKillboardURL(),
+		// Notifications for the same killmail replace each other rather than stacking up
+		Tag: fmt.Sprintf("killmail-%d", notification.Killmail.ID),
+	}
+	if details := notification.Details; details != nil && details.Victim != nil && details.Victim.Ship != nil {
+		p.Icon = zrule.ShipRenderURL(details.Victim.Ship.ID, 128)
+	}
+	if !notification.Killmail.KillmailTime.IsZero() {
+		p.Timestamp = notification.Killmail.KillmailTime.Unix() * 1000
+	}
+
+	return s.pushAll(ctx, p, urgencies[notification.Priority()])
+
+}
+
+func (s *service) SendTest(ctx context.Context, message string) error {
+
+	seg := newrelic.FromContext(ctx).StartSegment("send web push test notification")
+	defer seg.End()
+
+	return s.pushAll(ctx, &payload{Title: "zrule", Body: message}, urgencies[zrule.PriorityDefault])
+
+}
+
+// pushAll pushes a payload to every subscription of the owner of the action. The push only fails when
+// no subscription received it, so that a retry does not notify the browsers that already did twice.
+// Subscriptions that no longer exist are removed and passed over, rather than failing the push
+func (s *service) pushAll(ctx context.Context, p *payload, urgency string) error {
+
+	if len(s.subscriptions) == 0 {
+		return fmt.Errorf("no browsers are subscribed to web push notifications")
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to prepare web push payload: %w", err)
+	}
+
+	var delivered, gone int
+	var errs []string
+	for _, subscription := range s.subscriptions {
+		err := s.push(ctx, subscription, data, urgency)
+		if err == nil {
+			delivered++
+			continue
+		}
+		if errors.Is(err, errSubscriptionGone) {
+			gone++
+			continue
+		}
+
+		// With a single subscription its error is returned as is, so that rate limits are honored
+		if len(s.subscriptions) == 1 {
+			return err
+		}
+
+		errs = append(errs, err.Error())
+	}
+
+	if delivered == 0 && len(errs) == 0 {
+		return fmt.Errorf("no browsers are subscribed to web push notifications, %d subscriptions no longer existed and have been removed", gone)
+	}
+
+	if delivered == 0 {
+		return fmt.Errorf("failed to push to any subscription: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+
+}
+
+func (s *service) push(ctx context.Context, subscription *zrule.PushSubscription, data []byte, urgency string) error {
+
+	keys, err := decodeKeys(subscription.Keys)
+	if err != nil {
+		return err
+	}
+
+	body, err := encrypt(data, keys, rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt web push payload: %w", err)
+	}
+
+	authorization, err := s.vapid.Authorization(subscription.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to prepare request to push service: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Urgency", urgency)
+	req.Header.Set("Authorization", authorization)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request to push service: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode <= 299:
+		return nil
+	case res.StatusCode == http.StatusGone || res.StatusCode == http.StatusNotFound:
+		// The browser unsubscribed or the subscription expired, it will never accept another push
+		if s.pruner != nil {
+			_ = s.pruner.DeletePushSubscription(ctx, subscription.ID)
+		}
+		return fmt.Errorf("%w and has been removed: %s", errSubscriptionGone, subscription.ID.Hex())
+	default:
+		data, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("invalid response code %d received from push service: %s", res.StatusCode, string(data))
+	}
+
+}
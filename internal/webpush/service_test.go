@@ -0,0 +1,237 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// browser holds the keys that a browser subscribes with, so that pushes can be decrypted the way the browser would
+type browser struct {
+	key  *ecdsa.PrivateKey
+	auth []byte
+}
+
+func newBrowser(t *testing.T) *browser {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate browser key: %s", err)
+	}
+	auth := make([]byte, 16)
+	_, _ = rand.Read(auth)
+	return &browser{key: key, auth: auth}
+}
+
+func (b *browser) subscription(endpoint string) *zrule.PushSubscription {
+	return &zrule.PushSubscription{
+		ID:       primitive.NewObjectID(),
+		Endpoint: endpoint,
+		Keys: zrule.PushSubscriptionKeys{
+			P256dh: base64.RawURLEncoding.EncodeToString(elliptic.Marshal(b.key.Curve, b.key.X, b.key.Y)),
+			Auth:   base64.RawURLEncoding.EncodeToString(b.auth),
+		},
+	}
+}
+
+// decrypt reverses encrypt with the private key of the browser
+func (b *browser) decrypt(t *testing.T, body []byte) []byte {
+
+	salt, rs, idlen := body[:16], binary.BigEndian.Uint32(body[16:20]), int(body[20])
+	if rs != recordSize {
+		t.Fatalf("unexpected record size %d", rs)
+	}
+	asPublic := body[21 : 21+idlen]
+	ciphertext := body[21+idlen:]
+
+	curve := elliptic.P256()
+	asX, asY := elliptic.Unmarshal(curve, asPublic)
+	sharedX, _ := curve.ScalarMult(asX, asY, b.key.D.Bytes())
+	ecdhSecret := make([]byte, 32)
+	sharedX.FillBytes(ecdhSecret)
+
+	uaPublic := elliptic.Marshal(curve, b.key.X, b.key.Y)
+	ikm := hkdf(b.auth, ecdhSecret, append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...), 32)
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt push: %s", err)
+	}
+
+	if plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatalf("expected the last record delimiter, got %x", plaintext[len(plaintext)-1])
+	}
+
+	return plaintext[:len(plaintext)-1]
+
+}
+
+type pruner struct {
+	deleted []primitive.ObjectID
+}
+
+func (p *pruner) DeletePushSubscription(ctx context.Context, id primitive.ObjectID) error {
+	p.deleted = append(p.deleted, id)
+	return nil
+}
+
+func newVAPID(t *testing.T) *VAPID {
+	_, private, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("failed to generate vapid keys: %s", err)
+	}
+	vapid, err := NewVAPID("mailto:ops@example.com", private)
+	if err != nil {
+		t.Fatalf("failed to parse vapid keys: %s", err)
+	}
+	return vapid
+}
+
+func TestSendEncryptsPayload(t *testing.T) {
+
+	b := newBrowser(t)
+	vapid := newVAPID(t)
+
+	var received payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("Urgency") != "high" || r.Header.Get("TTL") != "3600" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "vapid t=") || !strings.HasSuffix(r.Header.Get("Authorization"), ", k="+vapid.PublicKey()) {
+			t.Errorf("unexpected authorization %s", r.Header.Get("Authorization"))
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(b.decrypt(t, body), &received)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	action := &zrule.Action{Platform: zrule.PlatformWebPush}
+	dispatcher, err := NewService(action, []*zrule.PushSubscription{b.subscription(server.URL)}, vapid, nil, server.Client())
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.Send(context.Background(), &zrule.Notification{
+		Policy:   &zrule.Policy{Name: "Capitals"},
+		Killmail: &zrule.Killmail{ID: 88486806},
+		Details:  &zrule.KillmailDetails{Victim: &zrule.KillmailParticipant{}, TotalValue: 3_200_000_000},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received.URL != "https://zkillboard.com/kill/88486806/" || received.Tag != "killmail-88486806" || received.Title == "" {
+		t.Errorf("unexpected payload %+v", received)
+	}
+
+}
+
+func TestSendPrunesGoneSubscriptions(t *testing.T) {
+
+	gone := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer gone.Close()
+
+	var delivered int
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ok.Close()
+
+	b := newBrowser(t)
+	stale := b.subscription(gone.URL)
+	p := new(pruner)
+
+	dispatcher, err := NewService(&zrule.Action{Platform: zrule.PlatformWebPush}, []*zrule.PushSubscription{stale, b.subscription(ok.URL)}, newVAPID(t), p, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.SendTest(context.Background(), "Is this thing on?!?!")
+	if err != nil {
+		t.Fatalf("expected the push to succeed while one subscription received it, got %s", err)
+	}
+
+	if delivered != 1 {
+		t.Errorf("expected one delivery, got %d", delivered)
+	}
+
+	if len(p.deleted) != 1 || p.deleted[0] != stale.ID {
+		t.Errorf("expected the gone subscription to be pruned, got %v", p.deleted)
+	}
+
+}
+
+func TestSendWithOnlyGoneSubscriptions(t *testing.T) {
+
+	gone := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer gone.Close()
+
+	stale := newBrowser(t).subscription(gone.URL)
+	p := new(pruner)
+
+	dispatcher, err := NewService(&zrule.Action{Platform: zrule.PlatformWebPush}, []*zrule.PushSubscription{stale}, newVAPID(t), p, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("failed to build service: %s", err)
+	}
+
+	err = dispatcher.SendTest(context.Background(), "Is this thing on?!?!")
+	if err == nil || !strings.Contains(err.Error(), "no browsers are subscribed") {
+		t.Fatalf("expected the push to fail for want of a subscription, got %v", err)
+	}
+
+	if len(p.deleted) != 1 || p.deleted[0] != stale.ID {
+		t.Errorf("expected the gone subscription to be pruned, got %v", p.deleted)
+	}
+
+}
+
+func TestAuthorizationIsSignedForPushService(t *testing.T) {
+
+	vapid := newVAPID(t)
+
+	authorization, err := vapid.Authorization("https://fcm.googleapis.com/fcm/send/abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	signed := strings.TrimSuffix(strings.TrimPrefix(authorization, "vapid t="), ", k="+vapid.PublicKey())
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(signed, claims, func(token *jwt.Token) (interface{}, error) {
+		return &vapid.PrivateKey.PublicKey, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to verify vapid token: %s", err)
+	}
+
+	if claims["aud"] != "https://fcm.googleapis.com" || claims["sub"] != "mailto:ops@example.com" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+
+}
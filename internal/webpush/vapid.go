@@ -0,0 +1,92 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// vapidExpiry is how long the VAPID token on a request is valid for. Push services reject tokens that are valid for more than 24 hours
+const vapidExpiry = time.Hour * 12
+
+// VAPID identifies this server to push services, which only accept pushes to a subscription from the
+// application server whose public key the subscription was created with
+// https://tools.ietf.org/html/rfc8292
+type VAPID struct {
+	// Subject is a mailto: or https: URL that push services can use to contact the operator
+	Subject    string
+	PrivateKey *ecdsa.PrivateKey
+}
+
+// NewVAPID parses a VAPID key pair from the base64url encoded private key, which is the form that
+// GenerateVAPIDKeys returns it in and that other Web Push libraries use
+func NewVAPID(subject, privateKey string) (*VAPID, error) {
+
+	if subject == "" || privateKey == "" {
+		return nil, fmt.Errorf("web push is not configured, a vapid subject and private key are required")
+	}
+
+	d, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil || len(d) != 32 {
+		return nil, fmt.Errorf("invalid vapid private key, expected 32 base64url encoded bytes")
+	}
+
+	curve := elliptic.P256()
+	key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d)
+
+	return &VAPID{Subject: subject, PrivateKey: key}, nil
+
+}
+
+// GenerateVAPIDKeys generates a new VAPID key pair, returning the base64url encoded public and private keys
+func GenerateVAPIDKeys() (string, string, error) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate vapid keys: %w", err)
+	}
+
+	d := make([]byte, 32)
+	key.D.FillBytes(d)
+
+	return base64.RawURLEncoding.EncodeToString(elliptic.Marshal(key.Curve, key.X, key.Y)),
+		base64.RawURLEncoding.EncodeToString(d), nil
+
+}
+
+// PublicKey returns the base64url encoded public key, which the frontend subscribes with as the applicationServerKey
+func (v *VAPID) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(elliptic.Marshal(v.PrivateKey.Curve, v.PrivateKey.X, v.PrivateKey.Y))
+}
+
+// Authorization returns the Authorization header for a push to endpoint
+func (v *VAPID) Authorization(endpoint string) (string, error) {
+
+	uri, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse push endpoint: %w", err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": fmt.Sprintf("%s://%s", uri.Scheme, uri.Host),
+		"exp": time.Now().Add(vapidExpiry).Unix(),
+		"sub": v.Subject,
+	})
+
+	signed, err := token.SignedString(v.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign vapid token: %w", err)
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", signed, v.PublicKey()), nil
+
+}
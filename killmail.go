@@ -1,15 +1,15 @@
 package zrule
 
 import (
+	"context"
 	"time"
 )
 
-// type KillmailRepository interface {
-// 	Killmail(ctx context.Context, id uint) (*Killmail, error)
-// 	Killmails(ctx context.Context, operators ...*Operator) ([]*Killmail, error)
-
-// 	CreateKillmail(ctx context.Context, killmail *Killmail) error
-// }
+type KillmailRepository interface {
+	Killmail(ctx context.Context, id uint) (*Killmail, error)
+	Killmails(ctx context.Context, operators ...*Operator) ([]*Killmail, error)
+	CreateKillmail(ctx context.Context, killmail *Killmail) error
+}
 
 type KillHash struct {
 	ID   uint      `bson:"id" json:"id"`
@@ -18,55 +18,58 @@ type KillHash struct {
 }
 
 type Killmail struct {
-	ID              uint      `json:"killmail_id"`       // bson:"killmail_id"
-	Hash            string    `json:"killmail_hash"`     // bson:"killmail_hash"
-	MoonID          *uint     `json:"moon_id,omitempty"` // bson:"moon_id,o
-	SolarSystemID   uint      `json:"solar_system_id"`   // bson:"solar_system_id"
-	ConstellationID uint      `json:"constellation_id"`
-	RegionID        uint      `json:"region_id"`
-	WarID           *uint     `json:"war_id,omitempty"` // bson:"war_id,o
-	KillmailTime    time.Time `json:"killmail_time"`    // bson:"killmail_time"
+	ID              uint      `bson:"killmail_id" json:"killmail_id"`
+	Hash            string    `bson:"killmail_hash" json:"killmail_hash"`
+	MoonID          *uint     `bson:"moon_id,omitempty" json:"moon_id,omitempty"`
+	SolarSystemID   uint      `bson:"solar_system_id" json:"solar_system_id"`
+	ConstellationID uint      `bson:"constellation_id" json:"constellation_id"`
+	RegionID        uint      `bson:"region_id" json:"region_id"`
+	WarID           *uint     `bson:"war_id,omitempty" json:"war_id,omitempty"`
+	KillmailTime    time.Time `bson:"killmail_time" json:"killmail_time"`
+
+	Attackers []*KillmailAttacker `bson:"attackers" json:"attackers"`
+	Victim    *KillmailVictim     `bson:"victim" json:"victim"`
+	Meta      *Meta               `bson:"zkb" json:"zkb"`
 
-	Attackers []*KillmailAttacker `json:"attackers"` // bson:"attackers"
-	Victim    *KillmailVictim     `json:"victim"`    // bson:"victim"
-	Meta      *Meta               `json:"zkb"`
+	// CreatedAt is the time the killmail was archived and drives the retention of the archive
+	CreatedAt time.Time `bson:"created_at" json:"-"`
 }
 
 type Meta struct {
-	LocationID  uint    `json:"locationID"`
-	Hash        string  `json:"hash"`
-	FittedValue float64 `json:"fittedValue"`
-	TotalValue  float64 `json:"totalValue"`
-	Points      uint    `json:"points"`
-	NPC         bool    `json:"npc"`
-	Solo        bool    `json:"bool"`
-	Awox        bool    `json:"awox"`
-	ESI         string  `json:"esi"`
-	URL         string  `json:"url"`
+	LocationID  uint    `bson:"location_id" json:"locationID"`
+	Hash        string  `bson:"hash" json:"hash"`
+	FittedValue float64 `bson:"fitted_value" json:"fittedValue"`
+	TotalValue  float64 `bson:"total_value" json:"totalValue"`
+	Points      uint    `bson:"points" json:"points"`
+	NPC         bool    `bson:"npc" json:"npc"`
+	Solo        bool    `bson:"solo" json:"bool"`
+	Awox        bool    `bson:"awox" json:"awox"`
+	ESI         string  `bson:"esi" json:"esi"`
+	URL         string  `bson:"url" json:"url"`
 }
 
 type KillmailAttacker struct {
-	AllianceID     *uint   `json:"alliance_id"`     // bson:"alliance_id"
-	CharacterID    *uint64 `json:"character_id"`    // bson:"character_id"
-	CorporationID  *uint   `json:"corporation_id"`  // bson:"corporation_id"
-	FactionID      *uint   `json:"faction_id"`      // bson:"faction_id"
-	DamageDone     uint    `json:"damage_done"`     // bson:"damage_done"
-	FinalBlow      bool    `json:"final_blow"`      // bson:"final_blow"
-	SecurityStatus float64 `json:"security_status"` // bson:"security_status"
-	ShipTypeID     *uint   `json:"ship_type_id"`    // bson:"ship_type_id"
-	ShipGroupID    *uint   `json:"shipGroupID"`     // bson:"shipGroupID"
-	WeaponTypeID   *uint   `json:"weapon_type_id"`  // bson:"weapon_type_id"
-	WeaponGroupID  *uint   `json:"weaponGroupID"`   // bson:"weaponGroupID"
+	AllianceID     *uint   `bson:"alliance_id" json:"alliance_id"`
+	CharacterID    *uint64 `bson:"character_id" json:"character_id"`
+	CorporationID  *uint   `bson:"corporation_id" json:"corporation_id"`
+	FactionID      *uint   `bson:"faction_id" json:"faction_id"`
+	DamageDone     uint    `bson:"damage_done" json:"damage_done"`
+	FinalBlow      bool    `bson:"final_blow" json:"final_blow"`
+	SecurityStatus float64 `bson:"security_status" json:"security_status"`
+	ShipTypeID     *uint   `bson:"ship_type_id" json:"ship_type_id"`
+	ShipGroupID    *uint   `bson:"ship_group_id" json:"shipGroupID"`
+	WeaponTypeID   *uint   `bson:"weapon_type_id" json:"weapon_type_id"`
+	WeaponGroupID  *uint   `bson:"weapon_group_id" json:"weaponGroupID"`
 }
 
 type KillmailVictim struct {
-	AllianceID    *uint   `json:"alliance_id"`    // bson:"alliance_id"
-	CharacterID   *uint64 `json:"character_id"`   // bson:"character_id"
-	CorporationID *uint   `json:"corporation_id"` // bson:"corporation_id"
-	FactionID     *uint   `json:"faction_id"`     // bson:"faction_id"
-	DamageTaken   uint    `json:"damage_taken"`   // bson:"damage_taken"
-	ShipTypeID    uint    `json:"ship_type_id"`   // bson:"ship_type_id"
-	ShipGroupID   uint    `json:"ship_group_id"`  // bson:"ship_group_id"
+	AllianceID    *uint   `bson:"alliance_id" json:"alliance_id"`
+	CharacterID   *uint64 `bson:"character_id" json:"character_id"`
+	CorporationID *uint   `bson:"corporation_id" json:"corporation_id"`
+	FactionID     *uint   `bson:"faction_id" json:"faction_id"`
+	DamageTaken   uint    `bson:"damage_taken" json:"damage_taken"`
+	ShipTypeID    uint    `bson:"ship_type_id" json:"ship_type_id"`
+	ShipGroupID   uint    `bson:"ship_group_id" json:"ship_group_id"`
 }
 
 type Position struct {
@@ -0,0 +1,52 @@
+package zrule
+
+import (
+	"fmt"
+
+	"github.com/eveisesi/zrule/pkg/ruler"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ActionLink configures how a policy notifies one of its actions. Actions that a policy
+// does not have a link for are notified of every match of the policy
+type ActionLink struct {
+	ActionID primitive.ObjectID `bson:"action_id" json:"action_id"`
+	// Rules narrow down the matches of the policy that are sent to the action, ie. a policy could send
+	// every kill to #intel but only supercapital kills to #ping. Every match is sent when there are none
+	Rules [][]*Rule `bson:"rules,omitempty" json:"rules,omitempty"`
+	// Mentions are pinged with every match that is sent to the action, or only with the matches worth at
+	// least MentionMinValue ISK. The threshold applies to every mention of the link, ie. a role ping for titans
+	Mentions        []*Mention `bson:"mentions,omitempty" json:"mentions,omitempty"`
+	MentionMinValue float64    `bson:"mention_min_value,omitempty" json:"mention_min_value,omitempty"`
+}
+
+// Link returns the link of the policy to an action, or nil when the policy does not have one
+func (p *Policy) Link(actionID primitive.ObjectID) *ActionLink {
+	for _, link := range p.Links {
+		if link != nil && link.ActionID == actionID {
+			return link
+		}
+	}
+	return nil
+}
+
+// Ruler builds a ruler from the rules of the link
+func (l *ActionLink) Ruler() (*ruler.Ruler, error) {
+	return newRuler(l.Rules)
+}
+
+// Notifies reports whether a killmail matched by the policy is sent to the action of the link
+func (l *ActionLink) Notifies(killmail *Killmail) (bool, error) {
+
+	if len(l.Rules) == 0 {
+		return true, nil
+	}
+
+	r, err := l.Ruler()
+	if err != nil {
+		return false, fmt.Errorf("failed to build ruler for link to action %s: %w", l.ActionID.Hex(), err)
+	}
+
+	return r.Test(killmail), nil
+
+}
@@ -0,0 +1,49 @@
+package zrule_test
+
+import (
+	"testing"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestActionLinkNotifies(t *testing.T) {
+
+	intel, ping := primitive.NewObjectID(), primitive.NewObjectID()
+
+	policy := &zrule.Policy{
+		Actions: []primitive.ObjectID{intel, ping},
+		Links: []*zrule.ActionLink{
+			{ActionID: ping, Rules: [][]*zrule.Rule{{{Comparator: "eq", Path: "Victim.ShipGroupID", Values: []interface{}{659.0, 30.0}}}}},
+		},
+	}
+
+	if link := policy.Link(intel); link != nil {
+		t.Fatalf("expected no link for intel, got %+v", link)
+	}
+
+	link := policy.Link(ping)
+	if link == nil {
+		t.Fatal("expected a link for ping")
+	}
+
+	tests := []struct {
+		shipGroupID uint
+		expected    bool
+	}{
+		{659, true},
+		{30, true},
+		{547, false},
+	}
+
+	for _, test := range tests {
+		notifies, err := link.Notifies(&zrule.Killmail{Victim: &zrule.KillmailVictim{ShipGroupID: test.shipGroupID}})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if notifies != test.expected {
+			t.Errorf("expected a kill of ship group %d to notify ping to be %t", test.shipGroupID, test.expected)
+		}
+	}
+
+}
@@ -0,0 +1,45 @@
+package zrule
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MatchRepository interface {
+	Match(ctx context.Context, id primitive.ObjectID) (*Match, error)
+	Matches(ctx context.Context, operators ...*Operator) ([]*Match, error)
+	CreateMatch(ctx context.Context, match *Match) (*Match, error)
+	CreateMatchDelivery(ctx context.Context, id primitive.ObjectID, delivery *MatchDelivery) error
+}
+
+// Match is a record of a policy matching a killmail. Deliveries are appended
+// by the dispatcher as each of the policies actions is attempted
+type Match struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	PolicyID     primitive.ObjectID `bson:"policy_id" json:"policy_id"`
+	OwnerID      primitive.ObjectID `bson:"owner_id" json:"owner_id"`
+	KillmailID   uint               `bson:"killmail_id" json:"killmail_id"`
+	KillmailHash string             `bson:"killmail_hash" json:"killmail_hash"`
+	KillmailTime time.Time          `bson:"killmail_time" json:"killmail_time"`
+	Deliveries   []*MatchDelivery   `bson:"deliveries" json:"deliveries"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type MatchDelivery struct {
+	ActionID    primitive.ObjectID `bson:"action_id" json:"action_id"`
+	Platform    Platform           `bson:"platform" json:"platform"`
+	Status      DeliveryStatus     `bson:"status" json:"status"`
+	Error       *string            `bson:"error,omitempty" json:"error,omitempty"`
+	DeliveredAt time.Time          `bson:"delivered_at" json:"delivered_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	DeliveryStatusFailed    DeliveryStatus = "failed"
+)
+
+func (s DeliveryStatus) String() string { return string(s) }
@@ -0,0 +1,125 @@
+package zrule
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MentionType is who a mention notifies
+type MentionType string
+
+const (
+	// MentionEveryone notifies every member of the channel, @everyone on Discord and @channel on Slack
+	MentionEveryone MentionType = "everyone"
+	// MentionHere notifies the members of the channel that are online
+	MentionHere MentionType = "here"
+	// MentionRole notifies the members of a Discord role
+	MentionRole MentionType = "role"
+	// MentionUser notifies a single Discord or Slack user
+	MentionUser MentionType = "user"
+	// MentionUserGroup notifies the members of a Slack user group
+	MentionUserGroup MentionType = "user_group"
+)
+
+func (t MentionType) String() string { return string(t) }
+
+// MaxMentions is the number of mentions that a link can have
+const MaxMentions = 10
+
+// mentionTypes are the types of mention that each platform can render. Platforms that are not listed cannot mention anyone
+var mentionTypes = map[Platform][]MentionType{
+	PlatformDiscord: {MentionEveryone, MentionHere, MentionRole, MentionUser},
+	PlatformSlack:   {MentionEveryone, MentionHere, MentionUser, MentionUserGroup},
+}
+
+// discordSnowflake matches the id of a Discord role or user
+var discordSnowflake = regexp.MustCompile(`^[0-9]{17,20}$`)
+
+// slackUserID and slackUserGroupID match the ids of a Slack user, ie. U024BE7LH, and user group, ie. SAZ94GDB8
+var slackUserID = regexp.MustCompile(`^[UW][A-Z0-9]{2,}$`)
+var slackUserGroupID = regexp.MustCompile(`^S[A-Z0-9]{2,}$`)
+
+// Mention is a role, user or group that is notified when a match is sent to an action. ID is the id of the
+// role, user or user group on the platform of the action, and is empty for everyone and here
+type Mention struct {
+	Type MentionType `bson:"type" json:"type"`
+	ID   string      `bson:"id,omitempty" json:"id,omitempty"`
+}
+
+// IsValid verifies that the mention can be rendered on a platform
+func (m *Mention) IsValid(platform Platform) error {
+
+	supported := false
+	for _, t := range mentionTypes[platform] {
+		if t == m.Type {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		if len(mentionTypes[platform]) == 0 {
+			return fmt.Errorf("%s actions do not support mentions", platform)
+		}
+		return fmt.Errorf("invalid mention type %s for %s actions, expected one of %v", m.Type, platform, mentionTypes[platform])
+	}
+
+	switch {
+	case m.Type == MentionEveryone, m.Type == MentionHere:
+		if m.ID != "" {
+			return fmt.Errorf("%s mentions do not take an id", m.Type)
+		}
+	case platform == PlatformDiscord:
+		if !discordSnowflake.MatchString(m.ID) {
+			return fmt.Errorf("invalid discord %s id %q, expected a snowflake such as 175928847299117063", m.Type, m.ID)
+		}
+	case m.Type == MentionUserGroup:
+		if !slackUserGroupID.MatchString(m.ID) {
+			return fmt.Errorf("invalid slack user group id %q, expected an id such as SAZ94GDB8", m.ID)
+		}
+	default:
+		if !slackUserID.MatchString(m.ID) {
+			return fmt.Errorf("invalid slack user id %q, expected an id such as U024BE7LH", m.ID)
+		}
+	}
+
+	return nil
+
+}
+
+// MentionsFor returns the mentions of the link when a match worth value is sent to its action
+func (l *ActionLink) MentionsFor(value float64) []*Mention {
+	if value < l.MentionMinValue {
+		return nil
+	}
+	return l.Mentions
+}
+
+// MentionsFor returns the mentions that the links of the policies that matched carry to an action, once each
+func (n *Notification) MentionsFor(actionID primitive.ObjectID) []*Mention {
+
+	policies := n.Policies
+	if len(policies) == 0 {
+		policies = []*Policy{n.Policy}
+	}
+
+	var mentions []*Mention
+	seen := make(map[Mention]bool)
+	for _, policy := range policies {
+		link := policy.Link(actionID)
+		if link == nil {
+			continue
+		}
+		for _, mention := range link.MentionsFor(n.Value()) {
+			if mention == nil || seen[*mention] {
+				continue
+			}
+			seen[*mention] = true
+			mentions = append(mentions, mention)
+		}
+	}
+
+	return mentions
+
+}
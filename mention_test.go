@@ -0,0 +1,75 @@
+package zrule_test
+
+import (
+	"testing"
+
+	"github.com/eveisesi/zrule"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMentionIsValidPerPlatform(t *testing.T) {
+
+	tests := []struct {
+		platform zrule.Platform
+		mention  zrule.Mention
+		valid    bool
+	}{
+		{zrule.PlatformDiscord, zrule.Mention{Type: zrule.MentionHere}, true},
+		{zrule.PlatformDiscord, zrule.Mention{Type: zrule.MentionRole, ID: "175928847299117063"}, true},
+		{zrule.PlatformDiscord, zrule.Mention{Type: zrule.MentionRole, ID: "@admins"}, false},
+		{zrule.PlatformDiscord, zrule.Mention{Type: zrule.MentionUserGroup, ID: "SAZ94GDB8"}, false},
+		{zrule.PlatformDiscord, zrule.Mention{Type: zrule.MentionEveryone, ID: "175928847299117063"}, false},
+		{zrule.PlatformSlack, zrule.Mention{Type: zrule.MentionUserGroup, ID: "SAZ94GDB8"}, true},
+		{zrule.PlatformSlack, zrule.Mention{Type: zrule.MentionUser, ID: "U024BE7LH"}, true},
+		{zrule.PlatformSlack, zrule.Mention{Type: zrule.MentionUser, ID: "175928847299117063"}, false},
+		{zrule.PlatformSlack, zrule.Mention{Type: zrule.MentionRole, ID: "175928847299117063"}, false},
+		{zrule.PlatformTelegram, zrule.Mention{Type: zrule.MentionHere}, false},
+	}
+
+	for _, test := range tests {
+		err := test.mention.IsValid(test.platform)
+		if test.valid && err != nil {
+			t.Errorf("expected %s mention %q to be valid on %s, got %s", test.mention.Type, test.mention.ID, test.platform, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("expected %s mention %q to be invalid on %s", test.mention.Type, test.mention.ID, test.platform)
+		}
+	}
+
+}
+
+func TestNotificationMentionsForAppliesThreshold(t *testing.T) {
+
+	actionID := primitive.NewObjectID()
+	here := &zrule.Mention{Type: zrule.MentionHere}
+	role := &zrule.Mention{Type: zrule.MentionRole, ID: "175928847299117063"}
+
+	capitals := &zrule.Policy{Name: "Capitals", Actions: []primitive.ObjectID{actionID}, Links: []*zrule.ActionLink{
+		{ActionID: actionID, Mentions: []*zrule.Mention{here}},
+	}}
+	titans := &zrule.Policy{Name: "Titans", Actions: []primitive.ObjectID{actionID}, Links: []*zrule.ActionLink{
+		{ActionID: actionID, Mentions: []*zrule.Mention{{Type: zrule.MentionHere}, role}, MentionMinValue: 50e9},
+	}}
+
+	notification := &zrule.Notification{
+		Policy:   capitals,
+		Policies: []*zrule.Policy{capitals, titans},
+		Killmail: &zrule.Killmail{Meta: &zrule.Meta{TotalValue: 10e9}},
+	}
+
+	mentions := notification.MentionsFor(actionID)
+	if len(mentions) != 1 || mentions[0].Type != zrule.MentionHere {
+		t.Errorf("expected only the mentions without a threshold, got %d mentions", len(mentions))
+	}
+
+	notification.Killmail.Meta.TotalValue = 80e9
+	mentions = notification.MentionsFor(actionID)
+	if len(mentions) != 2 {
+		t.Errorf("expected @here once along with the role, got %d mentions", len(mentions))
+	}
+
+	if mentions := notification.MentionsFor(primitive.NewObjectID()); len(mentions) != 0 {
+		t.Errorf("expected an action without a link to not be mentioned, got %d mentions", len(mentions))
+	}
+
+}
@@ -0,0 +1,219 @@
+package zrule
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Notification is everything that a platform needs to notify an action that a killmail matched a policy
+type Notification struct {
+	Policy   *Policy          `json:"policy"`
+	Killmail *Killmail        `json:"killmail"`
+	Details  *KillmailDetails `json:"details,omitempty"`
+
+	// Message is the rendered template of the action, or of the policy when it overrides
+	// the actions template. Platforms send it in place of their default message
+	Message string `json:"message,omitempty"`
+
+	// PolicyURL is the page of the policy in the zrule frontend, when one is configured
+	PolicyURL string `json:"policy_url,omitempty"`
+
+	// Policies are every policy that matched the killmail when the matches of several policies were
+	// coalesced into one notification, Policy among them. It is empty when only Policy matched
+	Policies []*Policy `json:"policies,omitempty"`
+
+	// Mentions are who the notification pings on the action that it is sent to, from the link of the policy to the action
+	Mentions []*Mention `json:"mentions,omitempty"`
+}
+
+// PolicyNames returns the name of the policy that matched, or the names of every policy that matched, ie. Capitals, Supers and Titans
+func (n *Notification) PolicyNames() string {
+
+	if len(n.Policies) == 0 {
+		return n.Policy.Name
+	}
+
+	names := make([]string, len(n.Policies))
+	for i, policy := range n.Policies {
+		names[i] = policy.Name
+	}
+
+	if len(names) == 1 {
+		return names[0]
+	}
+
+	return fmt.Sprintf("%s and %s", strings.Join(names[:len(names)-1], ", "), names[len(names)-1])
+
+}
+
+// PolicyNoun returns Policy, or Policies when more than one policy matched, to go in front of PolicyNames
+func (n *Notification) PolicyNoun() string {
+	if len(n.Policies) > 1 {
+		return "Policies"
+	}
+	return "Policy"
+}
+
+// KillmailDetails are the names behind the IDs on a killmail, looked up so that a notification can
+// be read without leaving the platform. Details are best effort, any lookup that fails is left nil
+type KillmailDetails struct {
+	Victim        *KillmailParticipant   `json:"victim"`
+	FinalBlow     *KillmailParticipant   `json:"final_blow"`
+	Attackers     []*KillmailParticipant `json:"attackers"`
+	AttackerCount int                    `json:"attacker_count"`
+	SolarSystem   *SolarSystem           `json:"solar_system"`
+	Region        *Region                `json:"region"`
+	TotalValue    float64                `json:"total_value"`
+}
+
+// KillmailParticipant is the victim or an attacker of a killmail
+type KillmailParticipant struct {
+	Character   *Character   `json:"character,omitempty"`
+	Corporation *Corporation `json:"corporation,omitempty"`
+	Alliance    *Alliance    `json:"alliance,omitempty"`
+	Faction     *Faction     `json:"faction,omitempty"`
+	Ship        *Item        `json:"ship,omitempty"`
+	Weapon      *Item        `json:"weapon,omitempty"`
+	Damage      uint         `json:"damage"`
+	FinalBlow   bool         `json:"final_blow"`
+}
+
+// Name returns the name of the character, falling back to the corporation and then the faction for
+// participants that are not characters, such as structures and NPCs
+func (p *KillmailParticipant) Name() string {
+	switch {
+	case p.Character != nil:
+		return p.Character.Name
+	case p.Corporation != nil:
+		return p.Corporation.Name
+	case p.Faction != nil:
+		return p.Faction.Name
+	default:
+		return "Unknown"
+	}
+}
+
+// Tickers returns the corporation and alliance tickers of the participant, ie. [CORP] <ALLY>
+func (p *KillmailParticipant) Tickers() string {
+	tickers := make([]string, 0, 2)
+	if p.Corporation != nil && p.Corporation.Ticker != "" {
+		tickers = append(tickers, fmt.Sprintf("[%s]", p.Corporation.Ticker))
+	}
+	if p.Alliance != nil && p.Alliance.Ticker != "" {
+		tickers = append(tickers, fmt.Sprintf("<%s>", p.Alliance.Ticker))
+	}
+	return strings.Join(tickers, " ")
+}
+
+// Label returns the name of the participant followed by their tickers, ie. Jita Trader [CORP] <ALLY>
+func (p *KillmailParticipant) Label() string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s", p.Name(), p.Tickers()))
+}
+
+// ShipName returns the name of the participants ship
+func (p *KillmailParticipant) ShipName() string {
+	if p.Ship == nil {
+		return "Unknown"
+	}
+	return p.Ship.Name
+}
+
+// SecurityStatus returns the security status of the solar system rounded the way the game displays it
+func (d *KillmailDetails) SecurityStatus() string {
+	if d.SolarSystem == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", math.Round(d.SolarSystem.SecurityStatus*10)/10)
+}
+
+// Archived reports whether the killmail was found in the archive. When it was not, the notification carries
+// only the ID and hash of the killmail, and neither the contents nor the value of the killmail are known
+func (n *Notification) Archived() bool {
+	return n.Details != nil || (n.Killmail != nil && n.Killmail.Meta != nil)
+}
+
+// Location returns the solar system of the killmail along with its security status, ie. Jita (0.9), or Unknown
+func (d *KillmailDetails) Location() string {
+	if d.SolarSystem == nil {
+		return "Unknown"
+	}
+	return fmt.Sprintf("%s (%s)", d.SolarSystem.Name, d.SecurityStatus())
+}
+
+// Title is a one line description of the notification, for platforms such as push notifications
+// that show a title above a short body
+func (n *Notification) Title() string {
+	if n.Details == nil || n.Details.Victim == nil {
+		return fmt.Sprintf("Match Found with %s %s", n.PolicyNoun(), n.PolicyNames())
+	}
+
+	if n.Details.SolarSystem == nil {
+		return fmt.Sprintf("%s destroyed", n.Details.Victim.ShipName())
+	}
+
+	return fmt.Sprintf("%s destroyed in %s (%s)", n.Details.Victim.ShipName(), n.Details.SolarSystem.Name, n.Details.SecurityStatus())
+}
+
+// Summary is the short body that accompanies the Title. The rendered template replaces it when there is one
+func (n *Notification) Summary() string {
+	if n.Message != "" {
+		return n.Message
+	}
+
+	if n.Details == nil || n.Details.Victim == nil {
+		return fmt.Sprintf("Killmail %d matched %s %s", n.Killmail.ID, n.PolicyNoun(), n.PolicyNames())
+	}
+
+	return fmt.Sprintf(
+		"%s lost a %s worth %s to %d attackers\nMatched %s %s",
+		n.Details.Victim.Label(), n.Details.Victim.ShipName(), FormatISK(n.Details.TotalValue), n.Details.AttackerCount, n.PolicyNoun(), n.PolicyNames(),
+	)
+}
+
+// ZKillboardURL returns the zkillboard page of the killmail
+func (k *Killmail) ZKillboardURL() string {
+	return fmt.Sprintf("https://zkillboard.com/kill/%d/", k.ID)
+}
+
+// ShipRenderURL returns the render of a ship type from the EVE image server
+func ShipRenderURL(typeID uint, size uint) string {
+	return fmt.Sprintf("https://images.evetech.net/types/%d/render?size=%d", typeID, size)
+}
+
+// CharacterPortraitURL returns the portrait of a character from the EVE image server
+func CharacterPortraitURL(characterID uint64, size uint) string {
+	return fmt.Sprintf("https://images.evetech.net/characters/%d/portrait?size=%d", characterID, size)
+}
+
+// Truncate shortens text to at most max characters, marking that it has been shortened
+func Truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// FormatISK formats an ISK value in the short form that players use, ie. 1.25b ISK
+func FormatISK(value float64) string {
+
+	units := []struct {
+		size   float64
+		suffix string
+	}{
+		{1e12, "t"},
+		{1e9, "b"},
+		{1e6, "m"},
+		{1e3, "k"},
+	}
+
+	for _, unit := range units {
+		if math.Abs(value) >= unit.size {
+			return fmt.Sprintf("%.2f%s ISK", value/unit.size, unit.suffix)
+		}
+	}
+
+	return fmt.Sprintf("%.2f ISK", value)
+
+}
@@ -0,0 +1,54 @@
+package zrule_test
+
+import (
+	"testing"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestNotificationPolicyNames(t *testing.T) {
+
+	capitals, supers, titans := &zrule.Policy{Name: "Capitals"}, &zrule.Policy{Name: "Supers"}, &zrule.Policy{Name: "Titans"}
+
+	tests := []struct {
+		policies []*zrule.Policy
+		names    string
+		noun     string
+	}{
+		{nil, "Capitals", "Policy"},
+		{[]*zrule.Policy{capitals, supers}, "Capitals and Supers", "Policies"},
+		{[]*zrule.Policy{capitals, supers, titans}, "Capitals, Supers and Titans", "Policies"},
+	}
+
+	for _, test := range tests {
+		notification := &zrule.Notification{Policy: capitals, Policies: test.policies}
+
+		if names := notification.PolicyNames(); names != test.names {
+			t.Errorf("expected %q, got %q", test.names, names)
+		}
+		if noun := notification.PolicyNoun(); noun != test.noun {
+			t.Errorf("expected %q for %q, got %q", test.noun, test.names, noun)
+		}
+	}
+
+}
+
+func TestTruncate(t *testing.T) {
+
+	tests := []struct {
+		text     string
+		max      int
+		expected string
+	}{
+		{"Jita", 4, "Jita"},
+		{"Jita 4-4", 5, "Jita…"},
+		{"Ω-Ω-Ω", 3, "Ω-…"},
+	}
+
+	for _, test := range tests {
+		if truncated := zrule.Truncate(test.text, test.max); truncated != test.expected {
+			t.Errorf("expected %q truncated to %d to be %q, got %q", test.text, test.max, test.expected, truncated)
+		}
+	}
+
+}
@@ -2,6 +2,8 @@ package zrule
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/eveisesi/zrule/pkg/ruler"
@@ -14,12 +16,42 @@ type PolicyRepository interface {
 	CreatePolicy(ctx context.Context, policy *Policy) (*Policy, error)
 	UpdatePolicy(ctx context.Context, id primitive.ObjectID, policy *Policy) (*Policy, error)
 	DeletePolicy(ctx context.Context, id primitive.ObjectID) error
+
+	// PausePolicy pauses a policy without touching anything else about it
+	PausePolicy(ctx context.Context, id primitive.ObjectID) error
 }
 
 type Dispatchable struct {
 	PolicyID primitive.ObjectID `json:"policyID"`
+	MatchID  primitive.ObjectID `json:"matchID"`
 	ID       uint               `json:"id"`
 	Hash     string             `json:"hash"`
+
+	// ActionID is set when the dispatchable is a retry of a delivery to a single action.
+	// When it is nil, the killmail is delivered to every action on the policy
+	ActionID  *primitive.ObjectID `json:"actionID,omitempty"`
+	Attempt   uint                `json:"attempt,omitempty"`
+	LastError string              `json:"lastError,omitempty"`
+
+	// FallbackFor is set when the delivery was handed off to a fallback of an action that failed.
+	// ActionID is then the fallback at index Fallback of the fallbacks of that action
+	FallbackFor *primitive.ObjectID `json:"fallbackFor,omitempty"`
+	Fallback    int                 `json:"fallback,omitempty"`
+
+	// Coalesce is set on the delivery that flushes the matches of the killmail that were buffered for ActionID.
+	// Once flushed, Coalesced holds every match that the delivery was coalesced from, when there was more than one
+	Coalesce  bool              `json:"coalesce,omitempty"`
+	Coalesced []*CoalescedMatch `json:"coalesced,omitempty"`
+
+	// Route is set on a retry of a delivery that could not be routed yet, because the killmail was not in the
+	// archive and whether the match is for ActionID depends on its contents. The retry is routed once more
+	Route bool `json:"route,omitempty"`
+}
+
+// CoalescedMatch is one of the matches of a killmail that were merged into a single delivery to an action
+type CoalescedMatch struct {
+	PolicyID primitive.ObjectID `json:"policyID"`
+	MatchID  primitive.ObjectID `json:"matchID"`
 }
 
 type Policy struct {
@@ -29,8 +61,87 @@ type Policy struct {
 	Rules     [][]*Rule            `bson:"rules" json:"rules"`
 	Actions   []primitive.ObjectID `bson:"actions" json:"actions"`
 	Paused    bool                 `bson:"paused" json:"paused"`
-	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
+	Schedule  *Schedule            `bson:"schedule,omitempty" json:"schedule,omitempty"`
+	ExpiresAt *time.Time           `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	// Template overrides the template of every action that the policy notifies
+	Template string `bson:"template" json:"template,omitempty"`
+	// Priority overrides the priority that push notifications are sent with, which is otherwise derived from the value of the killmail
+	Priority Priority `bson:"priority" json:"priority,omitempty"`
+	// Escalations notify additional actions when a match is worth enough
+	Escalations []*Escalation `bson:"escalations" json:"escalations,omitempty"`
+	// Links configure how the policy notifies individual actions
+	Links []*ActionLink `bson:"links" json:"links,omitempty"`
+	// Mode controls whether every match is sent on its own, or is grouped into a battle report.
+	// BattleWindow is how many minutes a battle goes without a kill before it is over
+	Mode         PolicyMode `bson:"mode" json:"mode,omitempty"`
+	BattleWindow uint       `bson:"battle_window" json:"battle_window,omitempty"`
+	CreatedAt    time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `bson:"updated_at" json:"updated_at"`
+
+	// Active and NextActivationAt are computed when the policy is read and are never persisted
+	Active           bool       `bson:"-" json:"active"`
+	NextActivationAt *time.Time `bson:"-" json:"next_activation_at"`
+}
+
+// Ruler builds a ruler from the rules of the policy. This is how the processor evaluates
+// a policy, so anything else that needs to test a killmail against a policy should use it too
+func (p *Policy) Ruler() (*ruler.Ruler, error) {
+	return newRuler(p.Rules)
+}
+
+func newRuler(policyRules [][]*Rule) (*ruler.Ruler, error) {
+
+	data, err := json.Marshal(policyRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal policy rules: %w", err)
+	}
+
+	rules := make([][]*ruler.Rule, 0)
+	err = json.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy rules onto ruler rules: %w", err)
+	}
+
+	r := ruler.NewRuler()
+	r.SetRules(rules)
+
+	return r, nil
+
+}
+
+// IsExpired reports whether the policy has an expiry and t is at or after it
+func (p *Policy) IsExpired(t time.Time) bool {
+	return p.ExpiresAt != nil && !t.Before(*p.ExpiresAt)
+}
+
+// IsActive reports whether the policy should be matching killmails at t. Paused and
+// expired policies are never active, otherwise the schedule, if any, is consulted
+func (p *Policy) IsActive(t time.Time) bool {
+	if p.Paused || p.IsExpired(t) {
+		return false
+	}
+
+	if p.Schedule == nil {
+		return true
+	}
+
+	return p.Schedule.IsActive(t)
+}
+
+// NextActivation returns when an inactive policy will next become active on its own.
+// Nil is returned if the policy is currently active, is paused, or will expire
+// before its schedule opens another window
+func (p *Policy) NextActivation(t time.Time) *time.Time {
+	if p.Paused || p.IsExpired(t) || p.Schedule == nil || p.IsActive(t) {
+		return nil
+	}
+
+	next := p.Schedule.Next(t)
+	if next == nil || p.IsExpired(*next) {
+		return nil
+	}
+
+	return next
 }
 
 type Rule struct {
@@ -370,8 +481,3 @@ func (p PathCategory) IsValid() bool {
 func (p PathCategory) String() string {
 	return string(p)
 }
-
-type Infraction struct {
-	InfrationID primitive.ObjectID `bson:"_id" json:"_id"`
-	Message     string
-}
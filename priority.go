@@ -0,0 +1,66 @@
+package zrule
+
+// Priority is how urgently a notification is pushed to a phone. Push platforms map it onto their own scale
+type Priority string
+
+const (
+	PriorityMin     Priority = "min"
+	PriorityLow     Priority = "low"
+	PriorityDefault Priority = "default"
+	PriorityHigh    Priority = "high"
+	PriorityUrgent  Priority = "urgent"
+)
+
+var AllPriorities = []Priority{PriorityMin, PriorityLow, PriorityDefault, PriorityHigh, PriorityUrgent}
+
+func (p Priority) IsValid() bool {
+	for _, v := range AllPriorities {
+		if v == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p Priority) String() string { return string(p) }
+
+// Thresholds of the value of a killmail that raise the priority of its notification when the policy does not
+// set one. Urgent notifications demand acknowledgement on some platforms, so the value of a killmail never makes
+// a notification urgent. Only a policy that sets an urgent priority does
+const (
+	priorityLowValue  = 100_000_000
+	priorityHighValue = 1_000_000_000
+)
+
+// Value returns the value of the killmail in ISK, or zero when it is not known
+func (n *Notification) Value() float64 {
+	switch {
+	case n.Details != nil:
+		return n.Details.TotalValue
+	case n.Killmail != nil && n.Killmail.Meta != nil:
+		return n.Killmail.Meta.TotalValue
+	}
+	return 0
+}
+
+// Priority returns the priority of the policy when it sets one. Otherwise the priority is derived
+// from the value of the killmail, up to high, so that expensive losses stand out
+func (n *Notification) Priority() Priority {
+
+	if n.Policy != nil && n.Policy.Priority != "" {
+		return n.Policy.Priority
+	}
+
+	value := n.Value()
+
+	switch {
+	case value >= priorityHighValue:
+		return PriorityHigh
+	case value >= priorityLowValue:
+		return PriorityDefault
+	default:
+		return PriorityLow
+	}
+
+}
@@ -0,0 +1,35 @@
+package zrule_test
+
+import (
+	"testing"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestNotificationPriority(t *testing.T) {
+
+	tests := []struct {
+		policy   zrule.Priority
+		value    float64
+		expected zrule.Priority
+	}{
+		{"", 25_000_000, zrule.PriorityLow},
+		{"", 450_000_000, zrule.PriorityDefault},
+		{"", 3_200_000_000, zrule.PriorityHigh},
+		{"", 90_000_000_000, zrule.PriorityHigh},
+		{zrule.PriorityMin, 90_000_000_000, zrule.PriorityMin},
+		{zrule.PriorityUrgent, 25_000_000, zrule.PriorityUrgent},
+	}
+
+	for _, test := range tests {
+		notification := &zrule.Notification{
+			Policy:  &zrule.Policy{Priority: test.policy},
+			Details: &zrule.KillmailDetails{TotalValue: test.value},
+		}
+
+		if priority := notification.Priority(); priority != test.expected {
+			t.Errorf("expected a killmail worth %.0f with policy priority %q to be %s, got %s", test.value, test.policy, test.expected, priority)
+		}
+	}
+
+}
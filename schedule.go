@@ -0,0 +1,148 @@
+package zrule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Schedule describes the recurring windows during which a policy is allowed to match killmails.
+// Windows are evaluated in the schedules Timezone, defaulting to UTC when one is not provided
+type Schedule struct {
+	Timezone string            `bson:"timezone" json:"timezone"`
+	Windows  []*ScheduleWindow `bson:"windows" json:"windows"`
+}
+
+// ScheduleWindow is a range of hours on a set of weekdays. StartHour is inclusive and EndHour is exclusive.
+// When EndHour is less than or equal to StartHour, the window wraps past midnight into the following day.
+// An empty list of Weekdays means the window applies to every day of the week
+type ScheduleWindow struct {
+	Weekdays  []time.Weekday `bson:"weekdays" json:"weekdays"`
+	StartHour uint           `bson:"start_hour" json:"start_hour"`
+	EndHour   uint           `bson:"end_hour" json:"end_hour"`
+}
+
+// maxScheduleLookahead is how far into the future Next will search for the start of a window.
+// Every window recurs weekly, so a week and a day is enough to find any of them
+const maxScheduleLookahead = time.Hour * 24 * 8
+
+func (s *Schedule) IsValid() error {
+
+	if _, err := s.location(); err != nil {
+		return fmt.Errorf("invalid timezone %s specified on schedule", s.Timezone)
+	}
+
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("schedule must have at least one window")
+	}
+
+	for _, window := range s.Windows {
+		if window == nil {
+			return fmt.Errorf("schedule window cannot be null")
+		}
+		if window.StartHour > 23 {
+			return fmt.Errorf("invalid start hour %d, expected value between 0 and 23", window.StartHour)
+		}
+		if window.EndHour > 24 {
+			return fmt.Errorf("invalid end hour %d, expected value between 0 and 24", window.EndHour)
+		}
+		if window.StartHour == window.EndHour {
+			return fmt.Errorf("start hour and end hour of a schedule window cannot be equal")
+		}
+		for _, day := range window.Weekdays {
+			if day < time.Sunday || day > time.Saturday {
+				return fmt.Errorf("invalid weekday %d, expected value between 0 (Sunday) and 6 (Saturday)", day)
+			}
+		}
+	}
+
+	return nil
+
+}
+
+// IsActive reports whether t falls inside of one of the schedules windows
+func (s *Schedule) IsActive(t time.Time) bool {
+
+	loc, err := s.location()
+	if err != nil {
+		return false
+	}
+
+	local := t.In(loc)
+	hour := uint(local.Hour())
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, window := range s.Windows {
+		if window == nil {
+			continue
+		}
+
+		if window.EndHour > window.StartHour {
+			if window.hasDay(today) && hour >= window.StartHour && hour < window.EndHour {
+				return true
+			}
+			continue
+		}
+
+		// Window wraps past midnight, so it is either in the late portion of a day it started on,
+		// or in the early portion of the day after a day it started on
+		if window.hasDay(today) && hour >= window.StartHour {
+			return true
+		}
+		if window.hasDay(yesterday) && hour < window.EndHour {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// Next returns the time that the schedule next becomes active at or after t. If t is
+// inside of a window, t is returned. Nil is returned if the schedule never becomes active
+func (s *Schedule) Next(t time.Time) *time.Time {
+
+	if s.IsActive(t) {
+		return &t
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return nil
+	}
+
+	local := t.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc)
+
+	for offset := time.Hour; offset <= maxScheduleLookahead; offset += time.Hour {
+		candidate := start.Add(offset)
+		if s.IsActive(candidate) {
+			return &candidate
+		}
+	}
+
+	return nil
+
+}
+
+func (s *Schedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+
+	return time.LoadLocation(s.Timezone)
+}
+
+func (w *ScheduleWindow) hasDay(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+
+	for _, d := range w.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+
+	return false
+}
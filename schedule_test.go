@@ -0,0 +1,90 @@
+package zrule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eveisesi/zrule"
+)
+
+func TestScheduleIsActive(t *testing.T) {
+
+	schedule := &zrule.Schedule{
+		Timezone: "America/New_York",
+		Windows: []*zrule.ScheduleWindow{
+			{
+				Weekdays:  []time.Weekday{time.Friday},
+				StartHour: 22,
+				EndHour:   2,
+			},
+			{
+				Weekdays:  []time.Weekday{time.Monday, time.Tuesday},
+				StartHour: 9,
+				EndHour:   17,
+			},
+		},
+	}
+
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		t.Fatalf("failed to load location: %s", err)
+	}
+
+	cases := []struct {
+		name   string
+		t      time.Time
+		result bool
+	}{
+		{"inside weekday window", time.Date(2020, time.November, 9, 12, 30, 0, 0, loc), true},
+		{"before weekday window", time.Date(2020, time.November, 9, 8, 59, 0, 0, loc), false},
+		{"end hour is exclusive", time.Date(2020, time.November, 10, 17, 0, 0, 0, loc), false},
+		{"weekday not in window", time.Date(2020, time.November, 11, 12, 0, 0, 0, loc), false},
+		{"late portion of wrapping window", time.Date(2020, time.November, 13, 23, 0, 0, 0, loc), true},
+		{"early portion of wrapping window", time.Date(2020, time.November, 14, 1, 0, 0, 0, loc), true},
+		{"after wrapping window", time.Date(2020, time.November, 14, 2, 0, 0, 0, loc), false},
+		{"evaluated in schedule timezone", time.Date(2020, time.November, 9, 14, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, c := range cases {
+		result := schedule.IsActive(c.t)
+		if result != c.result {
+			t.Errorf("Test Failed:\nName: %s\nTime: %s\nExpected %t, Got %t", c.name, c.t, c.result, result)
+		}
+	}
+
+}
+
+func TestPolicyNextActivation(t *testing.T) {
+
+	now := time.Date(2020, time.November, 11, 12, 15, 0, 0, time.UTC) // Wednesday
+	expires := now.Add(time.Hour * 24 * 30)
+
+	policy := &zrule.Policy{
+		Schedule: &zrule.Schedule{
+			Windows: []*zrule.ScheduleWindow{
+				{Weekdays: []time.Weekday{time.Saturday}, StartHour: 18, EndHour: 20},
+			},
+		},
+		ExpiresAt: &expires,
+	}
+
+	next := policy.NextActivation(now)
+	if next == nil {
+		t.Fatal("expected next activation, got nil")
+	}
+
+	expected := time.Date(2020, time.November, 14, 18, 0, 0, 0, time.UTC)
+	if !next.Equal(expected) {
+		t.Errorf("expected next activation of %s, got %s", expected, next)
+	}
+
+	expires = now.Add(time.Hour)
+	if next := policy.NextActivation(now); next != nil {
+		t.Errorf("expected nil next activation for a policy that expires before its window, got %s", next)
+	}
+
+	if policy.IsActive(expires) {
+		t.Error("expected policy to be inactive once expired")
+	}
+
+}
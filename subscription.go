@@ -0,0 +1,32 @@
+package zrule
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PushSubscriptionRepository interface {
+	PushSubscriptions(ctx context.Context, operators ...*Operator) ([]*PushSubscription, error)
+	SavePushSubscription(ctx context.Context, subscription *PushSubscription) (*PushSubscription, error)
+	DeletePushSubscription(ctx context.Context, id primitive.ObjectID) error
+}
+
+// PushSubscription is a browser that a user has allowed to receive Web Push notifications. Endpoint and
+// Keys are the PushSubscription that the browser handed to the frontend, as returned by PushSubscription.toJSON()
+type PushSubscription struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserID    primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	Endpoint  string               `bson:"endpoint" json:"endpoint"`
+	Keys      PushSubscriptionKeys `bson:"keys" json:"keys"`
+	UserAgent string               `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// PushSubscriptionKeys are the keys that payloads for a subscription are encrypted with, base64url encoded
+type PushSubscriptionKeys struct {
+	P256dh string `bson:"p256dh" json:"p256dh"`
+	Auth   string `bson:"auth" json:"auth"`
+}
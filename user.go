@@ -9,8 +9,11 @@ import (
 
 type UserRepository interface {
 	User(ctx context.Context, id uint64) (*User, error)
+	UserByID(ctx context.Context, id primitive.ObjectID) (*User, error)
 	CreateUser(ctx context.Context, user *User) (*User, error)
 	UpdateUser(ctx context.Context, id uint64, user *User) (*User, error)
+	// UpdateUserToken stores a refreshed token without touching anything else about the user
+	UpdateUserToken(ctx context.Context, id uint64, accessToken, refreshToken string, expires time.Time) error
 	DeleteUser(ctx context.Context, id primitive.ObjectID) error
 }
 
@@ -21,9 +24,38 @@ type User struct {
 	AccessToken       string             `bson:"access_token" json:"access_token"`
 	RefreshToken      string             `bson:"refresh_token" json:"refresh_token"`
 	Expires           time.Time          `bson:"expires" json:"expires"`
+	Scopes            []string           `bson:"scopes" json:"scopes"`
 	Disabled          bool               `bson:"disabled" json:"disabled"`
 	DisabledReason    *string            `bson:"disabled_reason" json:"disabled_reason"`
 	DisabledTimestamp *time.Time         `bson:"disabled_time" json:"disabled_time"`
 	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
 }
+
+// ScopeSendMail allows EVE mail to be sent on behalf of the character
+const ScopeSendMail = "esi-mail.send_mail.v1"
+
+// AllScopes are the ESI scopes that a user can grant zrule when logging in
+var AllScopes = []string{ScopeSendMail}
+
+// HasScope reports whether the refresh token of the user was granted scope
+func (u *User) HasScope(scope string) bool {
+	for _, v := range u.Scopes {
+		if v == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasScopes reports whether the refresh token of the user was granted every one of scopes
+func (u *User) HasScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if !u.HasScope(scope) {
+			return false
+		}
+	}
+
+	return true
+}